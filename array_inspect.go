@@ -1,67 +1,74 @@
 package inspect
 
-func setArraySize[T any](array *[]T, newSize int) {
-	if newSize > cap(*array) {
-		*array = (*array)[:newSize]
+// maxPreallocated bounds the room made for the items of an array or a map
+// before they are read, as their number comes from the input
+const maxPreallocated = 1024
+
+// preallocated returns the room to make for length items
+func preallocated(length int) int {
+	if length > maxPreallocated {
+		return maxPreallocated
+	}
+	return length
+}
+
+// failed tells whether reading has stopped, so loops over a length
+// taken from the input end with the input
+func failed(inspector *Inspector) bool {
+	reader, ok := inspector.impl.(interface{ failed() bool })
+	return ok && reader.failed()
+}
+
+// readItems reads the items of an array whose length ReadArray returned,
+// the array grows as items arrive
+func readItems[T any](array *[]T, inspector *Inspector, length int, read func(item *T)) {
+	*array = (*array)[:0]
+	if length == 0 {
 		return
 	}
-	*array = make([]T, newSize)
+	if cap(*array) < preallocated(length) {
+		*array = make([]T, 0, preallocated(length))
+	}
+	for i := 0; length < 0 || i < length; i++ {
+		if length < 0 && i > 0 && !inspector.HaveNext() || failed(inspector) {
+			break
+		}
+		var item T
+		read(&item)
+		*array = append(*array, item)
+	}
+	inspector.EndArray()
 }
 
 func Array[PT InspectablePtr[T], T any](array *[]T, inspector *Inspector, name string,
 	elementName string, description string) {
 
 	if inspector.IsReading() {
-		length := inspector.ReadArray()
-		if length <= 0 {
-			if length == -1 {
-				setArraySize(array, 1)
-				PT(&(*array)[0]).Inspect(inspector)
-				for inspector.HaveNext() {
-					var item T
-					PT(&item).Inspect(inspector)
-					*array = append(*array, item)
-				}
-				inspector.EndArray()
-			} else {
-				(*array) = (*array)[:0]
-			}
-			return
-		}
-		setArraySize(array, length)
-	} else {
-		inspector.WriteArray(name, elementName, len(*array), description)
+		readItems(array, inspector, inspector.ReadArray(), func(item *T) {
+			PT(item).Inspect(inspector)
+		})
+		return
 	}
-	for _, item := range *array {
-		PT(&item).Inspect(inspector)
+	inspector.WriteArray(name, elementName, len(*array), description)
+	for i := range *array {
+		PT(&(*array)[i]).Inspect(inspector)
 	}
+	inspector.EndArray()
 }
 
 func ArrayPtr[T any, PT InspectablePtr[T]](array *[]PT, inspector *Inspector, name string,
 	elementName string, description string) {
 
 	if inspector.IsReading() {
-		length := inspector.ReadArray()
-		if length <= 0 {
-			if length == -1 {
-				setArraySize(array, 1)
-				(*array)[0].Inspect(inspector)
-				for inspector.HaveNext() {
-					item := PT(new(T))
-					item.Inspect(inspector)
-					*array = append(*array, item)
-				}
-				inspector.EndArray()
-			} else {
-				(*array) = (*array)[:0]
-			}
-			return
-		}
-		setArraySize(array, length)
-	} else {
-		inspector.WriteArray(name, elementName, len(*array), description)
+		readItems(array, inspector, inspector.ReadArray(), func(item *PT) {
+			*item = PT(new(T))
+			(*item).Inspect(inspector)
+		})
+		return
 	}
+	inspector.WriteArray(name, elementName, len(*array), description)
 	for _, item := range *array {
 		item.Inspect(inspector)
 	}
+	inspector.EndArray()
 }
@@ -0,0 +1,70 @@
+package binary
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/tvanomr/inspect"
+)
+
+// countingReader keeps track of the number of bytes consumed from the input
+// and fails once more than the allowed total is consumed
+type countingReader struct {
+	reader *bufio.Reader
+	offset int64
+	limits *inspect.Limits
+}
+
+func (c *countingReader) reset(reader io.Reader) {
+	if c.reader == nil {
+		c.reader = bufio.NewReader(reader)
+	} else {
+		c.reader.Reset(reader)
+	}
+	c.offset = 0
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	if err := c.limits.CheckTotal(c.offset + 1); err != nil {
+		return 0, err
+	}
+	result, err := c.reader.ReadByte()
+	if err == nil {
+		c.offset++
+	}
+	return result, err
+}
+
+func (c *countingReader) Read(buffer []byte) (int, error) {
+	if max := c.limits.MaxTotalBytes; max > 0 && c.offset+int64(len(buffer)) > max {
+		if c.offset >= max {
+			return 0, c.limits.CheckTotal(c.offset + 1)
+		}
+		buffer = buffer[:max-c.offset]
+	}
+	read, err := c.reader.Read(buffer)
+	c.offset += int64(read)
+	return read, err
+}
+
+func (c *countingReader) discard(length int64) error {
+	if err := c.limits.CheckTotal(c.offset + length); err != nil {
+		return err
+	}
+	for length > 0 {
+		chunk := length
+		if chunk > int64(c.reader.Size()) {
+			chunk = int64(c.reader.Size())
+		}
+		discarded, err := c.reader.Discard(int(chunk))
+		c.offset += int64(discarded)
+		length -= int64(discarded)
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
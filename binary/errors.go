@@ -5,11 +5,23 @@ type binError int
 const (
 	ErrShortRead binError = iota
 	ErrShortWrite
+	ErrInvalidBool
+	ErrInvalidMarker
+	ErrRawField
+	ErrNotSkippable
+	ErrFrameOverrun
+	ErrInvalidLength
 )
 
 var binErrorMessages = map[binError]string{
-	ErrShortRead:  "short read",
-	ErrShortWrite: "short write"}
+	ErrShortRead:     "short read",
+	ErrShortWrite:    "short write",
+	ErrInvalidBool:   "invalid boolean value",
+	ErrInvalidMarker: "invalid presence marker",
+	ErrRawField:      "fields collected from another format can't be written",
+	ErrNotSkippable:  "only a whole property value can be skipped",
+	ErrFrameOverrun:  "property value is longer than its recorded length",
+	ErrInvalidLength: "length is negative or runs past the end of any input"}
 
 func (b binError) Error() string {
 	return binErrorMessages[b]
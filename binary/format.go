@@ -0,0 +1,40 @@
+package binary
+
+import (
+	"io"
+
+	"github.com/tvanomr/inspect"
+)
+
+// FixedReader is a Reader in fixed mode
+type FixedReader struct {
+	Reader
+}
+
+func (r *FixedReader) SetReader(reader io.Reader) {
+	r.fixed = true
+	r.Reader.SetReader(reader)
+}
+
+// FixedWriter is a Writer in fixed mode
+type FixedWriter struct {
+	Writer
+}
+
+func (w *FixedWriter) SetWriter(writer io.Writer, bufferSize int) {
+	w.fixed = true
+	w.Writer.SetWriter(writer, bufferSize)
+}
+
+// Format is the binary encoding for inspect.Marshal, inspect.Unmarshal, inspect.Encode and inspect.Decode
+var Format inspect.Format = inspect.BinaryFormat[Reader, *Reader, Writer, *Writer]{}
+
+// FixedFormat is the binary encoding with fixed-width integers and floats
+var FixedFormat inspect.Format = inspect.BinaryFormat[FixedReader, *FixedReader, FixedWriter, *FixedWriter]{}
+
+func init() {
+	inspect.RegisterFormat("binary", Format, "application/x-inspect")
+	inspect.RegisterFormat("binary-fixed", FixedFormat, "application/x-inspect-fixed")
+	var _ inspect.Reader = (*FixedReader)(nil)
+	var _ inspect.Writer = (*FixedWriter)(nil)
+}
@@ -0,0 +1,232 @@
+package binary_test
+
+import (
+	"bytes"
+	encoding "encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/binary"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+)
+
+type entry struct {
+	id    int64
+	label string
+}
+
+func (e *entry) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("entry", "labelled id")
+	o.Int64("id", &e.id, true, "id")
+	o.String("label", &e.label, true, "label")
+	o.End()
+}
+
+type fixture struct {
+	enabled bool
+	count   int
+	name    string
+	entries []entry
+}
+
+func (f *fixture) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("fixture", "every kind of value")
+	o.Bool("enabled", &f.enabled, true, "flag")
+	o.Int("count", &f.count, true, "signed count")
+	o.String("name", &f.name, true, "name")
+	inspect.PropertyArray[*entry](o, "entries", &f.entries, "entry", true, "entries")
+	o.End()
+}
+
+func (f *fixture) equal(other *fixture) bool {
+	if f.enabled != other.enabled || f.count != other.count || f.name != other.name ||
+		len(f.entries) != len(other.entries) {
+		return false
+	}
+	for i := range f.entries {
+		if f.entries[i] != other.entries[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// baseline is fixture in the format written before property values could be framed
+var baseline = []byte{1, 5, 4, 'a', 'b', 4, 2, 2, 'x', 0xd8, 0x04, 0}
+
+func TestBaselineFormat(t *testing.T) {
+	expected := fixture{enabled: true, count: -3, name: "ab", entries: []entry{{1, "x"}, {300, ""}}}
+	reader := inspect.NewInspector(new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader]))
+	reader.SetReader(bytes.NewReader(baseline))
+	var value fixture
+	value.Inspect(reader)
+	if reader.LastError() != nil || !value.equal(&expected) {
+		t.Fatal("got", value, reader.LastError())
+	}
+	writer := inspect.NewInspector(new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer]))
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil || !bytes.Equal(buffer.Bytes(), baseline) {
+		t.Fatal("got", buffer.Bytes(), writer.LastError())
+	}
+}
+
+type legacy struct {
+	name string
+}
+
+func (l *legacy) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("legacy", "object with a skipped property")
+	o.String("name", &l.name, true, "name")
+	if old := o.Property("old", true, "removed"); old != nil {
+		old.Skip()
+	}
+	o.End()
+}
+
+func TestSkipUnframed(t *testing.T) {
+	value := legacy{name: "a"}
+	var buffer bytes.Buffer
+	writer := inspect.NewInspector(new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer]))
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	if !errors.Is(writer.LastError(), binary.ErrNotSkippable) {
+		t.Fatal("got", writer.LastError())
+	}
+	reader := inspect.NewInspector(new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader]))
+	reader.SetReader(bytes.NewReader([]byte{2, 'a', 0}))
+	value.Inspect(reader)
+	if !errors.Is(reader.LastError(), binary.ErrNotSkippable) {
+		t.Fatal("got", reader.LastError())
+	}
+
+	writeInspector := new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer])
+	writeInspector.Writer().SetFramed(true)
+	writer = inspect.NewInspector(writeInspector)
+	buffer.Reset()
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	// the skipped property is an empty frame
+	if writer.LastError() != nil || !bytes.Equal(buffer.Bytes(), []byte{2, 2, 'a', 0}) {
+		t.Fatal("got", buffer.Bytes(), writer.LastError())
+	}
+}
+
+func TestFrameLength(t *testing.T) {
+	readInspector := new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader])
+	readInspector.Reader().SetFramed(true)
+	reader := inspect.NewInspector(readInspector)
+	for _, input := range [][]byte{
+		// a frame of math.MaxInt64 bytes would end past the largest offset
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, 2, 'a', 0},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 2, 'a', 0},
+	} {
+		reader.SetReader(bytes.NewReader(input))
+		var value legacy
+		value.Inspect(reader)
+		if !errors.Is(reader.LastError(), binary.ErrInvalidLength) {
+			t.Fatal(input, "got", reader.LastError())
+		}
+	}
+	readInspector.SetLimits(inspect.Limits{MaxTotalBytes: 64})
+	reader.SetReader(bytes.NewReader([]byte{0x80, 0x01, 2, 'a', 0}))
+	var value legacy
+	value.Inspect(reader)
+	if !errors.Is(reader.LastError(), inspect.ErrLimitExceeded) {
+		t.Fatal("got", reader.LastError())
+	}
+}
+
+type widths struct {
+	small  int8
+	short  uint16
+	medium int32
+	large  uint64
+	single float32
+	name   string
+}
+
+func (w *widths) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("widths", "integers and floats of every width")
+	o.Int8("small", &w.small, true, "int8")
+	o.Uint16("short", &w.short, true, "uint16")
+	o.Int32("medium", &w.medium, true, "int32")
+	o.Uint64("large", &w.large, true, "uint64")
+	o.Float32("single", &w.single, 'g', -1, true, "float32")
+	o.String("name", &w.name, true, "name")
+	o.End()
+}
+
+func TestFixedWidths(t *testing.T) {
+	value := widths{small: -128, short: 0xfffe, medium: -2, large: 1 << 63, single: 1, name: "ab"}
+	expected := []byte{0x80, 0xfe, 0xff, 0xfe, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0x80,
+		0, 0, 0x80, 0x3f, 4, 'a', 'b'}
+	data, err := inspect.Marshal(&value, binary.FixedFormat)
+	if err != nil || !bytes.Equal(data, expected) {
+		t.Fatal("got", data, err)
+	}
+	var result widths
+	if err := inspect.Unmarshal(data, &result, binary.FixedFormat); err != nil || result != value {
+		t.Fatal("got", result, err)
+	}
+	// every value cut short, the string length is a varint in both modes
+	for _, end := range []int{0, 2, 6, 14, 18, 20} {
+		if err := inspect.Unmarshal(data[:end+1], &result, binary.FixedFormat); err == nil {
+			t.Fatal(end, "got", result)
+		}
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add(baseline)
+	f.Add([]byte{0x80, 0xfe, 0xff, 0xfe, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0x80, 0, 0, 0x80, 0x3f, 4, 'a', 'b'})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		limits := inspect.WithLimits(inspect.Limits{MaxDepth: 16, MaxArrayLength: 1 << 10})
+		var value fixture
+		inspect.Unmarshal(data, &value, binary.Format, limits)
+		var fixed widths
+		inspect.Unmarshal(data, &fixed, binary.FixedFormat, limits)
+	})
+}
+
+type tick struct {
+	sequence uint32
+	price    float64
+	volume   int64
+}
+
+func (t *tick) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("tick", "market data")
+	o.Varint().Uint32("sequence", &t.sequence, true, "small counter")
+	o.Float64("price", &t.price, 'g', -1, true, "price")
+	o.Int64("volume", &t.volume, true, "volume")
+	o.End()
+}
+
+func TestFixed(t *testing.T) {
+	value := tick{sequence: 5, price: 1.5, volume: -2}
+	data, err := inspect.Marshal(&value, binary.FixedFormat)
+	expected := []byte{5, 0, 0, 0, 0, 0, 0, 0, 0, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	encoding.LittleEndian.PutUint64(expected[1:], math.Float64bits(1.5))
+	if err != nil || !bytes.Equal(data, expected) {
+		t.Fatal("got", data, err)
+	}
+	var result tick
+	if err := inspect.Unmarshal(data, &result, binary.FixedFormat); err != nil || result != value {
+		t.Fatal("got", result, err)
+	}
+	inspecttest.Nested(t, binary.FixedFormat.NewWriter(), binary.FixedFormat.NewReader())
+}
+
+func TestSkipFixed(t *testing.T) {
+	writer := new(inspect.BinaryWriteInspector[binary.FixedWriter, *binary.FixedWriter])
+	writer.Writer().SetFramed(true)
+	reader := new(inspect.BinaryReadInspector[binary.FixedReader, *binary.FixedReader])
+	reader.Reader().SetFramed(true)
+	inspecttest.Skip(t, inspect.NewInspector(writer), inspect.NewInspector(reader))
+}
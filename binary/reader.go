@@ -1,100 +1,359 @@
 package binary
 
 import (
-	"bufio"
 	"encoding/binary"
 	"io"
 	"math"
+
+	"github.com/tvanomr/inspect"
 )
 
+// frame is the byte range of a property value, end is -1 outside of a property
+type frame struct {
+	start int64
+	end   int64
+}
+
+var noFrame = frame{start: -1, end: -1}
+
 type Reader struct {
-	reader *bufio.Reader
+	reader countingReader
+	limits inspect.Limits
+	// property values are prefixed with their length, see Writer.SetFramed
+	framed bool
+	// frame of the property being read in the current object
+	current frame
+	frames  stack[frame]
+	// number of objects, arrays and maps open
+	depth int
+	// fixed-width integers and floats, see Writer.SetFixed
+	fixed       bool
+	varint      bool
+	nextVarint  bool
+	varints     stack[bool]
+	fixedBuffer [8]byte
+}
+
+// SetFramed reads property values written by a Writer with SetFramed.
+// The stream doesn't tell whether it is framed: with a setting other than
+// the writer's, values are misread, mostly without an error
+func (r *Reader) SetFramed(framed bool) {
+	r.framed = framed
+}
+
+// SetFixed reads integers and floats written by a Writer in fixed mode
+func (r *Reader) SetFixed(fixed bool) {
+	r.fixed = fixed
+}
+func (r *Reader) UseVarint() {
+	r.nextVarint = true
 }
 
 func (r *Reader) SetReader(reader io.Reader) {
-	r.reader = bufio.NewReader(reader)
+	r.reader.reset(reader)
+	r.reader.limits = &r.limits
+	r.current = noFrame
+	r.frames = r.frames[:0]
+	r.depth = 0
+	r.varint = false
+	r.nextVarint = false
+	r.varints = r.varints[:0]
+}
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+func (r *Reader) Offset() int64 {
+	return r.reader.offset
+}
+
+// fixedInts tells whether integers are read with a fixed width
+func (r *Reader) fixedInts() bool {
+	return r.fixed && !r.varint
+}
+
+// readFixed reads size bytes little endian
+func (r *Reader) readFixed(size int) (uint64, error) {
+	r.fixedBuffer = [8]byte{}
+	if _, err := io.ReadFull(&r.reader, r.fixedBuffer[:size]); err != nil {
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(r.fixedBuffer[:]), nil
+}
+func (r *Reader) Bool() (bool, error) {
+	result, err := r.reader.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch result {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	}
+	return false, ErrInvalidBool
+}
+func (r *Reader) Int8() (int8, error) {
+	if r.fixedInts() {
+		result, err := r.readFixed(1)
+		return int8(result), err
+	}
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	if r.fixedInts() {
+		result, err := r.readFixed(2)
+		return int16(result), err
+	}
+	return readNarrowInt[int16](r)
 }
 func (r *Reader) Int32() (int32, error) {
-	result, err := binary.ReadVarint(r.reader)
+	if r.fixedInts() {
+		result, err := r.readFixed(4)
+		return int32(result), err
+	}
+	return readNarrowInt[int32](r)
+}
+func (r *Reader) Int64() (int64, error) {
+	if r.fixedInts() {
+		result, err := r.readFixed(8)
+		return int64(result), err
+	}
+	result, err := binary.ReadVarint(&r.reader)
 	if err != nil {
 		return 0, err
 	}
-	return int32(result), nil
+	return result, nil
 }
-func (r *Reader) Int64() (int64, error) {
-	result, err := binary.ReadVarint(r.reader)
+func (r *Reader) Uint8() (uint8, error) {
+	if r.fixedInts() {
+		result, err := r.readFixed(1)
+		return uint8(result), err
+	}
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	if r.fixedInts() {
+		result, err := r.readFixed(2)
+		return uint16(result), err
+	}
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	if r.fixedInts() {
+		result, err := r.readFixed(4)
+		return uint32(result), err
+	}
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	if r.fixedInts() {
+		return r.readFixed(8)
+	}
+	result, err := binary.ReadUvarint(&r.reader)
 	if err != nil {
 		return 0, err
 	}
 	return result, nil
 }
 func (r *Reader) Float32() (float32, error) {
-	result, err := binary.ReadUvarint(r.reader)
+	if r.fixed {
+		result, err := r.readFixed(4)
+		return math.Float32frombits(uint32(result)), err
+	}
+	result, err := binary.ReadUvarint(&r.reader)
 	if err != nil {
 		return 0, err
 	}
 	return math.Float32frombits(uint32(result)), nil
 }
 func (r *Reader) Float64() (float64, error) {
-	result, err := binary.ReadUvarint(r.reader)
+	if r.fixed {
+		result, err := r.readFixed(8)
+		return math.Float64frombits(result), err
+	}
+	result, err := binary.ReadUvarint(&r.reader)
 	if err != nil {
 		return 0, err
 	}
 	return math.Float64frombits(result), err
 }
 func (r *Reader) String() (string, error) {
-	length, err := binary.ReadVarint(r.reader)
+	buffer, err := r.Bytes()
 	if err != nil {
 		return "", err
 	}
-	buffer := make([]byte, length)
-	read, err := io.ReadFull(r.reader, buffer)
+	return string(buffer), nil
+}
+
+// readLength reads a length prefix, the limits are checked before anything is allocated
+func (r *Reader) readLength(check func(length int64) error) (int64, error) {
+	length, err := binary.ReadVarint(&r.reader)
 	if err != nil {
-		return "", err
+		return 0, err
 	}
-	if read < int(length) {
-		return "", ErrShortRead
+	if length < 0 {
+		return 0, ErrInvalidLength
 	}
-	return string(buffer), nil
+	return length, check(length)
 }
 func (r *Reader) Bytes() ([]byte, error) {
-	length, err := binary.ReadVarint(r.reader)
+	length, err := r.readLength(r.limits.CheckString)
 	if err != nil {
 		return nil, err
 	}
-	buffer := make([]byte, length)
-	read, err := io.ReadFull(r.reader, buffer)
-	if err != nil {
+	if err := r.limits.CheckTotal(r.reader.offset + length); err != nil {
 		return nil, err
 	}
-	if read < int(length) {
+	buffer, err := inspect.ReadFull(&r.reader, length)
+	if err == io.ErrUnexpectedEOF {
 		return nil, ErrShortRead
 	}
-	return buffer, nil
+	return buffer, err
 }
 func (r *Reader) ByteString() ([]byte, error) {
 	return r.Bytes()
 }
+func (r *Reader) readMarker() (bool, error) {
+	result, err := r.reader.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch result {
+	case markerAbsent:
+		return false, nil
+	case markerPresent:
+		return true, nil
+	}
+	return false, ErrInvalidMarker
+}
+func (r *Reader) IsNull() (bool, error) {
+	present, err := r.readMarker()
+	return !present, err
+}
+
+// enter opens an object, an array or a map
+func (r *Reader) enter() error {
+	if err := r.limits.CheckDepth(r.depth + 1); err != nil {
+		return err
+	}
+	r.depth++
+	return nil
+}
+func (r *Reader) leave() {
+	if r.depth > 0 {
+		r.depth--
+	}
+}
 func (r *Reader) StartObject() error {
+	if err := r.enter(); err != nil {
+		return err
+	}
+	r.frames.push(r.current)
+	r.current = noFrame
+	r.varints.push(r.varint)
+	r.varint = false
 	return nil
 }
-func (r *Reader) Property(name string) error {
+
+// closeFrame moves past the rest of the current property value,
+// so values extended by a newer writer can still be read
+func (r *Reader) closeFrame() error {
+	if r.current.end < 0 {
+		return nil
+	}
+	end := r.current.end
+	r.current = noFrame
+	if r.reader.offset > end {
+		return ErrFrameOverrun
+	}
+	return r.reader.discard(end - r.reader.offset)
+}
+func (r *Reader) openFrame() error {
+	if !r.framed {
+		return nil
+	}
+	length, err := binary.ReadUvarint(&r.reader)
+	if err != nil {
+		return err
+	}
+	if length > uint64(math.MaxInt64-r.reader.offset) {
+		return ErrInvalidLength
+	}
+	end := r.reader.offset + int64(length)
+	if err := r.limits.CheckTotal(end); err != nil {
+		return err
+	}
+	r.current = frame{start: r.reader.offset, end: end}
 	return nil
 }
+
+// selectVarint applies UseVarint to the property that starts
+func (r *Reader) selectVarint() {
+	r.varint = r.nextVarint
+	r.nextVarint = false
+}
+func (r *Reader) Property(name string) error {
+	r.selectVarint()
+	err := r.closeFrame()
+	if err != nil {
+		return err
+	}
+	return r.openFrame()
+}
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	r.selectVarint()
+	err := r.closeFrame()
+	if err != nil {
+		return false, err
+	}
+	present, err := r.readMarker()
+	if err != nil || !present {
+		return false, err
+	}
+	return true, r.openFrame()
+}
+
+// UnknownFields only skips, properties are positional and nothing in the input tells
+// an unknown one, so FailOnUnknown and CollectUnknown return inspect.ErrUnknownFieldsUnsupported
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if policy != inspect.SkipUnknown {
+		return nil, inspect.ErrUnknownFieldsUnsupported
+	}
+	return nil, nil
+}
 func (r *Reader) EndObject() error {
-	return nil
+	r.leave()
+	err := r.closeFrame()
+	if len(r.frames) > 0 {
+		r.current = r.frames.pop()
+		r.varint = r.varints.pop()
+	}
+	return err
+}
+
+// Skip discards a property value, values outside of properties carry no length
+// and can't be skipped
+func (r *Reader) Skip() error {
+	if r.current.end < 0 || r.reader.offset != r.current.start {
+		return ErrNotSkippable
+	}
+	return r.reader.discard(r.current.end - r.reader.offset)
 }
 func (r *Reader) StartArray() (length int, err error) {
-	result, err := binary.ReadVarint(r.reader)
-	if err != nil {
+	result, err := r.readLength(r.limits.CheckArray)
+	if err != nil || result == 0 {
 		return 0, err
 	}
-	return int(result), nil
+	return int(result), r.enter()
 }
 func (r *Reader) HaveNext() (bool, error) {
 	return false, nil
 }
 func (r *Reader) EndArray() error {
+	r.leave()
 	return nil
 }
 func (r *Reader) StartMap() (length int, err error) {
@@ -104,5 +363,27 @@ func (r *Reader) NextKey() (string, error) {
 	return r.String()
 }
 func (r *Reader) EndMap() error {
+	r.leave()
 	return nil
 }
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := binary.ReadVarint(&r.reader)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](result)
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := binary.ReadUvarint(&r.reader)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+	var _ inspect.Varints = (*Reader)(nil)
+}
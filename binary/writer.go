@@ -4,91 +4,273 @@ import (
 	"encoding/binary"
 	"io"
 	"math"
+
+	"github.com/tvanomr/inspect"
+)
+
+// presence markers written before nullable values and optional properties
+const (
+	markerAbsent  byte = 0
+	markerPresent byte = 1
 )
 
 type Writer struct {
 	writer        io.Writer
+	bufferSize    int
+	buffer        []byte
 	variantBuffer []byte
+	// property values are prefixed with their length
+	framed bool
+	// start of the open property value in the current object, -1 when there is none
+	frame      int
+	frames     stack[int]
+	openFrames int
+	// fixed-width integers and floats instead of varints
+	fixed bool
+	// integers of the current property are varints in fixed mode,
+	// nextVarint is set by UseVarint for the following property
+	varint     bool
+	nextVarint bool
+	varints    stack[bool]
+}
+
+// SetFramed prefixes every property value with its length, so readers can skip
+// values they don't know. The stream doesn't tell whether it is framed, a Reader
+// has to be given the same setting or it misreads values, mostly without an error
+func (w *Writer) SetFramed(framed bool) {
+	w.framed = framed
+}
+
+// SetFixed writes integers and floats little endian with their own width,
+// lengths and counts stay varints
+func (w *Writer) SetFixed(fixed bool) {
+	w.fixed = fixed
+}
+func (w *Writer) UseVarint() {
+	w.nextVarint = true
 }
 
 func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
 	w.writer = writer
+	w.bufferSize = bufferSize
+	w.buffer = w.buffer[:0]
 	w.variantBuffer = make([]byte, 10)
+	w.frame = -1
+	w.frames = w.frames[:0]
+	w.openFrames = 0
+	w.varint = false
+	w.nextVarint = false
+	w.varints = w.varints[:0]
+}
+
+// write appends to the buffer, the buffer can only be written out when
+// no property value is open because property values are prefixed with their length
+func (w *Writer) write(data []byte) error {
+	w.buffer = append(w.buffer, data...)
+	if w.openFrames == 0 && len(w.buffer) > w.bufferSize {
+		return w.Flush()
+	}
+	return nil
 }
 
 func (w *Writer) writeBuffer(length int) error {
-	written, err := w.writer.Write(w.variantBuffer[:length])
-	if err != nil {
-		return err
+	return w.write(w.variantBuffer[:length])
+}
+
+// fixedInts tells whether integers are written with a fixed width
+func (w *Writer) fixedInts() bool {
+	return w.fixed && !w.varint
+}
+
+// writeFixed writes the low size bytes of value little endian
+func (w *Writer) writeFixed(value uint64, size int) error {
+	binary.LittleEndian.PutUint64(w.variantBuffer, value)
+	return w.writeBuffer(size)
+}
+
+// writeLength writes lengths and counts, which are varints in both modes
+func (w *Writer) writeLength(length int) error {
+	return w.writeBuffer(binary.PutVarint(w.variantBuffer, int64(length)))
+}
+
+func (w *Writer) openFrame() {
+	if !w.framed {
+		return
 	}
-	if written < length {
-		return ErrShortWrite
+	w.frame = len(w.buffer)
+	w.openFrames++
+	// one byte is enough for the length of most values, longer ones are shifted on close
+	w.buffer = append(w.buffer, 0)
+}
+
+func (w *Writer) closeFrame() error {
+	if w.frame < 0 {
+		return nil
 	}
-	return nil
+	start := w.frame
+	w.frame = -1
+	w.openFrames--
+	length := binary.PutUvarint(w.variantBuffer, uint64(len(w.buffer)-start-1))
+	if length > 1 {
+		end := len(w.buffer)
+		w.buffer = append(w.buffer, w.variantBuffer[1:length]...)
+		copy(w.buffer[start+length:], w.buffer[start+1:end])
+	}
+	copy(w.buffer[start:], w.variantBuffer[:length])
+	return w.write(nil)
 }
 
+func (w *Writer) Bool(value bool) error {
+	if value {
+		w.variantBuffer[0] = 1
+	} else {
+		w.variantBuffer[0] = 0
+	}
+	return w.writeBuffer(1)
+}
+func (w *Writer) Int8(value int8) error {
+	if w.fixedInts() {
+		return w.writeFixed(uint64(value), 1)
+	}
+	return w.writeBuffer(binary.PutVarint(w.variantBuffer, int64(value)))
+}
+func (w *Writer) Int16(value int16) error {
+	if w.fixedInts() {
+		return w.writeFixed(uint64(value), 2)
+	}
+	return w.writeBuffer(binary.PutVarint(w.variantBuffer, int64(value)))
+}
 func (w *Writer) Int32(value int32) error {
+	if w.fixedInts() {
+		return w.writeFixed(uint64(value), 4)
+	}
 	return w.writeBuffer(binary.PutVarint(w.variantBuffer, int64(value)))
 }
 func (w *Writer) Int64(value int64) error {
+	if w.fixedInts() {
+		return w.writeFixed(uint64(value), 8)
+	}
 	return w.writeBuffer(binary.PutVarint(w.variantBuffer, value))
 }
+func (w *Writer) Uint8(value uint8) error {
+	if w.fixedInts() {
+		return w.writeFixed(uint64(value), 1)
+	}
+	return w.writeBuffer(binary.PutUvarint(w.variantBuffer, uint64(value)))
+}
+func (w *Writer) Uint16(value uint16) error {
+	if w.fixedInts() {
+		return w.writeFixed(uint64(value), 2)
+	}
+	return w.writeBuffer(binary.PutUvarint(w.variantBuffer, uint64(value)))
+}
+func (w *Writer) Uint32(value uint32) error {
+	if w.fixedInts() {
+		return w.writeFixed(uint64(value), 4)
+	}
+	return w.writeBuffer(binary.PutUvarint(w.variantBuffer, uint64(value)))
+}
+func (w *Writer) Uint64(value uint64) error {
+	if w.fixedInts() {
+		return w.writeFixed(value, 8)
+	}
+	return w.writeBuffer(binary.PutUvarint(w.variantBuffer, value))
+}
+
+// Float32 writes IEEE 754 bits, as a varint unless in fixed mode
 func (w *Writer) Float32(value float32, format byte, precision int) error {
+	if w.fixed {
+		return w.writeFixed(uint64(math.Float32bits(value)), 4)
+	}
 	return w.writeBuffer(binary.PutUvarint(w.variantBuffer, uint64(math.Float32bits(value))))
 }
+
+// Float64 writes IEEE 754 bits, as a varint unless in fixed mode
 func (w *Writer) Float64(value float64, format byte, precision int) error {
+	if w.fixed {
+		return w.writeFixed(math.Float64bits(value), 8)
+	}
 	return w.writeBuffer(binary.PutUvarint(w.variantBuffer, math.Float64bits(value)))
 }
 func (w *Writer) String(value string) error {
-	length := len(value)
-	err := w.Int64(int64(length))
+	err := w.writeLength(len(value))
 	if err != nil {
 		return err
 	}
-	written, err := w.writer.Write([]byte(value))
-	if err != nil {
-		return err
-	}
-	if written < length {
-		return ErrShortWrite
-	}
-	return nil
+	w.buffer = append(w.buffer, value...)
+	return w.write(nil)
 }
 func (w *Writer) Bytes(value []byte) error {
-	length := len(value)
-	err := w.Int64(int64(length))
-	if err != nil {
-		return err
-	}
-	written, err := w.writer.Write(value)
+	err := w.writeLength(len(value))
 	if err != nil {
 		return err
 	}
-	if written < length {
-		return ErrShortWrite
-	}
-	return nil
+	return w.write(value)
 }
 func (w *Writer) ByteString(value []byte) error {
 	return w.Bytes(value)
 }
+func (w *Writer) writeMarker(marker byte) error {
+	w.variantBuffer[0] = marker
+	return w.writeBuffer(1)
+}
+func (w *Writer) Null() error {
+	return w.writeMarker(markerAbsent)
+}
+func (w *Writer) NotNull() error {
+	return w.writeMarker(markerPresent)
+}
 func (w *Writer) StartObject() error {
+	w.frames.push(w.frame)
+	w.frame = -1
+	w.varints.push(w.varint)
+	w.varint = false
 	return nil
 }
+
+// selectVarint applies UseVarint to the property that starts
+func (w *Writer) selectVarint() {
+	w.varint = w.nextVarint
+	w.nextVarint = false
+}
 func (w *Writer) Property(name string) error {
-	return nil
+	w.selectVarint()
+	err := w.closeFrame()
+	w.openFrame()
+	return err
+}
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	w.selectVarint()
+	err := w.closeFrame()
+	if err != nil {
+		return err
+	}
+	if !present {
+		return w.writeMarker(markerAbsent)
+	}
+	err = w.writeMarker(markerPresent)
+	w.openFrame()
+	return err
+}
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	return ErrRawField
 }
 func (w *Writer) EndObject() error {
-	return nil
+	err := w.closeFrame()
+	if len(w.frames) > 0 {
+		w.frame = w.frames.pop()
+		w.varint = w.varints.pop()
+	}
+	return err
 }
 func (w *Writer) StartArray(length int) error {
-	return w.Int64(int64(length))
+	return w.writeLength(length)
 }
 func (w *Writer) EndArray() error {
 	return nil
 }
 func (w *Writer) StartMap(length int) error {
-	return w.Int64(int64(length))
+	return w.writeLength(length)
 }
 func (w *Writer) NextKey(key string) error {
 	return w.String(key)
@@ -96,7 +278,34 @@ func (w *Writer) NextKey(key string) error {
 func (w *Writer) EndMap() error {
 	return nil
 }
+
+// Skip writes nothing, the empty frame of the property is what the reader skips
+func (w *Writer) Skip() error {
+	if w.frame < 0 || len(w.buffer) != w.frame+1 {
+		return ErrNotSkippable
+	}
+	return nil
+}
 func (w *Writer) Flush() error {
-	//this writer is not buffered
+	if w.openFrames > 0 || len(w.buffer) == 0 {
+		return nil
+	}
+	written, err := w.writer.Write(w.buffer)
+	if err != nil {
+		return err
+	}
+	if written < len(w.buffer) {
+		return ErrShortWrite
+	}
+	w.buffer = w.buffer[:0]
 	return nil
 }
+
+func (w *Writer) SortKeys() bool {
+	return false
+}
+
+func init() {
+	var _ inspect.Writer = (*Writer)(nil)
+	var _ inspect.Varints = (*Writer)(nil)
+}
@@ -0,0 +1,37 @@
+package cbor
+
+// major types, the top three bits of the first byte of a data item
+const (
+	majorUint     byte = 0
+	majorNegative byte = 1
+	majorBytes    byte = 2
+	majorText     byte = 3
+	majorArray    byte = 4
+	majorMap      byte = 5
+	majorTag      byte = 6
+	majorSimple   byte = 7
+)
+
+// additional information, the low five bits of the first byte
+const (
+	infoUint8      byte = 24
+	infoUint16     byte = 25
+	infoUint32     byte = 26
+	infoUint64     byte = 27
+	infoIndefinite byte = 31
+)
+
+// first bytes of the simple values used
+const (
+	codeFalse   byte = 0xf4
+	codeTrue    byte = 0xf5
+	codeNull    byte = 0xf6
+	codeFloat16 byte = 0xf9
+	codeFloat32 byte = 0xfa
+	codeFloat64 byte = 0xfb
+	codeBreak   byte = 0xff
+)
+
+func major(code byte) byte {
+	return code >> 5
+}
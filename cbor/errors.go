@@ -0,0 +1,58 @@
+package cbor
+
+import "github.com/tvanomr/inspect"
+
+type cborError int
+
+const (
+	ErrShortWrite cborError = iota
+	ErrNoObject
+	ErrNotAnObject
+	ErrNotABool
+	ErrNotANumber
+	ErrNotAString
+	ErrNotBytes
+	ErrNotAnArray
+	ErrNotAMap
+	ErrObjectTooBig
+	ErrArrayTooBig
+	ErrMapTooBig
+	ErrInvalidCode
+	ErrUnexpectedBreak
+	ErrIndefiniteLength
+	ErrTooLong
+	ErrRawField
+)
+
+var errorMessages = map[cborError]string{
+	ErrShortWrite:       "short write",
+	ErrNoObject:         "Property() call outside of object",
+	ErrNotAnObject:      "value is not an object",
+	ErrNotABool:         "value is not a boolean",
+	ErrNotANumber:       "value is not a number",
+	ErrNotAString:       "value is not a text string",
+	ErrNotBytes:         "value is neither a byte string nor a text string",
+	ErrNotAnArray:       "value is not an array",
+	ErrNotAMap:          "value is not a map",
+	ErrObjectTooBig:     "object contains more fields than requested",
+	ErrArrayTooBig:      "array contains more items than was read",
+	ErrMapTooBig:        "map contains more items than was read",
+	ErrInvalidCode:      "invalid CBOR initial byte",
+	ErrUnexpectedBreak:  "break outside of an indefinite length item",
+	ErrIndefiniteLength: "deterministic encoding needs the length of arrays and maps",
+	ErrTooLong:          "length is too large",
+	ErrRawField:         "fields collected from another format can't be written"}
+
+func (c cborError) Error() string {
+	return errorMessages[c]
+}
+
+func (c cborError) Is(target error) bool {
+	switch c {
+	case ErrNotABool, ErrNotANumber, ErrNotAString, ErrNotBytes, ErrNotAnArray, ErrNotAMap:
+		return target == inspect.ErrTypeMismatch
+	case ErrObjectTooBig:
+		return target == inspect.ErrUnknownField
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package cbor
+
+import "math"
+
+// halfBits converts value to a half precision float when that loses nothing
+func halfBits(value float32) (uint16, bool) {
+	bits := math.Float32bits(value)
+	sign := uint16(bits>>16) & 0x8000
+	exponent := int(bits>>23&0xff) - 127
+	mantissa := bits & 0x7fffff
+	switch {
+	case bits&0x7fffffff == 0:
+		return sign, true
+	case exponent == 128:
+		if mantissa != 0 {
+			return 0x7e00, true
+		}
+		return sign | 0x7c00, true
+	case exponent >= -14 && exponent <= 15:
+		if mantissa&0x1fff != 0 {
+			return 0, false
+		}
+		return sign | uint16(exponent+15)<<10 | uint16(mantissa>>13), true
+	case exponent >= -24 && exponent < -14:
+		// subnormal, value is full * 2^(exponent-23) and becomes half * 2^-24
+		full := mantissa | 0x800000
+		shift := -(exponent + 1)
+		if full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		return sign | uint16(full>>shift), true
+	}
+	return 0, false
+}
+
+func halfToFloat(bits uint16) float64 {
+	exponent := int(bits >> 10 & 0x1f)
+	mantissa := float64(bits & 0x3ff)
+	var result float64
+	switch exponent {
+	case 0:
+		result = math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa != 0 {
+			return math.NaN()
+		}
+		result = math.Inf(1)
+	default:
+		result = math.Ldexp(mantissa+0x400, exponent-25)
+	}
+	if bits&0x8000 != 0 {
+		return -result
+	}
+	return result
+}
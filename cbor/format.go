@@ -0,0 +1,13 @@
+package cbor
+
+import "github.com/tvanomr/inspect"
+
+// Format is CBOR for inspect.Marshal, inspect.Unmarshal, inspect.Encode and inspect.Decode
+var Format inspect.Format = inspect.BinaryFormat[Reader, *Reader, Writer, *Writer]{}
+
+// formatName tags the fields collected by Reader, Writer accepts only those
+const formatName = "cbor"
+
+func init() {
+	inspect.RegisterFormat(formatName, Format, "application/cbor")
+}
@@ -0,0 +1,83 @@
+package cbor_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/cbor"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+)
+
+func TestEncoding(t *testing.T) {
+	// indefinite length arrays, including an empty one
+	for _, test := range []struct {
+		input    []byte
+		expected inspecttest.Numbers
+	}{
+		{[]byte{0x9f, 0x01, 0x02, 0x20, 0xff}, inspecttest.Numbers{1, 2, -1}},
+		{[]byte{0x9f, 0xff}, inspecttest.Numbers{}},
+		{[]byte{0x83, 0x01, 0x18, 0x19, 0x39, 0x01, 0xf3}, inspecttest.Numbers{1, 25, -500}},
+	} {
+		var result inspecttest.Numbers
+		if err := inspect.Unmarshal(test.input, &result, cbor.Format); err != nil ||
+			len(result) != len(test.expected) || (len(result) > 0 && result[len(result)-1] != test.expected[len(result)-1]) {
+			t.Fatal(test.input, "got", result, err)
+		}
+	}
+
+	// examples from RFC 8949 appendix A in deterministic encoding
+	writeInspector := new(inspect.BinaryWriteInspector[cbor.Writer, *cbor.Writer])
+	writeInspector.Writer().SetDeterministic(true)
+	writer := inspect.NewInspector(writeInspector)
+	for _, test := range []struct {
+		value    inspecttest.Real
+		expected []byte
+	}{
+		{1.5, []byte{0xf9, 0x3e, 0x00}},
+		{65504, []byte{0xf9, 0x7b, 0xff}},
+		{100000, []byte{0xfa, 0x47, 0xc3, 0x50, 0x00}},
+		{1.1, []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}},
+		{5.960464477539063e-8, []byte{0xf9, 0x00, 0x01}},
+		{-4, []byte{0xf9, 0xc4, 0x00}},
+	} {
+		var buffer bytes.Buffer
+		writer.SetWriter(&buffer, 0)
+		test.value.Inspect(writer)
+		writer.Flush()
+		if writer.LastError() != nil || !bytes.Equal(buffer.Bytes(), test.expected) {
+			t.Fatal(test.value, "got", buffer.Bytes(), writer.LastError())
+		}
+		var result inspecttest.Real
+		if err := inspect.Unmarshal(buffer.Bytes(), &result, cbor.Format); err != nil || result != test.value {
+			t.Fatal(test.value, "got", result, err)
+		}
+	}
+
+	// deterministic members are ordered by their encoded keys, shorter first
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 0)
+	value := inspecttest.Settings{Name: "n", Count: 1, Mandatory: true}
+	value.Inspect(writer)
+	writer.Flush()
+	expected := []byte{0xa2, 0x64, 'n', 'a', 'm', 'e', 0x61, 'n', 0x65, 'c', 'o', 'u', 'n', 't', 0x01}
+	if !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatal("got", buffer.Bytes())
+	}
+	data, err := inspect.Marshal(&value, cbor.Format)
+	expected = []byte{0xbf, 0x64, 'n', 'a', 'm', 'e', 0x61, 'n', 0x65, 'c', 'o', 'u', 'n', 't', 0x01, 0xff}
+	if err != nil || !bytes.Equal(data, expected) {
+		t.Fatal("got", data, err)
+	}
+}
+
+func TestNested(t *testing.T) {
+	inspecttest.Nested(t, cbor.Format.NewWriter(), cbor.Format.NewReader())
+	writer := new(inspect.BinaryWriteInspector[cbor.Writer, *cbor.Writer])
+	writer.Writer().SetDeterministic(true)
+	inspecttest.Nested(t, inspect.NewInspector(writer), cbor.Format.NewReader())
+}
+
+func TestSkip(t *testing.T) {
+	inspecttest.Skip(t, cbor.Format.NewWriter(), cbor.Format.NewReader())
+}
@@ -0,0 +1,631 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/tvanomr/inspect"
+)
+
+// member is an object member read ahead of time
+type member struct {
+	data []byte
+	// offset of data in the input
+	offset int64
+}
+
+type level struct {
+	isObject bool
+	// items or members not read from the source yet, -1 for indefinite length
+	remaining int64
+	// items or members read, counted for the limits
+	count int64
+	// members skipped while looking for a property that came later in the input
+	buffered map[string]member
+	// names of the buffered members in input order
+	order []string
+	// source to go back to once a buffered member has been read
+	parent *source
+}
+
+type Reader struct {
+	input   source
+	current *source
+	limits  inspect.Limits
+	level   level
+	levels  stack[level]
+}
+
+func (r *Reader) SetReader(reader io.Reader) {
+	r.input.reset(reader)
+	r.input.limits = &r.limits
+	r.current = &r.input
+	r.level = level{}
+	r.levels = r.levels[:0]
+}
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+func (r *Reader) Offset() int64 {
+	return r.current.offset()
+}
+
+// peek returns the first byte of the next data item, tags in front of it are skipped
+func (r *Reader) peek() (byte, error) {
+	for {
+		code, err := r.current.peek()
+		if err != nil || major(code) != majorTag {
+			return code, err
+		}
+		if _, _, err := r.readHead(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// readHead reads the initial byte of a data item and its argument,
+// the argument of an indefinite length item is -1
+func (r *Reader) readHead() (code byte, argument uint64, err error) {
+	code, err = r.current.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	info := code & 0x1f
+	switch {
+	case info < infoUint8:
+		return code, uint64(info), nil
+	case info <= infoUint64:
+		data, err := r.current.read(1 << (info - infoUint8))
+		if err != nil {
+			return 0, 0, err
+		}
+		switch len(data) {
+		case 1:
+			argument = uint64(data[0])
+		case 2:
+			argument = uint64(binary.BigEndian.Uint16(data))
+		case 4:
+			argument = uint64(binary.BigEndian.Uint32(data))
+		default:
+			argument = binary.BigEndian.Uint64(data)
+		}
+		return code, argument, nil
+	case info == infoIndefinite:
+		switch major(code) {
+		case majorBytes, majorText, majorArray, majorMap:
+			return code, math.MaxUint64, nil
+		case majorSimple:
+			return 0, 0, ErrUnexpectedBreak
+		}
+	}
+	return 0, 0, ErrInvalidCode
+}
+
+// readItem consumes the head of the next data item when its first byte passes check,
+// otherwise the item is left in the input and mismatch is returned
+func (r *Reader) readItem(check func(code byte) bool, mismatch error) (byte, uint64, error) {
+	code, err := r.peek()
+	if err != nil {
+		return 0, 0, err
+	}
+	if !check(code) {
+		return 0, 0, mismatch
+	}
+	return r.readHead()
+}
+
+func isIndefinite(argument uint64) bool {
+	return argument == math.MaxUint64
+}
+
+// length converts the argument of a string, an array or a map
+func length(argument uint64) (int64, error) {
+	if argument > math.MaxInt32 {
+		return 0, ErrTooLong
+	}
+	return int64(argument), nil
+}
+
+func (r *Reader) Bool() (bool, error) {
+	code, _, err := r.readItem(func(code byte) bool {
+		return code == codeFalse || code == codeTrue
+	}, ErrNotABool)
+	return code == codeTrue, err
+}
+func (r *Reader) Int8() (int8, error) {
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	return readNarrowInt[int16](r)
+}
+func (r *Reader) Int32() (int32, error) {
+	return readNarrowInt[int32](r)
+}
+
+func isInt(code byte) bool {
+	return major(code) <= majorNegative && code&0x1f <= infoUint64
+}
+
+// readInt reads an integer, negative integers are returned as -1-argument
+func (r *Reader) readInt() (argument uint64, negative bool, err error) {
+	code, argument, err := r.readItem(isInt, ErrNotANumber)
+	return argument, major(code) == majorNegative, err
+}
+func (r *Reader) Int64() (int64, error) {
+	argument, negative, err := r.readInt()
+	if err != nil {
+		return 0, err
+	}
+	if argument > math.MaxInt64 {
+		value := strconv.FormatUint(argument, 10)
+		if negative {
+			value = "-1-" + value
+		}
+		return 0, &inspect.OverflowError{Type: "int64", Value: value}
+	}
+	if negative {
+		return -1 - int64(argument), nil
+	}
+	return int64(argument), nil
+}
+func (r *Reader) Uint8() (uint8, error) {
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	argument, negative, err := r.readInt()
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		return 0, &inspect.OverflowError{Type: "uint64", Value: "-1-" + strconv.FormatUint(argument, 10)}
+	}
+	return argument, nil
+}
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := r.Int64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](result)
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := r.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
+
+// readFloat accepts integers as well, other encoders write whole numbers that way
+func (r *Reader) readFloat() (float64, error) {
+	code, argument, err := r.readItem(func(code byte) bool {
+		return isInt(code) || code == codeFloat16 || code == codeFloat32 || code == codeFloat64
+	}, ErrNotANumber)
+	if err != nil {
+		return 0, err
+	}
+	switch code {
+	case codeFloat16:
+		return halfToFloat(uint16(argument)), nil
+	case codeFloat32:
+		return float64(math.Float32frombits(uint32(argument))), nil
+	case codeFloat64:
+		return math.Float64frombits(argument), nil
+	}
+	if major(code) == majorNegative {
+		return -1 - float64(argument), nil
+	}
+	return float64(argument), nil
+}
+func (r *Reader) Float32() (float32, error) {
+	result, err := r.readFloat()
+	return float32(result), err
+}
+func (r *Reader) Float64() (float64, error) {
+	return r.readFloat()
+}
+
+// readString reads a text string, or a byte string when allowBytes is set,
+// chunks of indefinite length strings are joined
+func (r *Reader) readString(allowBytes bool, mismatch error) ([]byte, error) {
+	code, argument, err := r.readItem(func(code byte) bool {
+		return major(code) == majorText || (allowBytes && major(code) == majorBytes)
+	}, mismatch)
+	if err != nil {
+		return nil, err
+	}
+	if !isIndefinite(argument) {
+		size, err := length(argument)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.limits.CheckString(size); err != nil {
+			return nil, err
+		}
+		data, err := r.current.read(size)
+		return append([]byte(nil), data...), err
+	}
+	var result []byte
+	for {
+		next, err := r.current.peek()
+		if err != nil {
+			return nil, err
+		}
+		if next == codeBreak {
+			_, err = r.current.ReadByte()
+			return result, err
+		}
+		// chunks are definite length strings of the same type
+		if major(next) != major(code) || next&0x1f == infoIndefinite {
+			return nil, ErrInvalidCode
+		}
+		_, argument, err := r.readHead()
+		if err != nil {
+			return nil, err
+		}
+		size, err := length(argument)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.limits.CheckString(int64(len(result)) + size); err != nil {
+			return nil, err
+		}
+		data, err := r.current.read(size)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, data...)
+	}
+}
+func (r *Reader) String() (string, error) {
+	result, err := r.readString(false, ErrNotAString)
+	return string(result), err
+}
+func (r *Reader) Bytes() ([]byte, error) {
+	return r.readString(true, ErrNotBytes)
+}
+func (r *Reader) ByteString() ([]byte, error) {
+	return r.readString(true, ErrNotBytes)
+}
+func (r *Reader) IsNull() (bool, error) {
+	code, err := r.peek()
+	if err != nil || code != codeNull {
+		return false, err
+	}
+	_, err = r.current.ReadByte()
+	return true, err
+}
+
+// startContainer reads the head of an array or a map, remaining is -1 for indefinite length
+func (r *Reader) startContainer(majorType byte, mismatch error) (remaining int64, err error) {
+	_, argument, err := r.readItem(func(code byte) bool {
+		return major(code) == majorType
+	}, mismatch)
+	if err != nil {
+		return 0, err
+	}
+	if isIndefinite(argument) {
+		return -1, nil
+	}
+	remaining, err = length(argument)
+	if err != nil {
+		return 0, err
+	}
+	return remaining, r.limits.CheckArray(remaining)
+}
+func (r *Reader) push(isObject bool, remaining int64) error {
+	if err := r.limits.CheckDepth(len(r.levels) + 1); err != nil {
+		return err
+	}
+	r.levels.push(r.level)
+	r.level = level{isObject: isObject, remaining: remaining}
+	return nil
+}
+func (r *Reader) pop() {
+	r.restore()
+	if len(r.levels) > 0 {
+		r.level = r.levels.pop()
+	}
+}
+
+// restore switches back to the parent source after a buffered member was read
+func (r *Reader) restore() {
+	if r.level.parent != nil {
+		r.current = r.level.parent
+		r.level.parent = nil
+	}
+}
+
+// next tells whether the current array or map has another item and counts it
+func (r *Reader) next() (bool, error) {
+	if r.level.remaining == 0 {
+		return false, nil
+	}
+	if r.level.remaining > 0 {
+		r.level.remaining--
+		return true, nil
+	}
+	code, err := r.current.peek()
+	if err != nil {
+		return false, err
+	}
+	if code == codeBreak {
+		r.level.remaining = 0
+		_, err = r.current.ReadByte()
+		return false, err
+	}
+	r.level.count++
+	return true, r.limits.CheckArray(r.level.count)
+}
+func (r *Reader) StartObject() error {
+	remaining, err := r.startContainer(majorMap, ErrNotAnObject)
+	if err != nil {
+		return err
+	}
+	return r.push(true, remaining)
+}
+
+// findField positions the reader on the value of the named member,
+// buffering every member that precedes it in the input
+func (r *Reader) findField(name string) (bool, error) {
+	r.restore()
+	if value, ok := r.level.buffered[name]; ok {
+		delete(r.level.buffered, name)
+		r.level.parent = r.current
+		r.current = &source{data: value.data, base: value.offset, limits: &r.limits}
+		return true, nil
+	}
+	for {
+		hasNext, err := r.next()
+		if err != nil || !hasNext {
+			return false, err
+		}
+		field, err := r.String()
+		if err != nil {
+			return false, err
+		}
+		if field == name {
+			return true, nil
+		}
+		if r.level.buffered == nil {
+			r.level.buffered = make(map[string]member)
+		}
+		offset := r.current.offset()
+		data, err := r.recordValue()
+		if err != nil {
+			return false, err
+		}
+		r.level.buffered[field] = member{data: data, offset: offset}
+		r.level.order = append(r.level.order, field)
+	}
+}
+func (r *Reader) Property(name string) error {
+	if !r.level.isObject {
+		return ErrNoObject
+	}
+	found, err := r.findField(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return inspect.ErrNoField
+	}
+	return nil
+}
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	if !r.level.isObject {
+		return false, ErrNoObject
+	}
+	return r.findField(name)
+}
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if !r.level.isObject {
+		return nil, ErrNoObject
+	}
+	r.restore()
+	var result []inspect.RawField
+	if len(r.level.buffered) > 0 {
+		if policy == inspect.FailOnUnknown {
+			return nil, ErrObjectTooBig
+		}
+		if policy == inspect.CollectUnknown {
+			for _, name := range r.level.order {
+				if value, ok := r.level.buffered[name]; ok {
+					result = append(result, inspect.RawField{Format: formatName, Name: name, Value: value.data})
+				}
+			}
+		}
+		r.level.buffered = nil
+	}
+	r.level.order = r.level.order[:0]
+	for {
+		hasNext, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if !hasNext {
+			return result, nil
+		}
+		if policy == inspect.FailOnUnknown {
+			return nil, ErrObjectTooBig
+		}
+		field, err := r.String()
+		if err != nil {
+			return nil, err
+		}
+		if policy == inspect.CollectUnknown {
+			var data []byte
+			data, err = r.recordValue()
+			result = append(result, inspect.RawField{Format: formatName, Name: field, Value: data})
+		} else {
+			err = r.Skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+func (r *Reader) EndObject() error {
+	if !r.level.isObject {
+		return ErrNoObject
+	}
+	_, err := r.UnknownFields(inspect.FailOnUnknown)
+	r.pop()
+	return err
+}
+
+// StartArray returns -1 for indefinite length arrays
+func (r *Reader) StartArray() (length int, err error) {
+	remaining, err := r.startContainer(majorArray, ErrNotAnArray)
+	if err != nil || remaining == 0 {
+		return 0, err
+	}
+	if err := r.push(false, remaining); err != nil {
+		return 0, err
+	}
+	if remaining > 0 {
+		return int(remaining), nil
+	}
+	hasNext, err := r.next()
+	if err != nil || hasNext {
+		return -1, err
+	}
+	r.pop()
+	return 0, nil
+}
+func (r *Reader) HaveNext() (bool, error) {
+	if r.level.remaining >= 0 {
+		return false, nil
+	}
+	return r.next()
+}
+func (r *Reader) EndArray() error {
+	if r.level.remaining < 0 {
+		hasNext, err := r.next()
+		if err != nil || hasNext {
+			r.pop()
+			if err != nil {
+				return err
+			}
+			return ErrArrayTooBig
+		}
+	}
+	r.pop()
+	return nil
+}
+
+// StartMap returns -1 for indefinite length maps, NextKey then returns an empty key at the end
+func (r *Reader) StartMap() (length int, err error) {
+	remaining, err := r.startContainer(majorMap, ErrNotAMap)
+	if err != nil || remaining == 0 {
+		return 0, err
+	}
+	if remaining > 0 {
+		// definite lengths are counted by the caller
+		return int(remaining), r.push(false, 0)
+	}
+	return -1, r.push(false, remaining)
+}
+func (r *Reader) NextKey() (string, error) {
+	if r.level.remaining < 0 {
+		hasNext, err := r.next()
+		if err != nil || !hasNext {
+			return "", err
+		}
+	}
+	return r.String()
+}
+func (r *Reader) EndMap() error {
+	if r.level.remaining < 0 {
+		hasNext, err := r.next()
+		if err != nil || hasNext {
+			r.pop()
+			if err != nil {
+				return err
+			}
+			return ErrMapTooBig
+		}
+	}
+	r.pop()
+	return nil
+}
+
+// recordValue skips the next value and returns its encoding
+func (r *Reader) recordValue() ([]byte, error) {
+	var data []byte
+	r.current.record = &data
+	err := r.Skip()
+	r.current.record = nil
+	return data, err
+}
+
+// Skip discards the next data item, nested items are counted rather than recursed into,
+// -1 counts the items of an indefinite length one
+func (r *Reader) Skip() error {
+	pending := []int64{1}
+	for len(pending) > 0 {
+		top := &pending[len(pending)-1]
+		if *top == 0 {
+			pending = pending[:len(pending)-1]
+			continue
+		}
+		if *top < 0 {
+			code, err := r.current.peek()
+			if err != nil {
+				return err
+			}
+			if code == codeBreak {
+				r.current.ReadByte()
+				pending = pending[:len(pending)-1]
+				continue
+			}
+		} else {
+			*top--
+		}
+		code, argument, err := r.readHead()
+		if err != nil {
+			return err
+		}
+		majorType := major(code)
+		if majorType >= majorBytes && majorType <= majorMap && isIndefinite(argument) {
+			pending = append(pending, -1)
+			continue
+		}
+		switch majorType {
+		case majorBytes, majorText:
+			size, err := length(argument)
+			if err != nil {
+				return err
+			}
+			if _, err := r.current.read(size); err != nil {
+				return err
+			}
+		case majorArray, majorMap:
+			items, err := length(argument)
+			if err != nil {
+				return err
+			}
+			if majorType == majorMap {
+				items *= 2
+			}
+			pending = append(pending, items)
+		case majorTag:
+			pending = append(pending, 1)
+		}
+	}
+	return nil
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+}
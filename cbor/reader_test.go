@@ -0,0 +1,170 @@
+package cbor_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/cbor"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+)
+
+type record struct {
+	name string
+	tags inspecttest.Texts
+}
+
+func (r *record) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("record", "named record")
+	o.String("name", &r.name, true, "name")
+	inspect.PropertyObject(o, "tags", &r.tags, false, "tags")
+	o.End()
+}
+
+func TestIndefiniteStrings(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected inspecttest.Text
+	}{
+		{[]byte{0x7f, 0x62, 'a', 'b', 0x61, 'c', 0xff}, "abc"},
+		{[]byte{0x7f, 0xff}, ""},
+		{[]byte{0x7f, 0x60, 0x61, 'a', 0x60, 0xff}, "a"},
+		// a tag in front of the string is ignored
+		{[]byte{0xc0, 0x61, 'a'}, "a"},
+	}
+	for _, test := range tests {
+		var result inspecttest.Text
+		if err := inspect.Unmarshal(test.input, &result, cbor.Format); err != nil || result != test.expected {
+			t.Fatal(test.input, "got", result, err)
+		}
+	}
+	var data inspecttest.Blob
+	if err := inspect.Unmarshal([]byte{0x5f, 0x41, 1, 0x42, 2, 3, 0xff}, &data, cbor.Format); err != nil ||
+		string(data) != "\x01\x02\x03" {
+		t.Fatal("got", data, err)
+	}
+	for _, input := range [][]byte{
+		// chunks of another major type and nested indefinite chunks
+		{0x7f, 0x41, 'a', 0xff},
+		{0x7f, 0x7f, 0xff, 0xff},
+		// no break at the end
+		{0x7f, 0x61, 'a'},
+	} {
+		var result inspecttest.Text
+		if err := inspect.Unmarshal(input, &result, cbor.Format); err == nil {
+			t.Fatal(input, "got", result)
+		}
+	}
+}
+
+func TestIndefiniteContainers(t *testing.T) {
+	var list inspecttest.Texts
+	input := []byte{0x9f, 0x61, 'a', 0x7f, 0x61, 'b', 0xff, 0xff}
+	if err := inspect.Unmarshal(input, &list, cbor.Format); err != nil || len(list) != 2 || list[1] != "b" {
+		t.Fatal("got", list, err)
+	}
+	var values inspecttest.Dictionary
+	input = []byte{0xbf, 0x61, 'a', 0x61, 'x', 0x61, 'b', 0x61, 'y', 0xff}
+	if err := inspect.Unmarshal(input, &values, cbor.Format); err != nil || len(values) != 2 || values["b"] != "y" {
+		t.Fatal("got", values, err)
+	}
+	input = []byte{0xbf, 0xff}
+	if err := inspect.Unmarshal(input, &values, cbor.Format); err != nil || len(values) != 0 {
+		t.Fatal("got", values, err)
+	}
+
+	// an unknown member holding indefinite items is skipped whole
+	var value record
+	input = []byte{0xbf, 0x65, 'e', 'x', 't', 'r', 'a', 0x9f, 0xbf, 0x61, 'k', 0x5f, 0x41, 0, 0xff, 0xff, 0xff,
+		0x64, 'n', 'a', 'm', 'e', 0x61, 'n', 0x64, 't', 'a', 'g', 's', 0x9f, 0x61, 't', 0xff, 0xff}
+	impl := new(inspect.BinaryReadInspector[cbor.Reader, *cbor.Reader])
+	impl.SetUnknownFieldPolicy(inspect.SkipUnknown)
+	reader := inspect.NewInspector(impl)
+	reader.SetReader(&sliceReader{input})
+	value.Inspect(reader)
+	if reader.LastError() != nil || value.name != "n" || len(value.tags) != 1 || value.tags[0] != "t" {
+		t.Fatal("got", value, reader.LastError())
+	}
+
+	for _, input := range [][]byte{
+		// a break without an indefinite item and a map missing its last value
+		{0x82, 0x61, 'a', 0xff},
+		{0xbf, 0x61, 'a', 0xff},
+	} {
+		var list inspecttest.Texts
+		if err := inspect.Unmarshal(input, &list, cbor.Format); err == nil {
+			t.Fatal(input, "got", list)
+		}
+		if err := inspect.Unmarshal(input, &values, cbor.Format); err == nil {
+			t.Fatal(input, "got", values)
+		}
+	}
+}
+
+func TestHostileLength(t *testing.T) {
+	// 2 GiB is read as far as the input goes, 4 GiB is refused up front
+	var value inspecttest.Text
+	err := inspect.Unmarshal([]byte{0x7a, 0x7f, 0xff, 0xff, 0xff, 'a'}, &value, cbor.Format)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("got", err)
+	}
+	err = inspect.Unmarshal([]byte{0x7b, 0, 0, 0, 1, 0, 0, 0, 0, 'a'}, &value, cbor.Format)
+	if !errors.Is(err, cbor.ErrTooLong) {
+		t.Fatal("got", err)
+	}
+	var list inspecttest.Texts
+	if err := inspect.Unmarshal([]byte{0x9b, 0, 0, 0, 1, 0, 0, 0, 0, 0x60}, &list, cbor.Format); err == nil {
+		t.Fatal("got", list)
+	}
+	// headers followed by a little data, far less than they announce
+	for _, test := range []struct {
+		input []byte
+		value inspect.Inspectable
+	}{
+		{[]byte{0x9b, 0, 0, 0, 4, 0, 0, 0, 0}, new(inspecttest.Numbers)},
+		{[]byte{0xbb, 0, 0, 0, 4, 0, 0, 0, 0}, new(inspecttest.Counts)},
+		{[]byte{0x5a, 0x7f, 0xff, 0xff, 0xff}, new(inspecttest.Blob)},
+	} {
+		for _, limits := range []inspect.Limits{{}, {MaxTotalBytes: 1024}} {
+			impl := new(inspect.BinaryReadInspector[cbor.Reader, *cbor.Reader])
+			impl.SetLimits(limits)
+			reader := inspect.NewInspector(impl)
+			reader.SetReader(bytes.NewBuffer(append(test.input, make([]byte, 64)...)))
+			test.value.Inspect(reader)
+			if reader.LastError() == nil {
+				t.Fatal(test.input, limits, "read without an error")
+			}
+		}
+	}
+}
+
+// sliceReader hands out the input one byte at a time
+type sliceReader struct {
+	data []byte
+}
+
+func (s *sliceReader) Read(buffer []byte) (int, error) {
+	if len(s.data) == 0 {
+		return 0, io.EOF
+	}
+	if len(buffer) == 0 {
+		return 0, nil
+	}
+	buffer[0] = s.data[0]
+	s.data = s.data[1:]
+	return 1, nil
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte{0x9f, 0x61, 'a', 0x7f, 0x61, 'b', 0xff, 0xff})
+	f.Add([]byte{0xbf, 0x61, 'a', 0x61, 'x', 0xff})
+	f.Add([]byte{0x7f, 0x62, 'a', 'b', 0x61, 'c', 0xff})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inspecttest.Fuzz(data, cbor.Format, new(inspecttest.Texts), new(inspecttest.Dictionary))
+		var value record
+		inspect.Unmarshal(data, &value, cbor.Format, inspect.WithLimits(inspect.Limits{MaxDepth: 16}),
+			inspect.WithUnknownFields(inspect.SkipUnknown))
+	})
+}
@@ -0,0 +1,106 @@
+package cbor
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/tvanomr/inspect"
+)
+
+// source is where values are read from: the input,
+// or a member of an object that was read ahead of time
+type source struct {
+	// nil for a member read ahead of time
+	reader *bufio.Reader
+	data   []byte
+	// offset of data or of the input in the input
+	base     int64
+	position int64
+	limits   *inspect.Limits
+	// bytes consumed are appended here when it is set
+	record *[]byte
+	// holds what read returns when reading the input
+	scratch []byte
+}
+
+func (s *source) reset(reader io.Reader) {
+	if s.reader == nil {
+		s.reader = bufio.NewReader(reader)
+	} else {
+		s.reader.Reset(reader)
+	}
+	s.position = 0
+	s.record = nil
+}
+
+func (s *source) offset() int64 {
+	return s.base + s.position
+}
+
+func (s *source) peek() (byte, error) {
+	if s.reader == nil {
+		if s.position >= int64(len(s.data)) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return s.data[s.position], nil
+	}
+	if err := s.limits.CheckTotal(s.position + 1); err != nil {
+		return 0, err
+	}
+	result, err := s.reader.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return result[0], nil
+}
+
+func (s *source) ReadByte() (byte, error) {
+	result, err := s.peek()
+	if err != nil {
+		return 0, err
+	}
+	if s.reader != nil {
+		s.reader.ReadByte()
+	}
+	s.position++
+	if s.record != nil {
+		*s.record = append(*s.record, result)
+	}
+	return result, nil
+}
+
+// read returns the next length bytes, the slice is only valid until the next read
+func (s *source) read(length int64) ([]byte, error) {
+	var result []byte
+	if s.reader == nil {
+		if length > int64(len(s.data))-s.position {
+			return nil, io.ErrUnexpectedEOF
+		}
+		result = s.data[s.position : s.position+length]
+	} else {
+		if err := s.limits.CheckTotal(s.position + length); err != nil {
+			return nil, err
+		}
+		if int64(cap(s.scratch)) >= length {
+			result = s.scratch[:length]
+			if _, err := io.ReadFull(s.reader, result); err != nil {
+				if err == io.EOF {
+					return nil, io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+		} else {
+			// the length comes from the input, the buffer only grows with what arrives
+			var err error
+			if result, err = inspect.ReadFull(s.reader, length); err != nil {
+				return nil, err
+			}
+			s.scratch = result
+		}
+	}
+	s.position += length
+	if s.record != nil {
+		*s.record = append(*s.record, result...)
+	}
+	return result, nil
+}
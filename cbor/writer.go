@@ -0,0 +1,360 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/tvanomr/inspect"
+)
+
+// container is an open array, map or object
+type container struct {
+	indefinite bool
+	// members of deterministic maps and objects are sorted once they end
+	sorted bool
+	// offset of the head of an object, written once the number of members is known
+	head int
+	// buffer offsets of the keys and of the values
+	keys   []int
+	values []int
+}
+
+type Writer struct {
+	writer        io.Writer
+	bufferSize    int
+	buffer        []byte
+	deterministic bool
+	containers    stack[container]
+	// number of sorted containers open
+	sorted int
+}
+
+// SetDeterministic turns on the core deterministic encoding of RFC 8949:
+// definite lengths only, map keys in order and floats in their shortest exact form.
+// Objects and maps are kept in memory until they end.
+func (w *Writer) SetDeterministic(deterministic bool) {
+	w.deterministic = deterministic
+}
+
+// SetCanonical is SetDeterministic, for inspect.WithCanonical
+func (w *Writer) SetCanonical(canonical bool) {
+	w.SetDeterministic(canonical)
+}
+
+func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
+	w.writer = writer
+	w.bufferSize = bufferSize
+	w.buffer = w.buffer[:0]
+	w.containers = w.containers[:0]
+	w.sorted = 0
+}
+
+// write checks whether the buffer can be written out,
+// it has to stay in memory while a sorted container is open
+func (w *Writer) write() error {
+	if w.sorted == 0 && len(w.buffer) > w.bufferSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// appendHead appends the initial byte of a data item with the shortest form of argument
+func appendHead(buffer []byte, majorType byte, argument uint64) []byte {
+	var data [9]byte
+	size := 0
+	switch {
+	case argument < uint64(infoUint8):
+		data[0] = majorType<<5 | byte(argument)
+	case argument <= math.MaxUint8:
+		data[0] = majorType<<5 | infoUint8
+		data[1] = byte(argument)
+		size = 1
+	case argument <= math.MaxUint16:
+		data[0] = majorType<<5 | infoUint16
+		binary.BigEndian.PutUint16(data[1:], uint16(argument))
+		size = 2
+	case argument <= math.MaxUint32:
+		data[0] = majorType<<5 | infoUint32
+		binary.BigEndian.PutUint32(data[1:], uint32(argument))
+		size = 4
+	default:
+		data[0] = majorType<<5 | infoUint64
+		binary.BigEndian.PutUint64(data[1:], argument)
+		size = 8
+	}
+	return append(buffer, data[:1+size]...)
+}
+
+func (w *Writer) writeHead(majorType byte, argument uint64) error {
+	w.buffer = appendHead(w.buffer, majorType, argument)
+	return w.write()
+}
+
+func (w *Writer) writeCode(code byte) error {
+	w.buffer = append(w.buffer, code)
+	return w.write()
+}
+
+func (w *Writer) Bool(value bool) error {
+	if value {
+		return w.writeCode(codeTrue)
+	}
+	return w.writeCode(codeFalse)
+}
+func (w *Writer) Int8(value int8) error {
+	return w.Int64(int64(value))
+}
+func (w *Writer) Int16(value int16) error {
+	return w.Int64(int64(value))
+}
+func (w *Writer) Int32(value int32) error {
+	return w.Int64(int64(value))
+}
+func (w *Writer) Int64(value int64) error {
+	if value < 0 {
+		return w.writeHead(majorNegative, uint64(-1-value))
+	}
+	return w.writeHead(majorUint, uint64(value))
+}
+func (w *Writer) Uint8(value uint8) error {
+	return w.writeHead(majorUint, uint64(value))
+}
+func (w *Writer) Uint16(value uint16) error {
+	return w.writeHead(majorUint, uint64(value))
+}
+func (w *Writer) Uint32(value uint32) error {
+	return w.writeHead(majorUint, uint64(value))
+}
+func (w *Writer) Uint64(value uint64) error {
+	return w.writeHead(majorUint, value)
+}
+
+func (w *Writer) writeFloat(code byte, bits uint64, size int) error {
+	var data [9]byte
+	data[0] = code
+	switch size {
+	case 2:
+		binary.BigEndian.PutUint16(data[1:], uint16(bits))
+	case 4:
+		binary.BigEndian.PutUint32(data[1:], uint32(bits))
+	default:
+		binary.BigEndian.PutUint64(data[1:], bits)
+	}
+	w.buffer = append(w.buffer, data[:1+size]...)
+	return w.write()
+}
+
+// shortestFloat writes value in the shortest form that keeps it exact
+func (w *Writer) shortestFloat(value float64) error {
+	if single := float32(value); float64(single) == value || math.IsNaN(value) {
+		if half, ok := halfBits(single); ok {
+			return w.writeFloat(codeFloat16, uint64(half), 2)
+		}
+		return w.writeFloat(codeFloat32, uint64(math.Float32bits(single)), 4)
+	}
+	return w.writeFloat(codeFloat64, math.Float64bits(value), 8)
+}
+func (w *Writer) Float32(value float32, format byte, precision int) error {
+	if w.deterministic {
+		return w.shortestFloat(float64(value))
+	}
+	return w.writeFloat(codeFloat32, uint64(math.Float32bits(value)), 4)
+}
+func (w *Writer) Float64(value float64, format byte, precision int) error {
+	if w.deterministic {
+		return w.shortestFloat(value)
+	}
+	return w.writeFloat(codeFloat64, math.Float64bits(value), 8)
+}
+func (w *Writer) String(value string) error {
+	w.buffer = appendHead(w.buffer, majorText, uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+	return w.write()
+}
+func (w *Writer) Bytes(value []byte) error {
+	w.buffer = appendHead(w.buffer, majorBytes, uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+	return w.write()
+}
+func (w *Writer) ByteString(value []byte) error {
+	w.buffer = appendHead(w.buffer, majorText, uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+	return w.write()
+}
+func (w *Writer) Null() error {
+	return w.writeCode(codeNull)
+}
+
+func (w *Writer) Skip() error {
+	return w.Null()
+}
+func (w *Writer) NotNull() error {
+	return nil
+}
+
+// open starts an array or a map, length is -1 when it is not known
+func (w *Writer) open(majorType byte, length int) error {
+	if length >= 0 {
+		w.buffer = appendHead(w.buffer, majorType, uint64(length))
+	} else if w.deterministic {
+		return ErrIndefiniteLength
+	} else {
+		w.buffer = append(w.buffer, majorType<<5|infoIndefinite)
+	}
+	w.push(container{indefinite: length < 0, sorted: w.deterministic && majorType == majorMap, head: -1})
+	return w.write()
+}
+
+func (w *Writer) push(value container) {
+	if value.sorted {
+		w.sorted++
+	}
+	w.containers.push(value)
+}
+
+// close ends the current container, sorting its members when needed
+func (w *Writer) close() error {
+	if len(w.containers) == 0 {
+		return nil
+	}
+	value := w.containers.pop()
+	if value.indefinite {
+		return w.writeCode(codeBreak)
+	}
+	if !value.sorted {
+		return nil
+	}
+	w.sorted--
+	w.sortMembers(value)
+	if value.head >= 0 {
+		// make room for the object head
+		head := appendHead(nil, majorMap, uint64(len(value.keys)))
+		end := len(w.buffer)
+		w.buffer = append(w.buffer, head[1:]...)
+		copy(w.buffer[value.head+len(head):], w.buffer[value.head+1:end])
+		copy(w.buffer[value.head:], head)
+	}
+	return w.write()
+}
+
+// sortMembers orders the members of a map by the bytes of their encoded keys
+func (w *Writer) sortMembers(value container) {
+	if len(value.keys) < 2 {
+		return
+	}
+	type entry struct {
+		key  []byte
+		data []byte
+	}
+	entries := make([]entry, len(value.keys))
+	for i, start := range value.keys {
+		end := len(w.buffer)
+		if i+1 < len(value.keys) {
+			end = value.keys[i+1]
+		}
+		data := append([]byte(nil), w.buffer[start:end]...)
+		entries[i] = entry{key: data[:value.values[i]-start], data: data}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+	buffer := w.buffer[:value.keys[0]]
+	for _, entry := range entries {
+		buffer = append(buffer, entry.data...)
+	}
+	w.buffer = buffer
+}
+
+// key writes the key of a member
+func (w *Writer) key(name string) error {
+	if len(w.containers) == 0 {
+		return ErrNoObject
+	}
+	current := &w.containers[len(w.containers)-1]
+	if current.sorted {
+		current.keys = append(current.keys, len(w.buffer))
+	}
+	w.buffer = appendHead(w.buffer, majorText, uint64(len(name)))
+	w.buffer = append(w.buffer, name...)
+	if current.sorted {
+		current.values = append(current.values, len(w.buffer))
+	}
+	return w.write()
+}
+
+// StartObject writes an indefinite length map, deterministic objects get their length on EndObject
+func (w *Writer) StartObject() error {
+	if w.deterministic {
+		w.push(container{sorted: true, head: len(w.buffer)})
+		w.buffer = append(w.buffer, majorMap<<5)
+		return nil
+	}
+	w.push(container{indefinite: true, head: -1})
+	return w.writeCode(majorMap<<5 | infoIndefinite)
+}
+func (w *Writer) Property(name string) error {
+	return w.key(name)
+}
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	if !present {
+		return nil
+	}
+	return w.key(name)
+}
+
+// RawProperty writes a member collected by a CBOR reader
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	if field.Format != formatName {
+		return ErrRawField
+	}
+	if err := w.key(field.Name); err != nil {
+		return err
+	}
+	w.buffer = append(w.buffer, field.Value...)
+	return w.write()
+}
+func (w *Writer) EndObject() error {
+	return w.close()
+}
+func (w *Writer) StartArray(length int) error {
+	return w.open(majorArray, length)
+}
+func (w *Writer) EndArray() error {
+	return w.close()
+}
+func (w *Writer) StartMap(length int) error {
+	return w.open(majorMap, length)
+}
+func (w *Writer) NextKey(key string) error {
+	return w.key(key)
+}
+func (w *Writer) EndMap() error {
+	return w.close()
+}
+
+// SortKeys asks the map helpers for keys in order in deterministic encoding
+func (w *Writer) SortKeys() bool {
+	return w.deterministic
+}
+func (w *Writer) Flush() error {
+	if w.sorted > 0 || len(w.buffer) == 0 {
+		return nil
+	}
+	written, err := w.writer.Write(w.buffer)
+	if err != nil {
+		return err
+	}
+	if written < len(w.buffer) {
+		return ErrShortWrite
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func init() {
+	var _ inspect.Writer = (*Writer)(nil)
+	var _ inspect.Canonical = (*Writer)(nil)
+}
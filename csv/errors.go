@@ -0,0 +1,48 @@
+package csv
+
+import "github.com/tvanomr/inspect"
+
+type csvError int
+
+const (
+	ErrShortWrite csvError = iota
+	ErrNested
+	ErrNotARow
+	ErrNoProperty
+	ErrUnknownColumn
+	ErrDuplicateColumn
+	ErrColumnCount
+	ErrQuote
+	ErrNotABool
+	ErrNotANumber
+	ErrInvalidBytes
+	ErrRawField
+)
+
+var errorMessages = map[csvError]string{
+	ErrShortWrite:      "short write",
+	ErrNested:          "csv cells hold only scalar values, not objects, arrays or maps",
+	ErrNotARow:         "csv rows are objects or maps",
+	ErrNoProperty:      "value without selecting a column",
+	ErrUnknownColumn:   "column not in the header",
+	ErrDuplicateColumn: "column name repeated in the header",
+	ErrColumnCount:     "row and header have different numbers of columns",
+	ErrQuote:           "bad quotes around a cell",
+	ErrNotABool:        "not a boolean",
+	ErrNotANumber:      "not a number",
+	ErrInvalidBytes:    "not base64",
+	ErrRawField:        "fields collected from another format can't be written"}
+
+func (c csvError) Error() string {
+	return errorMessages[c]
+}
+
+func (c csvError) Is(target error) bool {
+	switch c {
+	case ErrNested, ErrNotARow, ErrNotABool, ErrNotANumber, ErrInvalidBytes:
+		return target == inspect.ErrTypeMismatch
+	case ErrUnknownColumn:
+		return target == inspect.ErrUnknownField
+	}
+	return false
+}
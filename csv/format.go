@@ -0,0 +1,47 @@
+package csv
+
+import (
+	"io"
+
+	"github.com/tvanomr/inspect"
+)
+
+// TSVReader is a Reader with tabs between cells unless SetDelimiter says otherwise
+type TSVReader struct {
+	Reader
+}
+
+func (r *TSVReader) SetReader(reader io.Reader) {
+	if r.delimiter == 0 {
+		r.delimiter = '\t'
+	}
+	r.Reader.SetReader(reader)
+}
+
+// TSVWriter is a Writer with tabs between cells unless SetDelimiter says otherwise
+type TSVWriter struct {
+	Writer
+}
+
+func (w *TSVWriter) SetWriter(writer io.Writer, bufferSize int) {
+	if w.delimiter == 0 {
+		w.delimiter = '\t'
+	}
+	w.Writer.SetWriter(writer, bufferSize)
+}
+
+// Format is CSV for inspect.Marshal, inspect.Unmarshal, inspect.Encode and inspect.Decode
+var Format inspect.Format = inspect.TextFormat[Reader, *Reader, Writer, *Writer]{}
+
+// TSVFormat is CSV with tabs between cells
+var TSVFormat inspect.Format = inspect.TextFormat[TSVReader, *TSVReader, TSVWriter, *TSVWriter]{}
+
+// formatName tags the fields collected by Reader, Writer accepts only those
+const formatName = "csv"
+
+func init() {
+	inspect.RegisterFormat(formatName, Format, "text/csv")
+	inspect.RegisterFormat("tsv", TSVFormat, "text/tab-separated-values")
+	var _ inspect.Reader = (*TSVReader)(nil)
+	var _ inspect.Writer = (*TSVWriter)(nil)
+}
@@ -0,0 +1,66 @@
+package csv_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/csv"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+)
+
+type report []inspecttest.Item
+
+func (r *report) Inspect(inspector *inspect.Inspector) {
+	inspect.Array[*inspecttest.Item]((*[]inspecttest.Item)(r), inspector, "report", "item", "report rows")
+}
+
+func TestRecords(t *testing.T) {
+	value := report{{Name: "a,\"b\"", Price: 1.5}, {Name: "c", Price: 2}}
+	data, err := inspect.Marshal(&value, csv.Format)
+	if err != nil || string(data) != "name,price\n\"a,\"\"b\"\"\",1.5\nc,2\n" {
+		t.Fatal("got", string(data), err)
+	}
+	var result report
+	if err := inspect.Unmarshal(data, &result, csv.Format); err != nil || len(result) != 2 ||
+		result[0] != value[0] || result[1] != value[1] {
+		t.Fatal("got", result, err)
+	}
+
+	// columns are found by name, with a byte order mark, CRLF and blank lines
+	input := "\xef\xbb\xbfprice\tname\tcomment\r\n2\t\"tab\tand\nline\"\tx\r\n\r\n3\tb\t\n"
+	if err := inspect.Unmarshal([]byte(input), &result, csv.TSVFormat); !errors.Is(err, inspect.ErrUnknownField) {
+		t.Fatal("got", err, "expected", inspect.ErrUnknownField)
+	}
+	// empty cells of unknown columns are ignored
+	input = strings.Replace(input, "\tx", "\t", 1)
+	if err := inspect.Unmarshal([]byte(input), &result, csv.TSVFormat); err != nil || len(result) != 2 ||
+		result[0] != (inspecttest.Item{Name: "tab\tand\nline", Price: 2}) ||
+		result[1] != (inspecttest.Item{Name: "b", Price: 3}) {
+		t.Fatal("got", result, err)
+	}
+
+	writeInspector := new(inspect.TextWriteInspector[csv.Writer, *csv.Writer])
+	writeInspector.Writer().SetDelimiter(';')
+	writer := inspect.NewInspector(writeInspector)
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil || buffer.String() != "name;price\n\"a,\"\"b\"\"\";1.5\nc;2\n" {
+		t.Fatal("got", buffer.String(), writer.LastError())
+	}
+
+	nested := inspecttest.Shipment{ID: 1, Label: "a", Sizes: inspecttest.Numbers{1}}
+	if _, err := inspect.Marshal(&nested, csv.Format); !errors.Is(err, csv.ErrNested) {
+		t.Fatal("got", err, "expected", csv.ErrNested)
+	}
+	if err := inspect.Unmarshal([]byte("name\na\n"), &result, csv.Format); !errors.Is(err, inspect.ErrNoField) {
+		t.Fatal("got", err, "expected", inspect.ErrNoField)
+	}
+	if err := inspect.Unmarshal([]byte("name,price\na,1\nb\n"), &result, csv.Format); !errors.Is(err, csv.ErrColumnCount) {
+		t.Fatal("got", err, "expected", csv.ErrColumnCount)
+	}
+}
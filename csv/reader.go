@@ -0,0 +1,555 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/tvanomr/inspect"
+)
+
+var byteOrderMark = []byte{0xef, 0xbb, 0xbf}
+
+// Reader reads the rows after the header as an array of objects or maps,
+// properties are found by the column names in the header.
+// An empty cell is a null or a missing optional property.
+type Reader struct {
+	reader    *bufio.Reader
+	limits    inspect.Limits
+	delimiter byte
+	// bytes read and the position of the value being read
+	total  int64
+	offset int64
+	// column names and the column of every name
+	header    []string
+	columns   map[string]int
+	hasHeader bool
+	// cells of the row read ahead and where they start
+	record  []string
+	offsets []int64
+	field   []byte
+	pending bool
+	rows    int64
+	// inside the top level array and inside a row
+	table bool
+	row   bool
+	// columns asked for in the row, the selected one, -1 when there is none
+	read   []bool
+	column int
+}
+
+// SetDelimiter sets the byte between cells, a comma by default,
+// it panics when the delimiter is a quote or a line break
+func (r *Reader) SetDelimiter(delimiter byte) {
+	checkDelimiter(delimiter)
+	r.delimiter = delimiter
+}
+
+func (r *Reader) SetReader(reader io.Reader) {
+	if r.reader == nil {
+		r.reader = bufio.NewReader(reader)
+	} else {
+		r.reader.Reset(reader)
+	}
+	if r.delimiter == 0 {
+		r.delimiter = ','
+	}
+	r.total = 0
+	r.offset = 0
+	r.header = r.header[:0]
+	r.columns = map[string]int{}
+	r.hasHeader = false
+	r.pending = false
+	r.rows = 0
+	r.table = false
+	r.row = false
+	r.column = -1
+}
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+func (r *Reader) readByte() (byte, error) {
+	c, err := r.reader.ReadByte()
+	if err == nil {
+		r.total++
+	}
+	return c, err
+}
+
+// lineEnd tells whether c ends the line, the \n of \r\n is consumed
+func (r *Reader) lineEnd(c byte) bool {
+	if c == '\n' {
+		return true
+	}
+	if c != '\r' {
+		return false
+	}
+	if next, err := r.reader.Peek(1); err == nil && next[0] == '\n' {
+		r.readByte()
+		return true
+	}
+	return false
+}
+
+// readField reads a cell, last is true when it ends the row
+func (r *Reader) readField() (last bool, err error) {
+	offset := r.total
+	r.field = r.field[:0]
+	c, err := r.readByte()
+	quoted := err == nil && c == '"'
+	if quoted {
+		for {
+			if c, err = r.readByte(); err != nil {
+				if err == io.EOF {
+					r.offset = offset
+					return false, ErrQuote
+				}
+				return false, err
+			}
+			if c == '"' {
+				// a doubled quote stands for one, otherwise the cell ends
+				if c, err = r.readByte(); err != nil || c != '"' {
+					break
+				}
+			}
+			r.field = append(r.field, c)
+		}
+	} else {
+		for err == nil && c != r.delimiter && !r.lineEnd(c) {
+			r.field = append(r.field, c)
+			c, err = r.readByte()
+		}
+	}
+	switch {
+	case err == io.EOF:
+		last = true
+	case err != nil:
+		return false, err
+	case c == r.delimiter:
+	case quoted && !r.lineEnd(c):
+		r.offset = offset
+		return false, ErrQuote
+	default:
+		last = true
+	}
+	if err := r.limits.CheckString(int64(len(r.field))); err != nil {
+		return false, err
+	}
+	r.record = append(r.record, string(r.field))
+	r.offsets = append(r.offsets, offset)
+	return last, nil
+}
+
+// readRecord reads the cells of the next row, blank lines are skipped,
+// io.EOF tells that there are no more rows
+func (r *Reader) readRecord() error {
+	r.record = r.record[:0]
+	r.offsets = r.offsets[:0]
+	for {
+		next, err := r.reader.Peek(1)
+		if err != nil {
+			return err
+		}
+		if next[0] != '\n' && next[0] != '\r' {
+			break
+		}
+		r.readByte()
+	}
+	for {
+		last, err := r.readField()
+		if err != nil {
+			return err
+		}
+		if err := r.limits.CheckArray(int64(len(r.record))); err != nil {
+			return err
+		}
+		if err := r.limits.CheckTotal(r.total); err != nil {
+			return err
+		}
+		if last {
+			r.offset = r.offsets[0]
+			return nil
+		}
+	}
+}
+
+// readHeader reads the column names once, before the first row
+func (r *Reader) readHeader() error {
+	if r.hasHeader {
+		return nil
+	}
+	if start, err := r.reader.Peek(len(byteOrderMark)); err == nil && bytes.Equal(start, byteOrderMark) {
+		r.reader.Discard(len(byteOrderMark))
+		r.total += int64(len(byteOrderMark))
+	}
+	if err := r.readRecord(); err != nil {
+		return err
+	}
+	r.hasHeader = true
+	r.header = append(r.header[:0], r.record...)
+	for i, name := range r.header {
+		if _, ok := r.columns[name]; ok {
+			r.offset = r.offsets[i]
+			return ErrDuplicateColumn
+		}
+		r.columns[name] = i
+	}
+	return nil
+}
+
+// next reads the row that follows, io.EOF when there is none
+func (r *Reader) next() error {
+	if err := r.readHeader(); err != nil {
+		return err
+	}
+	if err := r.readRecord(); err != nil {
+		return err
+	}
+	if len(r.record) != len(r.header) {
+		return ErrColumnCount
+	}
+	r.rows++
+	if err := r.limits.CheckArray(r.rows); err != nil {
+		return err
+	}
+	r.pending = true
+	return nil
+}
+
+// cell returns the text of the selected column without consuming it
+func (r *Reader) cell() (string, error) {
+	if !r.row {
+		return "", ErrNotARow
+	}
+	if r.column < 0 {
+		return "", ErrNoProperty
+	}
+	return r.record[r.column], nil
+}
+
+// scalar consumes the cell once parse accepts it
+func (r *Reader) scalar(parse func(text string) error) error {
+	text, err := r.cell()
+	if err != nil {
+		return err
+	}
+	if err := parse(text); err != nil {
+		return err
+	}
+	r.column = -1
+	return nil
+}
+
+// number parses a cell, it is consumed also when it overflows
+func number[T any](r *Reader, parse func(text string) (T, error), kind string) (T, error) {
+	var result T
+	overflow := ""
+	err := r.scalar(func(text string) error {
+		var err error
+		result, err = parse(text)
+		if numError, ok := err.(*strconv.NumError); ok && numError.Err == strconv.ErrRange {
+			overflow = text
+			return nil
+		}
+		if err != nil {
+			return ErrNotANumber
+		}
+		return nil
+	})
+	if err == nil && len(overflow) > 0 {
+		return result, &inspect.OverflowError{Type: kind, Value: overflow}
+	}
+	return result, err
+}
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := r.Int64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](result)
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := r.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
+
+// Bool accepts what strconv.ParseBool does, e.g. TRUE written by spreadsheets
+func (r *Reader) Bool() (result bool, err error) {
+	err = r.scalar(func(text string) error {
+		var err error
+		if result, err = strconv.ParseBool(text); err != nil {
+			return ErrNotABool
+		}
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) Int8() (int8, error) {
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	return readNarrowInt[int16](r)
+}
+func (r *Reader) Int32() (int32, error) {
+	return readNarrowInt[int32](r)
+}
+func (r *Reader) Int64() (int64, error) {
+	return number(r, func(text string) (int64, error) {
+		return strconv.ParseInt(text, 10, 64)
+	}, "int64")
+}
+func (r *Reader) Uint8() (uint8, error) {
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	return number(r, func(text string) (uint64, error) {
+		return strconv.ParseUint(text, 10, 64)
+	}, "uint64")
+}
+func (r *Reader) Float32() (float32, error) {
+	result, err := number(r, func(text string) (float64, error) {
+		result, err := strconv.ParseFloat(text, 64)
+		if err == nil && !math.IsInf(result, 0) && math.Abs(result) > math.MaxFloat32 {
+			err = &strconv.NumError{Func: "ParseFloat", Num: text, Err: strconv.ErrRange}
+		}
+		return result, err
+	}, "float32")
+	return float32(result), err
+}
+func (r *Reader) Float64() (float64, error) {
+	return number(r, func(text string) (float64, error) {
+		return strconv.ParseFloat(text, 64)
+	}, "float64")
+}
+func (r *Reader) String() (result string, err error) {
+	err = r.scalar(func(text string) error {
+		result = text
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) Bytes() (result []byte, err error) {
+	err = r.scalar(func(text string) error {
+		var err error
+		if result, err = base64.StdEncoding.DecodeString(text); err != nil {
+			return ErrInvalidBytes
+		}
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) ByteString() ([]byte, error) {
+	result, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(result), nil
+}
+
+// IsNull consumes an empty cell, rows themselves are never null
+func (r *Reader) IsNull() (bool, error) {
+	if !r.row {
+		return false, nil
+	}
+	text, err := r.cell()
+	if err != nil || len(text) > 0 {
+		return false, err
+	}
+	r.column = -1
+	return true, nil
+}
+
+// startRow enters the row read ahead in the array or reads one at the top level
+func (r *Reader) startRow() error {
+	if r.row {
+		return ErrNested
+	}
+	if !r.table {
+		if err := r.next(); err != nil {
+			return err
+		}
+	} else if !r.pending {
+		return io.ErrUnexpectedEOF
+	}
+	r.pending = false
+	r.row = true
+	r.column = -1
+	r.read = r.read[:0]
+	for range r.record {
+		r.read = append(r.read, false)
+	}
+	return nil
+}
+func (r *Reader) StartObject() error {
+	return r.startRow()
+}
+func (r *Reader) Property(name string) error {
+	if !r.row {
+		return ErrNotARow
+	}
+	column, ok := r.columns[name]
+	if !ok {
+		return inspect.ErrNoField
+	}
+	r.read[column] = true
+	r.column = column
+	r.offset = r.offsets[column]
+	return nil
+}
+
+// OptionalProperty returns false for a missing column and for an empty cell
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	err := r.Property(name)
+	if err == inspect.ErrNoField {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(r.record[r.column]) == 0 {
+		r.column = -1
+		return false, nil
+	}
+	return true, nil
+}
+
+// UnknownFields returns the non-empty cells of the columns not asked for
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if !r.row {
+		return nil, ErrNotARow
+	}
+	var result []inspect.RawField
+	for i, text := range r.record {
+		if r.read[i] || len(text) == 0 {
+			continue
+		}
+		if policy == inspect.FailOnUnknown {
+			r.offset = r.offsets[i]
+			return nil, ErrUnknownColumn
+		}
+		r.read[i] = true
+		if policy == inspect.CollectUnknown {
+			result = append(result, inspect.RawField{Format: formatName, Name: r.header[i], Value: []byte(text)})
+		}
+	}
+	return result, nil
+}
+func (r *Reader) EndObject() error {
+	r.row = false
+	return nil
+}
+
+// StartArray reads the header and the first row of the table,
+// a table without rows is an empty array
+func (r *Reader) StartArray() (length int, err error) {
+	if r.table || r.row {
+		return 0, ErrNested
+	}
+	if err := r.next(); err != nil {
+		if err == io.EOF {
+			return 0, nil
+		}
+		return 0, err
+	}
+	r.table = true
+	return -1, nil
+}
+func (r *Reader) HaveNext() (bool, error) {
+	if !r.table {
+		return false, nil
+	}
+	if r.pending {
+		return true, nil
+	}
+	if err := r.next(); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+func (r *Reader) EndArray() error {
+	r.table = false
+	r.pending = false
+	return nil
+}
+
+// StartMap enters a row as a map of its non-empty cells
+func (r *Reader) StartMap() (length int, err error) {
+	if err := r.startRow(); err != nil {
+		return 0, err
+	}
+	for _, text := range r.record {
+		if len(text) > 0 {
+			length++
+		}
+	}
+	if length == 0 {
+		r.row = false
+	}
+	return length, nil
+}
+
+// NextKey returns the name of the next non-empty cell and selects it
+func (r *Reader) NextKey() (string, error) {
+	if !r.row {
+		return "", nil
+	}
+	for i, text := range r.record {
+		if !r.read[i] && len(text) > 0 {
+			r.read[i] = true
+			r.column = i
+			r.offset = r.offsets[i]
+			return r.header[i], nil
+		}
+	}
+	return "", nil
+}
+func (r *Reader) EndMap() error {
+	r.row = false
+	return nil
+}
+
+// Skip consumes the selected cell, or the next row outside of rows
+func (r *Reader) Skip() error {
+	if r.row {
+		if r.column < 0 {
+			return ErrNoProperty
+		}
+		r.column = -1
+		return nil
+	}
+	if r.table {
+		if !r.pending {
+			return io.ErrUnexpectedEOF
+		}
+		r.pending = false
+		return nil
+	}
+	err := r.next()
+	r.pending = false
+	return err
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+}
@@ -0,0 +1,114 @@
+package csv_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/csv"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+)
+
+type row struct {
+	name string
+	note string
+}
+
+func (r *row) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("row", "row with two text cells")
+	o.String("name", &r.name, true, "name")
+	o.String("note", &r.note, true, "note")
+	o.End()
+}
+
+type table []row
+
+func (t *table) Inspect(inspector *inspect.Inspector) {
+	inspect.Array[*row]((*[]row)(t), inspector, "table", "row", "rows")
+}
+
+func TestQuoting(t *testing.T) {
+	tests := []struct {
+		cell    string
+		encoded string
+	}{
+		{"plain", "plain"},
+		{"", ""},
+		{" spaced ", " spaced "},
+		{"a,b", `"a,b"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{`"`, `""""`},
+		{"two\nlines", "\"two\nlines\""},
+		{"cr\r", "\"cr\r\""},
+		{"tab\there", "tab\there"},
+	}
+	for _, test := range tests {
+		value := table{{test.cell, "x"}}
+		data, err := inspect.Marshal(&value, csv.Format)
+		if err != nil || string(data) != "name,note\n"+test.encoded+",x\n" {
+			t.Fatalf("%q: got %q %v", test.cell, data, err)
+		}
+		var result table
+		if err := inspect.Unmarshal(data, &result, csv.Format); err != nil || len(result) != 1 || result[0] != value[0] {
+			t.Fatalf("%q: got %q %v", test.cell, result, err)
+		}
+	}
+}
+
+func TestReadCells(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected table
+	}{
+		// quoted header names and a quoted empty cell
+		{"\"name\",\"note\"\n\"\",a\n", table{{"", "a"}}},
+		// a quote inside an unquoted cell is kept
+		{"name,note\na\"b,c\n", table{{"a\"b", "c"}}},
+		// a quoted cell spans lines, CRLF ends the row
+		{"name,note\r\n\"a\r\nb\",c\r\n", table{{"a\r\nb", "c"}}},
+		// the last row needs no line break, the last cell may be empty
+		{"name,note\na,", table{{"a", ""}}},
+		{"name,note\na,\"\"", table{{"a", ""}}},
+		{"name,note\n", nil},
+	}
+	for _, test := range tests {
+		var result table
+		if err := inspect.Unmarshal([]byte(test.input), &result, csv.Format); err != nil || len(result) != len(test.expected) {
+			t.Fatalf("%q: got %q %v", test.input, result, err)
+		}
+		for i := range test.expected {
+			if result[i] != test.expected[i] {
+				t.Fatalf("%q: got %q", test.input, result)
+			}
+		}
+	}
+}
+
+func TestBadQuotes(t *testing.T) {
+	for _, input := range []string{
+		"name,note\n\"a\"b,c\n",
+		"name,note\n\"a,c\n",
+		"name,note\na,\"c",
+		"\"name,note\n",
+	} {
+		var result table
+		if err := inspect.Unmarshal([]byte(input), &result, csv.Format); !errors.Is(err, csv.ErrQuote) {
+			t.Fatalf("%q: got %v", input, err)
+		}
+	}
+	var result table
+	err := inspect.Unmarshal([]byte("name,note\n\"aaaaaaaaaa\",b\n"), &result, csv.Format,
+		inspect.WithLimits(inspect.Limits{MaxStringLength: 4}))
+	if !errors.Is(err, inspect.ErrLimitExceeded) {
+		t.Fatal("got", err)
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte("name,note\n\"a,\"\"b\"\"\",c\r\n\nd,\n"))
+	f.Add([]byte("\xef\xbb\xbfnote,name\n\"x\ny\",z"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inspecttest.Fuzz(data, csv.Format, new(table))
+		inspecttest.Fuzz(data, csv.TSVFormat, new(table))
+	})
+}
@@ -0,0 +1,333 @@
+package csv
+
+import (
+	"encoding/base64"
+	"io"
+	"strconv"
+
+	"github.com/tvanomr/inspect"
+)
+
+// Writer writes a top level array of objects or maps as a header row
+// with the property names of the first element and a row for every element.
+// Top level objects that follow each other are rows of the same table.
+type Writer struct {
+	writer     io.Writer
+	bufferSize int
+	buffer     []byte
+	delimiter  byte
+	// inside the top level array and inside a row
+	table bool
+	row   bool
+	// columns are added by the first row and fixed once the header is written
+	header        []string
+	columns       map[string]int
+	headerWritten bool
+	cells         [][]byte
+	// column of the property being written, -1 when there is none
+	column int
+}
+
+// SetDelimiter sets the byte between cells, a comma by default,
+// it panics when the delimiter is a quote or a line break
+func (w *Writer) SetDelimiter(delimiter byte) {
+	checkDelimiter(delimiter)
+	w.delimiter = delimiter
+}
+
+func checkDelimiter(delimiter byte) {
+	if delimiter == '"' || delimiter == '\r' || delimiter == '\n' {
+		panic("csv: invalid delimiter " + strconv.QuoteRune(rune(delimiter)))
+	}
+}
+
+func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
+	w.writer = writer
+	w.bufferSize = bufferSize
+	w.buffer = w.buffer[:0]
+	if w.delimiter == 0 {
+		w.delimiter = ','
+	}
+	w.table = false
+	w.row = false
+	w.header = w.header[:0]
+	w.columns = map[string]int{}
+	w.headerWritten = false
+	w.cells = w.cells[:0]
+	w.column = -1
+}
+
+// cell returns the cell of the selected column emptied for the value
+func (w *Writer) cell() ([]byte, error) {
+	if !w.row {
+		return nil, ErrNotARow
+	}
+	if w.column < 0 {
+		return nil, ErrNoProperty
+	}
+	return w.cells[w.column][:0], nil
+}
+
+// set stores the cell written for the selected column
+func (w *Writer) set(cell []byte) error {
+	w.cells[w.column] = cell
+	w.column = -1
+	return nil
+}
+
+func (w *Writer) integer(value int64) error {
+	cell, err := w.cell()
+	if err != nil {
+		return err
+	}
+	return w.set(strconv.AppendInt(cell, value, 10))
+}
+
+func (w *Writer) unsigned(value uint64) error {
+	cell, err := w.cell()
+	if err != nil {
+		return err
+	}
+	return w.set(strconv.AppendUint(cell, value, 10))
+}
+
+func (w *Writer) Bool(value bool) error {
+	cell, err := w.cell()
+	if err != nil {
+		return err
+	}
+	return w.set(strconv.AppendBool(cell, value))
+}
+func (w *Writer) Int8(value int8) error {
+	return w.integer(int64(value))
+}
+func (w *Writer) Int16(value int16) error {
+	return w.integer(int64(value))
+}
+func (w *Writer) Int32(value int32) error {
+	return w.integer(int64(value))
+}
+func (w *Writer) Int64(value int64) error {
+	return w.integer(value)
+}
+func (w *Writer) Uint8(value uint8) error {
+	return w.unsigned(uint64(value))
+}
+func (w *Writer) Uint16(value uint16) error {
+	return w.unsigned(uint64(value))
+}
+func (w *Writer) Uint32(value uint32) error {
+	return w.unsigned(uint64(value))
+}
+func (w *Writer) Uint64(value uint64) error {
+	return w.unsigned(value)
+}
+func (w *Writer) Float32(value float32, format byte, precision int) error {
+	cell, err := w.cell()
+	if err != nil {
+		return err
+	}
+	return w.set(strconv.AppendFloat(cell, float64(value), format, precision, 32))
+}
+func (w *Writer) Float64(value float64, format byte, precision int) error {
+	cell, err := w.cell()
+	if err != nil {
+		return err
+	}
+	return w.set(strconv.AppendFloat(cell, value, format, precision, 64))
+}
+func (w *Writer) String(value string) error {
+	cell, err := w.cell()
+	if err != nil {
+		return err
+	}
+	return w.set(append(cell, value...))
+}
+func (w *Writer) Bytes(value []byte) error {
+	cell, err := w.cell()
+	if err != nil {
+		return err
+	}
+	start := len(cell)
+	cell = append(cell, make([]byte, base64.StdEncoding.EncodedLen(len(value)))...)
+	base64.StdEncoding.Encode(cell[start:], value)
+	return w.set(cell)
+}
+func (w *Writer) ByteString(value []byte) error {
+	cell, err := w.cell()
+	if err != nil {
+		return err
+	}
+	return w.set(append(cell, value...))
+}
+
+// Null leaves the cell empty
+func (w *Writer) Null() error {
+	cell, err := w.cell()
+	if err != nil {
+		return err
+	}
+	return w.set(cell)
+}
+
+func (w *Writer) Skip() error {
+	return w.Null()
+}
+func (w *Writer) NotNull() error {
+	return nil
+}
+
+// startRow begins a row for an object or a map, at the top level or in the array
+func (w *Writer) startRow() error {
+	if w.row {
+		return ErrNested
+	}
+	w.row = true
+	w.column = -1
+	for i := range w.cells {
+		w.cells[i] = w.cells[i][:0]
+	}
+	return nil
+}
+
+// endRow writes the row and before the first one the header
+func (w *Writer) endRow() error {
+	w.row = false
+	if !w.headerWritten {
+		w.headerWritten = true
+		for i, name := range w.header {
+			if i > 0 {
+				w.buffer = append(w.buffer, w.delimiter)
+			}
+			w.buffer = appendCell(w.buffer, []byte(name), w.delimiter)
+		}
+		w.buffer = append(w.buffer, '\n')
+	}
+	for i, cell := range w.cells {
+		if i > 0 {
+			w.buffer = append(w.buffer, w.delimiter)
+		}
+		w.buffer = appendCell(w.buffer, cell, w.delimiter)
+	}
+	w.buffer = append(w.buffer, '\n')
+	if len(w.buffer) > w.bufferSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// selectColumn finds the column of a property, the first row adds new ones
+func (w *Writer) selectColumn(name string) error {
+	if !w.row {
+		return ErrNotARow
+	}
+	column, ok := w.columns[name]
+	if !ok {
+		if w.headerWritten {
+			return ErrUnknownColumn
+		}
+		column = len(w.header)
+		w.header = append(w.header, name)
+		w.columns[name] = column
+		w.cells = append(w.cells, nil)
+	}
+	w.column = column
+	return nil
+}
+
+func (w *Writer) StartObject() error {
+	return w.startRow()
+}
+func (w *Writer) Property(name string) error {
+	return w.selectColumn(name)
+}
+
+// OptionalProperty leaves the cell of an absent property empty,
+// the first row adds its column all the same
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	err := w.selectColumn(name)
+	if !present {
+		w.column = -1
+	}
+	return err
+}
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	if field.Format != formatName {
+		return ErrRawField
+	}
+	if err := w.selectColumn(field.Name); err != nil {
+		return err
+	}
+	cell, _ := w.cell()
+	return w.set(append(cell, field.Value...))
+}
+func (w *Writer) EndObject() error {
+	return w.endRow()
+}
+
+// StartArray starts the table at the top level, arrays in rows aren't supported
+func (w *Writer) StartArray(length int) error {
+	if w.table || w.row {
+		return ErrNested
+	}
+	w.table = true
+	return nil
+}
+func (w *Writer) EndArray() error {
+	w.table = false
+	return nil
+}
+func (w *Writer) StartMap(length int) error {
+	return w.startRow()
+}
+func (w *Writer) NextKey(key string) error {
+	return w.selectColumn(key)
+}
+func (w *Writer) EndMap() error {
+	return w.endRow()
+}
+
+// SortKeys keeps the columns of map rows in order
+func (w *Writer) SortKeys() bool {
+	return true
+}
+func (w *Writer) Flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	written, err := w.writer.Write(w.buffer)
+	if err != nil {
+		return err
+	}
+	if written < len(w.buffer) {
+		return ErrShortWrite
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// appendCell quotes cells containing the delimiter, quotes or line breaks
+func appendCell(buffer []byte, cell []byte, delimiter byte) []byte {
+	quoted := false
+	for _, c := range cell {
+		if c == delimiter || c == '"' || c == '\r' || c == '\n' {
+			quoted = true
+			break
+		}
+	}
+	if !quoted {
+		return append(buffer, cell...)
+	}
+	buffer = append(buffer, '"')
+	for _, c := range cell {
+		if c == '"' {
+			buffer = append(buffer, '"')
+		}
+		buffer = append(buffer, c)
+	}
+	return append(buffer, '"')
+}
+
+func init() {
+	var _ inspect.Writer = (*Writer)(nil)
+}
@@ -1,18 +1,92 @@
 package inspect
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type inspectError int
 
 const (
 	ErrNoField inspectError = iota
 	ErrReaderCantWrite
 	ErrWriterCantRead
+	ErrOverflow
+	ErrTypeMismatch
+	ErrUnknownField
+	ErrLimitExceeded
+	ErrUnknownFieldsUnsupported
 )
 
 var errorMessages = map[inspectError]string{
-	ErrNoField:         "field not present",
-	ErrReaderCantWrite: "trying to write to a reading inspector",
-	ErrWriterCantRead:  "trying to read from a a writing inspector"}
+	ErrNoField:                  "field not present",
+	ErrReaderCantWrite:          "trying to write to a reading inspector",
+	ErrWriterCantRead:           "trying to read from a a writing inspector",
+	ErrOverflow:                 "value overflows the requested type",
+	ErrTypeMismatch:             "value has another type",
+	ErrUnknownField:             "unknown field",
+	ErrLimitExceeded:            "input exceeds a limit",
+	ErrUnknownFieldsUnsupported: "the format can't tell unknown fields from known ones"}
 
 func (i inspectError) Error() string {
 	return errorMessages[i]
 }
+
+// OverflowError is returned by readers when a value read from the input
+// does not fit into the requested integer type. It matches ErrOverflow
+// with errors.Is.
+type OverflowError struct {
+	Type  string
+	Value string
+}
+
+func (o *OverflowError) Error() string {
+	return fmt.Sprintf("value %s overflows %s", o.Value, o.Type)
+}
+
+func (o *OverflowError) Is(target error) bool {
+	return target == ErrOverflow
+}
+
+// isRecoverable tells errors after which the input is still positioned
+// right after the failed value
+func isRecoverable(err error) bool {
+	return errors.Is(err, ErrNoField) || errors.Is(err, ErrTypeMismatch) ||
+		errors.Is(err, ErrOverflow) || errors.Is(err, ErrUnknownField)
+}
+
+// ErrorList holds every error found while reading with error collection enabled
+type ErrorList []error
+
+func (e ErrorList) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e ErrorList) Unwrap() []error {
+	return e
+}
+
+// Is matches when any of the errors matches, Go before 1.20 doesn't look into Unwrap() []error
+func (e ErrorList) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches target
+func (e ErrorList) As(target any) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
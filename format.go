@@ -0,0 +1,71 @@
+package inspect
+
+import (
+	"bytes"
+	"io"
+)
+
+// Format creates the inspectors of one encoding, backends export theirs,
+// e.g. json.Format and binary.Format
+type Format interface {
+	NewReader() *Inspector
+	NewWriter() *Inspector
+}
+
+// TextFormat is the Format of a backend using TextReadInspector and TextWriteInspector
+type TextFormat[R any, PR ReaderPtr[R], W any, PW WriterPtr[W]] struct{}
+
+func (TextFormat[R, PR, W, PW]) NewReader() *Inspector {
+	return NewInspector(new(TextReadInspector[R, PR]))
+}
+
+func (TextFormat[R, PR, W, PW]) NewWriter() *Inspector {
+	return NewInspector(new(TextWriteInspector[W, PW]))
+}
+
+// BinaryFormat is the Format of a backend using BinaryReadInspector and BinaryWriteInspector
+type BinaryFormat[R any, PR ReaderPtr[R], W any, PW WriterPtr[W]] struct{}
+
+func (BinaryFormat[R, PR, W, PW]) NewReader() *Inspector {
+	return NewInspector(new(BinaryReadInspector[R, PR]))
+}
+
+func (BinaryFormat[R, PR, W, PW]) NewWriter() *Inspector {
+	return NewInspector(new(BinaryWriteInspector[W, PW]))
+}
+
+// size of the output kept before it is written by Encode
+const encodeBufferSize = 4096
+
+// Encode writes value to writer in the given format
+func Encode[T any, PT InspectablePtr[T]](writer io.Writer, value PT, format Format, options ...Option) error {
+	inspector := format.NewWriter()
+	applyOptions(inspector, options)
+	inspector.SetWriter(writer, encodeBufferSize)
+	value.Inspect(inspector)
+	inspector.Flush()
+	return inspector.LastError()
+}
+
+// Decode reads value from reader in the given format
+func Decode[T any, PT InspectablePtr[T]](reader io.Reader, value PT, format Format, options ...Option) error {
+	inspector := format.NewReader()
+	applyOptions(inspector, options)
+	inspector.SetReader(reader)
+	value.Inspect(inspector)
+	return inspector.LastError()
+}
+
+// Marshal returns value encoded in the given format
+func Marshal[T any, PT InspectablePtr[T]](value PT, format Format, options ...Option) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := Encode[T](&buffer, value, format, options...); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Unmarshal reads value from data encoded in the given format
+func Unmarshal[T any, PT InspectablePtr[T]](data []byte, value PT, format Format, options ...Option) error {
+	return Decode[T](bytes.NewReader(data), value, format, options...)
+}
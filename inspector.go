@@ -18,6 +18,15 @@ func (i *Inspector) SetReader(reader io.Reader) {
 func (i *Inspector) SetWriter(writer io.Writer, bufferSize int) {
 	i.impl.SetWriter(writer, bufferSize)
 }
+func (i *Inspector) Bool(value *bool) {
+	i.impl.Bool(value)
+}
+func (i *Inspector) Int8(value *int8) {
+	i.impl.Int8(value)
+}
+func (i *Inspector) Int16(value *int16) {
+	i.impl.Int16(value)
+}
 func (i *Inspector) Int32(value *int32) {
 	i.impl.Int32(value)
 }
@@ -27,6 +36,21 @@ func (i *Inspector) Int64(value *int64) {
 func (i *Inspector) Int(value *int) {
 	i.impl.Int(value)
 }
+func (i *Inspector) Uint8(value *uint8) {
+	i.impl.Uint8(value)
+}
+func (i *Inspector) Uint16(value *uint16) {
+	i.impl.Uint16(value)
+}
+func (i *Inspector) Uint32(value *uint32) {
+	i.impl.Uint32(value)
+}
+func (i *Inspector) Uint64(value *uint64) {
+	i.impl.Uint64(value)
+}
+func (i *Inspector) Uint(value *uint) {
+	i.impl.Uint(value)
+}
 func (i *Inspector) Float32(value *float32, format byte, precision int) {
 	i.impl.Float32(value, format, precision)
 }
@@ -45,6 +69,9 @@ func (i *Inspector) ByteString(value *[]byte) {
 func (i *Inspector) Value(value RawValue) {
 	i.impl.Value(value)
 }
+func (i *Inspector) Null(isNull *bool) {
+	i.impl.Null(isNull)
+}
 
 func (i *Inspector) ReadArray() int {
 	return i.impl.ReadArray()
@@ -73,9 +100,17 @@ func (i *Inspector) WriteNextKey(key string) {
 func (i *Inspector) EndMap() {
 	i.impl.EndMap()
 }
+
+// Skip discards the next value when reading, e.g. a deprecated property
+func (i *Inspector) Skip() {
+	i.impl.Skip()
+}
 func (i *Inspector) IsReading() bool {
 	return i.impl.IsReading()
 }
+func (i *Inspector) SortKeys() bool {
+	return i.impl.SortKeys()
+}
 func (i *Inspector) Flush() {
 	i.impl.Flush()
 }
@@ -92,12 +127,48 @@ func (o *ObjectInspector) IsReading() bool {
 func (o *ObjectInspector) Flush() {
 	o.impl.Flush()
 }
+
+// Number sets the field number of the following property,
+// formats that identify properties by name ignore it
+func (o *ObjectInspector) Number(number int) *ObjectInspector {
+	o.impl.FieldNumber(number)
+	return o
+}
+
+// Varint makes formats with fixed-width integers write the integers of the following
+// property as varints, other formats ignore it
+func (o *ObjectInspector) Varint() *ObjectInspector {
+	o.impl.UseVarint()
+	return o
+}
+
+// Attribute makes formats with attributes write the following property, a scalar,
+// as an attribute of the element of the object, other formats ignore it
+func (o *ObjectInspector) Attribute() *ObjectInspector {
+	o.impl.UseAttribute()
+	return o
+}
 func (o *ObjectInspector) Property(name string, mandatory bool, description string) *Inspector {
 	if o.impl.Property(name, mandatory, description) {
 		return (*Inspector)(o)
 	}
 	return nil
 }
+func (o *ObjectInspector) Optional(name string, present *bool, description string) *Inspector {
+	if o.impl.OptionalProperty(name, present, description) {
+		return (*Inspector)(o)
+	}
+	return nil
+}
+func (o *ObjectInspector) Bool(name string, value *bool, mandatory bool, description string) {
+	o.impl.PropertyBool(name, value, mandatory, description)
+}
+func (o *ObjectInspector) Int8(name string, value *int8, mandatory bool, description string) {
+	o.impl.PropertyInt8(name, value, mandatory, description)
+}
+func (o *ObjectInspector) Int16(name string, value *int16, mandatory bool, description string) {
+	o.impl.PropertyInt16(name, value, mandatory, description)
+}
 func (o *ObjectInspector) Int32(name string, value *int32, mandatory bool, description string) {
 	o.impl.PropertyInt32(name, value, mandatory, description)
 }
@@ -107,6 +178,21 @@ func (o *ObjectInspector) Int64(name string, value *int64, mandatory bool, descr
 func (o *ObjectInspector) Int(name string, value *int, mandatory bool, description string) {
 	o.impl.PropertyInt(name, value, mandatory, description)
 }
+func (o *ObjectInspector) Uint8(name string, value *uint8, mandatory bool, description string) {
+	o.impl.PropertyUint8(name, value, mandatory, description)
+}
+func (o *ObjectInspector) Uint16(name string, value *uint16, mandatory bool, description string) {
+	o.impl.PropertyUint16(name, value, mandatory, description)
+}
+func (o *ObjectInspector) Uint32(name string, value *uint32, mandatory bool, description string) {
+	o.impl.PropertyUint32(name, value, mandatory, description)
+}
+func (o *ObjectInspector) Uint64(name string, value *uint64, mandatory bool, description string) {
+	o.impl.PropertyUint64(name, value, mandatory, description)
+}
+func (o *ObjectInspector) Uint(name string, value *uint, mandatory bool, description string) {
+	o.impl.PropertyUint(name, value, mandatory, description)
+}
 func (o *ObjectInspector) Float32(name string, value *float32, format byte, precision int, mandatory bool, description string) {
 	o.impl.PropertyFloat32(name, value, format, precision, mandatory, description)
 }
@@ -125,6 +211,13 @@ func (o *ObjectInspector) ByteString(name string, value *[]byte, mandatory bool,
 func (o *ObjectInspector) Value(name string, value RawValue, mandatory bool, description string) {
 	o.impl.PropertyValue(name, value, mandatory, description)
 }
+
+// UnknownFields handles the members of the object that were not inspected,
+// with CollectUnknown they are stored into fields on reading;
+// on writing previously collected fields are written back
+func (o *ObjectInspector) UnknownFields(policy UnknownFieldPolicy, fields *[]RawField) {
+	o.impl.UnknownFields(policy, fields)
+}
 func (o *ObjectInspector) End() *Inspector {
 	o.impl.EndObject()
 	return (*Inspector)(o)
@@ -2,24 +2,22 @@ package inspect_test
 
 import (
 	"bytes"
+	"errors"
+	"math"
 	"testing"
 
 	"strconv"
 
 	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/binary"
+	"github.com/tvanomr/inspect/internal/inspecttest"
 	"github.com/tvanomr/inspect/json"
 )
 
-type intValue int
-
-func (v *intValue) Inspect(inspector *inspect.Inspector) {
-	inspector.Int((*int)(v))
-}
-
-const defaultIntValue intValue = 10
+const defaultIntValue inspecttest.IntValue = 10
 
 func TestValueWrite(t *testing.T) {
-	writer := inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer]))
+	writer := inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer, *json.Writer]))
 	value := defaultIntValue
 	var buffer bytes.Buffer
 	writer.SetWriter(&buffer, 10)
@@ -33,3 +31,266 @@ func TestValueWrite(t *testing.T) {
 		t.Log("ok", result)
 	}
 }
+
+func TestBoolJSON(t *testing.T) {
+	writer := inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer, *json.Writer]))
+	value := inspecttest.Flags{Enabled: true}
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil {
+		t.Fatal(writer.LastError())
+	}
+	expected := `{"enabled":true,"visible":false}`
+	if buffer.String() != expected {
+		t.Fatal("got", buffer.String(), "expected", expected)
+	}
+
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader]))
+	reader.SetReader(&buffer)
+	var result inspecttest.Flags
+	result.Inspect(reader)
+	if reader.LastError() != nil {
+		t.Fatal(reader.LastError())
+	}
+	if result != value {
+		t.Fatal("got", result, "expected", value)
+	}
+
+	reader.SetReader(bytes.NewBufferString(`{"enabled":1,"visible":false}`))
+	result.Inspect(reader)
+	if !errors.Is(reader.LastError(), json.ErrNotABool) {
+		t.Fatal("got", reader.LastError(), "expected", json.ErrNotABool)
+	}
+}
+
+func TestBoolBinary(t *testing.T) {
+	writer := inspect.NewInspector(new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer]))
+	value := inspecttest.Flags{Visible: true}
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil {
+		t.Fatal(writer.LastError())
+	}
+	if !bytes.Equal(buffer.Bytes(), []byte{0, 1}) {
+		t.Fatal("got", buffer.Bytes(), "expected", []byte{0, 1})
+	}
+
+	reader := inspect.NewInspector(new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader]))
+	reader.SetReader(&buffer)
+	var result inspecttest.Flags
+	result.Inspect(reader)
+	if reader.LastError() != nil {
+		t.Fatal(reader.LastError())
+	}
+	if result != value {
+		t.Fatal("got", result, "expected", value)
+	}
+
+	reader.SetReader(bytes.NewReader([]byte{2, 0}))
+	result.Inspect(reader)
+	if !errors.Is(reader.LastError(), binary.ErrInvalidBool) {
+		t.Fatal("got", reader.LastError(), "expected", binary.ErrInvalidBool)
+	}
+}
+
+type counters struct {
+	small  int8
+	medium int16
+	byte   uint8
+	total  uint64
+}
+
+func (c *counters) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("counters", "integer counters")
+	o.Int8("small", &c.small, true, "small signed counter")
+	o.Int16("medium", &c.medium, true, "medium signed counter")
+	o.Uint8("byte", &c.byte, true, "byte counter")
+	o.Uint64("total", &c.total, true, "total counter")
+	o.End()
+}
+
+func TestIntegersJSON(t *testing.T) {
+	writer := inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer, *json.Writer]))
+	value := counters{small: math.MinInt8, medium: math.MaxInt16, byte: math.MaxUint8, total: math.MaxUint64}
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil {
+		t.Fatal(writer.LastError())
+	}
+
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader]))
+	reader.SetReader(&buffer)
+	var result counters
+	result.Inspect(reader)
+	if reader.LastError() != nil {
+		t.Fatal(reader.LastError())
+	}
+	if result != value {
+		t.Fatal("got", result, "expected", value)
+	}
+
+	reader.SetReader(bytes.NewBufferString(`{"small":1,"medium":2,"byte":256,"total":4}`))
+	result.Inspect(reader)
+	var overflow *inspect.OverflowError
+	if !errors.As(reader.LastError(), &overflow) || !errors.Is(reader.LastError(), inspect.ErrOverflow) {
+		t.Fatal("got", reader.LastError(), "expected overflow")
+	}
+	if overflow.Type != "uint8" || overflow.Value != "256" {
+		t.Fatal("got", overflow.Type, overflow.Value, "expected uint8 256")
+	}
+}
+
+func TestIntegersBinary(t *testing.T) {
+	writer := inspect.NewInspector(new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer]))
+	value := counters{small: math.MaxInt8, medium: math.MinInt16, byte: 7, total: math.MaxUint64}
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil {
+		t.Fatal(writer.LastError())
+	}
+
+	reader := inspect.NewInspector(new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader]))
+	reader.SetReader(&buffer)
+	var result counters
+	result.Inspect(reader)
+	if reader.LastError() != nil {
+		t.Fatal(reader.LastError())
+	}
+	if result != value {
+		t.Fatal("got", result, "expected", value)
+	}
+
+	// 128 encoded as a zigzag varint does not fit into int8
+	reader.SetReader(bytes.NewReader([]byte{0x80, 0x02}))
+	result.Inspect(reader)
+	if !errors.Is(reader.LastError(), inspect.ErrOverflow) {
+		t.Fatal("got", reader.LastError(), "expected overflow")
+	}
+}
+
+type patch struct {
+	name  inspect.Optional[inspecttest.IntValue]
+	age   inspect.Optional[inspecttest.IntValue]
+	score inspect.Optional[inspecttest.IntValue]
+}
+
+func (p *patch) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("patch", "partial update")
+	inspect.PropertyOptional(o, "name", &p.name, "name")
+	inspect.PropertyOptional(o, "age", &p.age, "age")
+	inspect.PropertyOptional(o, "score", &p.score, "score")
+	o.End()
+}
+
+func testOptional(t *testing.T, writer *inspect.Inspector, reader *inspect.Inspector, expected []byte) {
+	value := patch{score: inspect.Some(defaultIntValue)}
+	value.age.SetNull()
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil {
+		t.Fatal(writer.LastError())
+	}
+	if !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatal("got", buffer.Bytes(), "expected", expected)
+	}
+
+	reader.SetReader(&buffer)
+	result := patch{name: inspect.Some(inspecttest.IntValue(1)), age: inspect.Some(inspecttest.IntValue(2))}
+	result.Inspect(reader)
+	if reader.LastError() != nil {
+		t.Fatal(reader.LastError())
+	}
+	if result != value {
+		t.Fatal("got", result, "expected", value)
+	}
+}
+
+func TestOptionalJSON(t *testing.T) {
+	testOptional(t, inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer, *json.Writer])),
+		inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader])),
+		[]byte(`{"age":null,"score":10}`))
+}
+
+func TestOptionalBinary(t *testing.T) {
+	testOptional(t, inspect.NewInspector(new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer])),
+		inspect.NewInspector(new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader])),
+		[]byte{0, 1, 0, 1, 1, 20})
+}
+
+type scalarPatch struct {
+	title inspect.Optional[string]
+	count inspect.Optional[int64]
+	ratio inspect.Optional[float64]
+}
+
+func (p *scalarPatch) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("scalarPatch", "partial update of built-in types")
+	inspect.PropertyOptionalScalar(o, "title", &p.title, "title")
+	inspect.PropertyOptionalScalar(o, "count", &p.count, "count")
+	inspect.PropertyOptionalScalar(o, "ratio", &p.ratio, "ratio")
+	o.End()
+}
+
+func TestOptionalScalar(t *testing.T) {
+	writer := inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer, *json.Writer]))
+	value := scalarPatch{title: inspect.Some("a"), ratio: inspect.Some(0.5)}
+	value.count.SetNull()
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil || buffer.String() != `{"title":"a","count":null,"ratio":0.5}` {
+		t.Fatal("got", buffer.String(), writer.LastError())
+	}
+
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader]))
+	reader.SetReader(&buffer)
+	result := scalarPatch{count: inspect.Some(int64(1))}
+	result.Inspect(reader)
+	if reader.LastError() != nil || result != value {
+		t.Fatal("got", result, reader.LastError())
+	}
+	reader.SetReader(bytes.NewBufferString(`{"count":3}`))
+	result.Inspect(reader)
+	if reader.LastError() != nil || result != (scalarPatch{count: inspect.Some(int64(3))}) {
+		t.Fatal("got", result, reader.LastError())
+	}
+}
+
+func TestOutOfOrderJSON(t *testing.T) {
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader]))
+	inputs := []string{
+		`{"id":7,"flags":{"enabled":true,"visible":true}}`,
+		`{"flags":{"visible":true,"enabled":true},"id":7}`,
+		`{ "flags" : { "visible" : true , "enabled" : true } , "id" : 7 }`,
+	}
+	expected := inspecttest.Wrapper{ID: 7, Flags: inspecttest.Flags{Enabled: true, Visible: true}}
+	for _, input := range inputs {
+		reader.SetReader(bytes.NewBufferString(input))
+		var result inspecttest.Wrapper
+		result.Inspect(reader)
+		if reader.LastError() != nil {
+			t.Fatal(input, reader.LastError())
+		}
+		if result != expected {
+			t.Fatal(input, "got", result, "expected", expected)
+		}
+	}
+
+	reader.SetReader(bytes.NewBufferString(`{"flags":{"visible":true,"enabled":true},"id":7,"extra":1}`))
+	var result inspecttest.Wrapper
+	result.Inspect(reader)
+	if !errors.Is(reader.LastError(), json.ErrObjectTooBig) {
+		t.Fatal("got", reader.LastError(), "expected", json.ErrObjectTooBig)
+	}
+}
@@ -8,15 +8,36 @@ import (
 
 type Reader interface {
 	SetReader(io.Reader)
+	// SetLimits bounds what is accepted from the following inputs
+	SetLimits(limits Limits)
+	// Offset returns the position in the input in bytes
+	Offset() int64
+	Bool() (bool, error)
+	Int8() (int8, error)
+	Int16() (int16, error)
 	Int32() (int32, error)
 	Int64() (int64, error)
+	Uint8() (uint8, error)
+	Uint16() (uint16, error)
+	Uint32() (uint32, error)
+	Uint64() (uint64, error)
 	Float32() (float32, error)
 	Float64() (float64, error)
 	String() (string, error)
 	Bytes() ([]byte, error)
 	ByteString() ([]byte, error)
+	// IsNull consumes the next value and returns true if it is a null,
+	// otherwise the value is left for the following read
+	IsNull() (bool, error)
 	StartObject() error
 	Property(name string) error
+	// OptionalProperty returns false without consuming anything
+	// when the property is not present
+	OptionalProperty(name string) (bool, error)
+	// UnknownFields consumes the members of the current object that were not read yet,
+	// it returns them only with CollectUnknown policy. Readers of formats without member
+	// names return ErrUnknownFieldsUnsupported for FailOnUnknown and CollectUnknown
+	UnknownFields(policy UnknownFieldPolicy) ([]RawField, error)
 	EndObject() error
 	// length==-1 => check HaveNext() after every value
 	// length==0 => no need to call EndArray
@@ -27,28 +48,82 @@ type Reader interface {
 	StartMap() (length int, err error)
 	NextKey() (string, error)
 	EndMap() error
+	// Skip discards the next value whatever its structure is
+	Skip() error
 }
 
 type Writer interface {
 	SetWriter(writer io.Writer, bufferSize int)
+	Bool(value bool) error
+	Int8(value int8) error
+	Int16(value int16) error
 	Int32(value int32) error
 	Int64(value int64) error
+	Uint8(value uint8) error
+	Uint16(value uint16) error
+	Uint32(value uint32) error
+	Uint64(value uint64) error
 	Float32(value float32, format byte, precision int) error
 	Float64(value float64, format byte, precision int) error
 	String(value string) error
 	Bytes(value []byte) error
 	ByteString(value []byte) error
+	Null() error
+	// NotNull marks that a nullable value follows
+	NotNull() error
 	StartObject() error
 	Property(name string) error
+	OptionalProperty(name string, present bool) error
+	RawProperty(field RawField) error
 	EndObject() error
 	StartArray(length int) error
 	EndArray() error
 	StartMap(length int) error
 	NextKey(key string) error
 	EndMap() error
+	// Skip stands for a value left out, it writes what the reader of the format
+	// discards with Skip, if anything
+	Skip() error
+	// SortKeys tells whether the output needs map keys in order
+	SortKeys() bool
 	Flush() error
 }
 
+// FieldNumbered is implemented by readers and writers of formats that identify
+// properties by number rather than by name, e.g. protobuf. FieldNumber is called
+// with the number of the property that follows.
+type FieldNumbered interface {
+	FieldNumber(number int)
+}
+
+// Described is implemented by writers of formats that show the names and descriptions
+// given to the inspector, e.g. XML. Describe is called before StartObject, Property,
+// OptionalProperty, StartArray and StartMap, elementName is set only for arrays and maps.
+type Described interface {
+	Describe(name string, elementName string, description string)
+}
+
+// Varints is implemented by readers and writers of formats that write integers
+// with a fixed width, e.g. binary in fixed mode. UseVarint is called before
+// a property whose integers are small enough to be written as varints.
+type Varints interface {
+	UseVarint()
+}
+
+// Attributes is implemented by readers and writers of formats that can hold scalar
+// properties as attributes of the element of their object, e.g. XML. UseAttribute
+// is called before such a property.
+type Attributes interface {
+	UseAttribute()
+}
+
+// Canonical is implemented by writers of formats with a canonical form, e.g. JSON
+// after RFC 8785 or deterministically encoded CBOR, so equal values are written
+// as equal bytes
+type Canonical interface {
+	SetCanonical(canonical bool)
+}
+
 type RawValue interface {
 	encoding.TextMarshaler
 	gob.GobEncoder
@@ -60,26 +135,51 @@ type InspectorInterface interface {
 	LastError() error
 	SetReader(io.Reader)
 	SetWriter(writer io.Writer, bufferSize int)
+	Bool(value *bool)
+	Int8(value *int8)
+	Int16(value *int16)
 	Int32(value *int32)
 	Int64(value *int64)
 	Int(value *int)
+	Uint8(value *uint8)
+	Uint16(value *uint16)
+	Uint32(value *uint32)
+	Uint64(value *uint64)
+	Uint(value *uint)
 	Float32(value *float32, format byte, precision int)
 	Float64(value *float64, format byte, precision int)
 	String(value *string)
 	Bytes(value *[]byte)
 	ByteString(value *[]byte)
 	Value(value RawValue)
+	Null(isNull *bool)
 	StartObject(name string, description string)
+	// FieldNumber sets the number of the following property for formats that use numbers
+	FieldNumber(number int)
+	// UseVarint makes the integers of the following property varints for formats with fixed-width integers
+	UseVarint()
+	// UseAttribute makes the following property an attribute for formats that have attributes
+	UseAttribute()
 	Property(name string, mandatory bool, description string) bool
+	OptionalProperty(name string, present *bool, description string) bool
+	PropertyBool(name string, value *bool, mandatory bool, description string)
 	PropertyInt32(name string, value *int32, mandatory bool, description string)
 	PropertyInt64(name string, value *int64, mandatory bool, description string)
+	PropertyInt8(name string, value *int8, mandatory bool, description string)
+	PropertyInt16(name string, value *int16, mandatory bool, description string)
 	PropertyInt(name string, value *int, mandatory bool, description string)
+	PropertyUint8(name string, value *uint8, mandatory bool, description string)
+	PropertyUint16(name string, value *uint16, mandatory bool, description string)
+	PropertyUint32(name string, value *uint32, mandatory bool, description string)
+	PropertyUint64(name string, value *uint64, mandatory bool, description string)
+	PropertyUint(name string, value *uint, mandatory bool, description string)
 	PropertyFloat32(name string, value *float32, format byte, precision int, mandatory bool, description string)
 	PropertyFloat64(name string, value *float64, format byte, precision int, mandatory bool, description string)
 	PropertyString(name string, value *string, mandatory bool, description string)
 	PropertyBytes(name string, value *[]byte, mandatory bool, description string)
 	PropertyByteString(name string, value *[]byte, mandatory bool, description string)
 	PropertyValue(name string, value RawValue, mandatory bool, description string)
+	UnknownFields(policy UnknownFieldPolicy, fields *[]RawField)
 	EndObject()
 	ReadArray() int
 	WriteArray(name string, elementName string, length int, description string)
@@ -90,7 +190,10 @@ type InspectorInterface interface {
 	ReadNextKey() string
 	WriteNextKey(key string)
 	EndMap()
+	Skip()
 	IsReading() bool
+	// SortKeys tells the map helpers to write keys in order
+	SortKeys() bool
 	Flush()
 }
 
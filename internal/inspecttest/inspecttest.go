@@ -0,0 +1,319 @@
+// Package inspecttest holds the values shared by the tests of inspect and of its formats
+package inspecttest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+)
+
+type IntValue int
+
+func (v *IntValue) Inspect(inspector *inspect.Inspector) {
+	inspector.Int((*int)(v))
+}
+
+type Integer int64
+
+func (i *Integer) Inspect(inspector *inspect.Inspector) {
+	inspector.Int64((*int64)(i))
+}
+
+type Real float64
+
+func (r *Real) Inspect(inspector *inspect.Inspector) {
+	inspector.Float64((*float64)(r), 'g', -1)
+}
+
+type Text string
+
+func (t *Text) Inspect(inspector *inspect.Inspector) {
+	inspector.String((*string)(t))
+}
+
+type Blob []byte
+
+func (b *Blob) Inspect(inspector *inspect.Inspector) {
+	inspector.Bytes((*[]byte)(b))
+}
+
+type Numbers []IntValue
+
+func (n *Numbers) Inspect(inspector *inspect.Inspector) {
+	inspect.Array[*IntValue]((*[]IntValue)(n), inspector, "numbers", "number", "numbers")
+}
+
+type Integers []Integer
+
+func (i *Integers) Inspect(inspector *inspect.Inspector) {
+	inspect.Array[*Integer]((*[]Integer)(i), inspector, "integers", "integer", "integers")
+}
+
+type Texts []Text
+
+func (t *Texts) Inspect(inspector *inspect.Inspector) {
+	inspect.Array[*Text]((*[]Text)(t), inspector, "texts", "text", "texts")
+}
+
+type Counts map[string]IntValue
+
+func (c *Counts) Inspect(inspector *inspect.Inspector) {
+	inspect.StringMap[IntValue]((*map[string]IntValue)(c), inspector, "counts", "count", "counts")
+}
+
+type Dictionary map[string]Text
+
+func (d *Dictionary) Inspect(inspector *inspect.Inspector) {
+	inspect.StringMap[Text]((*map[string]Text)(d), inspector, "dictionary", "text", "texts by key")
+}
+
+type Flags struct {
+	Enabled bool
+	Visible bool
+}
+
+func (f *Flags) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("flags", "boolean flags")
+	o.Bool("enabled", &f.Enabled, true, "enabled flag")
+	o.Bool("visible", &f.Visible, true, "visible flag")
+	o.End()
+}
+
+type Wrapper struct {
+	ID    IntValue
+	Flags Flags
+}
+
+func (w *Wrapper) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("wrapper", "object with a nested object")
+	w.ID.Inspect(o.Property("id", true, "identifier"))
+	w.Flags.Inspect(o.Property("flags", true, "nested flags"))
+	o.End()
+}
+
+type Settings struct {
+	Mandatory bool
+	Name      string
+	Count     int
+}
+
+func (s *Settings) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("settings", "settings with a single property")
+	o.String("name", &s.Name, true, "name")
+	o.Int("count", &s.Count, s.Mandatory, "count")
+	o.End()
+}
+
+type Item struct {
+	Name  string
+	Price float64
+}
+
+func (i *Item) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("item", "order item")
+	o.String("name", &i.Name, true, "item name")
+	o.Float64("price", &i.Price, 'g', -1, true, "item price")
+	o.End()
+}
+
+type Order struct {
+	ID       IntValue
+	Customer Wrapper
+	Items    []Item
+	Notes    []*Item
+	Tags     map[string]IntValue
+}
+
+func (o *Order) Inspect(inspector *inspect.Inspector) {
+	object := inspector.StartObject("order", "order with nested values")
+	inspect.PropertyObject(object, "id", &o.ID, true, "order id")
+	inspect.PropertyObject(object, "customer", &o.Customer, true, "customer")
+	inspect.PropertyArray(object, "items", &o.Items, "item", true, "order items")
+	inspect.PropertyArrayPtr(object, "notes", &o.Notes, "note", true, "order notes")
+	inspect.PropertyMap(object, "tags", &o.Tags, "tag", true, "order tags")
+	object.End()
+}
+
+func (o *Order) Equal(other *Order) bool {
+	if o.ID != other.ID || o.Customer != other.Customer || len(o.Items) != len(other.Items) ||
+		len(o.Notes) != len(other.Notes) || len(o.Tags) != len(other.Tags) {
+		return false
+	}
+	for i := range o.Items {
+		if o.Items[i] != other.Items[i] {
+			return false
+		}
+	}
+	for i := range o.Notes {
+		if *o.Notes[i] != *other.Notes[i] {
+			return false
+		}
+	}
+	for key, value := range o.Tags {
+		if other.Tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+type Extensible struct {
+	Name    string
+	Unknown []inspect.RawField
+}
+
+func (e *Extensible) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("extensible", "object keeping unknown members")
+	o.String("name", &e.Name, true, "name")
+	o.UnknownFields(inspect.CollectUnknown, &e.Unknown)
+	o.End()
+}
+
+type RecordV1 struct {
+	Name   string
+	Legacy string
+	Count  int
+}
+
+func (r *RecordV1) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("record", "record with a legacy field")
+	o.String("name", &r.Name, true, "name")
+	o.String("legacy", &r.Legacy, true, "legacy field")
+	o.Int("count", &r.Count, true, "count")
+	o.End()
+}
+
+// RecordV2 is RecordV1 with the legacy field skipped
+type RecordV2 struct {
+	Name  string
+	Count int
+}
+
+func (r *RecordV2) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("record", "record with a legacy field")
+	o.String("name", &r.Name, true, "name")
+	if legacy := o.Property("legacy", true, "deprecated"); legacy != nil {
+		legacy.Skip()
+	}
+	o.Int("count", &r.Count, true, "count")
+	o.End()
+}
+
+type Part struct {
+	Name   string
+	Weight float64
+}
+
+func (p *Part) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("part", "part of a shipment")
+	o.Number(1).String("name", &p.Name, true, "name")
+	o.Number(2).Float64("weight", &p.Weight, 'g', -1, true, "weight in kg")
+	o.End()
+}
+
+type Shipment struct {
+	ID     int32
+	Label  string
+	Sizes  Numbers
+	Parts  []Part
+	Stock  map[string]IntValue
+	Prices map[IntValue]Real
+}
+
+func (s *Shipment) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("shipment", "shipment with numbered properties")
+	o.Number(1).Int32("id", &s.ID, true, "id")
+	o.Number(2).String("label", &s.Label, true, "label")
+	inspect.PropertyObject(o.Number(3), "sizes", &s.Sizes, true, "sizes")
+	inspect.PropertyArray[*Part](o.Number(4), "parts", &s.Parts, "part", true, "parts")
+	inspect.PropertyMap[IntValue](o.Number(5), "stock", &s.Stock, "count", true, "stock")
+	if inspector := o.Number(6).Property("prices", true, "prices"); inspector != nil {
+		inspect.Map[IntValue, *IntValue, Real](&s.Prices, inspector, "prices", "id", "price", "prices")
+	}
+	o.End()
+}
+
+type Book struct {
+	ISBN  string
+	Title string
+	Pages int
+}
+
+func (b *Book) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("book", "book with an attribute")
+	o.Attribute().String("isbn", &b.ISBN, true, "isbn")
+	o.String("title", &b.Title, true, "title")
+	o.Int("pages", &b.Pages, true, "number of pages")
+	o.End()
+}
+
+// Nested writes orders with writer and reads them back with reader
+func Nested(t *testing.T, writer *inspect.Inspector, reader *inspect.Inspector) {
+	value := Order{
+		ID:       3,
+		Customer: Wrapper{ID: 4, Flags: Flags{Enabled: true}},
+		Items:    []Item{{"apple", 1.5}, {"pear", 2}},
+		Notes:    []*Item{{"gift", 0}},
+		Tags:     map[string]IntValue{"a": 1, "b": 2},
+	}
+	for _, expected := range []Order{value, {Items: []Item{}, Notes: []*Item{}, Tags: map[string]IntValue{}}} {
+		var buffer bytes.Buffer
+		writer.SetWriter(&buffer, 10)
+		expected.Inspect(writer)
+		writer.Flush()
+		if writer.LastError() != nil {
+			t.Fatal(writer.LastError())
+		}
+		reader.SetReader(&buffer)
+		result := Order{Items: make([]Item, 5), Tags: map[string]IntValue{"c": 3}}
+		result.Inspect(reader)
+		if reader.LastError() != nil {
+			t.Fatal(reader.LastError())
+		}
+		if !result.Equal(&expected) {
+			t.Fatal("got", result, "expected", expected)
+		}
+	}
+}
+
+// Skip writes records of both versions with writer and reads them back as RecordV2 with reader
+func Skip(t *testing.T, writer *inspect.Inspector, reader *inspect.Inspector) {
+	old := RecordV1{Name: "a", Legacy: string(bytes.Repeat([]byte("x"), 300)), Count: 7}
+	for _, value := range []inspect.Inspectable{&old, &RecordV2{Name: "a", Count: 7}} {
+		var buffer bytes.Buffer
+		writer.SetWriter(&buffer, 10)
+		value.Inspect(writer)
+		writer.Flush()
+		if writer.LastError() != nil {
+			t.Fatal(writer.LastError())
+		}
+		reader.SetReader(&buffer)
+		var result RecordV2
+		result.Inspect(reader)
+		if reader.LastError() != nil {
+			t.Fatal(reader.LastError())
+		}
+		if result.Name != old.Name || result.Count != old.Count {
+			t.Fatal("got", result, "expected", old)
+		}
+	}
+}
+
+// Fuzz reads data into each of values, the errors are ignored as fuzzing looks for panics
+// and hangs, the limits keep hostile lengths and nesting from running out of memory
+func Fuzz(data []byte, format inspect.Format, values ...inspect.Inspectable) {
+	limits := inspect.WithLimits(inspect.Limits{MaxDepth: 16, MaxArrayLength: 1 << 10})
+	for _, value := range values {
+		inspect.Unmarshal(data, &inspectable{value}, format, limits)
+	}
+}
+
+// inspectable lets Unmarshal take any Inspectable
+type inspectable struct {
+	value inspect.Inspectable
+}
+
+func (i *inspectable) Inspect(inspector *inspect.Inspector) {
+	i.value.Inspect(inspector)
+}
@@ -0,0 +1,122 @@
+package json
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// canonicalObject is an object of canonical output, its members are sorted once it ends
+type canonicalObject struct {
+	// buffer offset right after the opening brace
+	start   int
+	members []canonicalMember
+}
+
+type canonicalMember struct {
+	name string
+	// buffer offset of the member name
+	start int
+}
+
+// lessUTF16 orders strings by their UTF-16 code units as RFC 8785 requires
+func lessUTF16(a string, b string) bool {
+	left, right := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(left) && i < len(right); i++ {
+		if left[i] != right[i] {
+			return left[i] < right[i]
+		}
+	}
+	return len(left) < len(right)
+}
+
+// sortMembers rewrites the members written after object.start in sorted order
+func sortMembers(buffer []byte, object canonicalObject) []byte {
+	if len(object.members) < 2 {
+		return buffer
+	}
+	type span struct {
+		name string
+		data []byte
+	}
+	spans := make([]span, len(object.members))
+	for i, member := range object.members {
+		end := len(buffer)
+		if i+1 < len(object.members) {
+			// leave out the comma
+			end = object.members[i+1].start - 1
+		}
+		spans[i] = span{member.name, append([]byte(nil), buffer[member.start:end]...)}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return lessUTF16(spans[i].name, spans[j].name)
+	})
+	buffer = buffer[:object.start]
+	for i, span := range spans {
+		if i > 0 {
+			buffer = append(buffer, ',')
+		}
+		buffer = append(buffer, span.data...)
+	}
+	return buffer
+}
+
+// maxSafeInteger is the largest integer held exactly by a double along with its neighbours,
+// readers of canonical output parse every number as a double
+const maxSafeInteger = 1<<53 - 1
+
+// appendCanonicalFloat formats value the way ECMAScript does, as RFC 8785 requires
+func appendCanonicalFloat(buffer []byte, value float64) ([]byte, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return buffer, ErrInvalidNumber
+	}
+	if value == 0 {
+		// -0 as well
+		return append(buffer, '0'), nil
+	}
+	format := byte('f')
+	if abs := math.Abs(value); abs < 1e-6 || abs >= 1e21 {
+		format = 'e'
+	}
+	start := len(buffer)
+	buffer = strconv.AppendFloat(buffer, value, format, -1, 64)
+	if format == 'e' {
+		// e-07 => e-7
+		number := buffer[start:]
+		if n := len(number); n >= 4 && number[n-4] == 'e' && number[n-3] == '-' && number[n-2] == '0' {
+			number[n-2] = number[n-1]
+			buffer = buffer[:len(buffer)-1]
+		}
+	}
+	return buffer, nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendCanonicalString escapes only quotes, backslashes and control characters
+func appendCanonicalString(buffer []byte, value string) []byte {
+	buffer = append(buffer, '"')
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '"' || c == '\\':
+			buffer = append(buffer, '\\', c)
+		case c == '\b':
+			buffer = append(buffer, '\\', 'b')
+		case c == '\t':
+			buffer = append(buffer, '\\', 't')
+		case c == '\n':
+			buffer = append(buffer, '\\', 'n')
+		case c == '\f':
+			buffer = append(buffer, '\\', 'f')
+		case c == '\r':
+			buffer = append(buffer, '\\', 'r')
+		case c < 0x20:
+			buffer = append(buffer, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+		default:
+			buffer = append(buffer, c)
+		}
+	}
+	return append(buffer, '"')
+}
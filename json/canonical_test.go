@@ -0,0 +1,81 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/json"
+)
+
+func canonicalWriter() *inspect.Inspector {
+	impl := new(inspect.TextWriteInspector[json.Writer, *json.Writer])
+	impl.Writer().SetCanonical(true)
+	return inspect.NewInspector(impl)
+}
+
+func TestCanonicalUnknownFields(t *testing.T) {
+	input := `{"z":"l\u0061st","name":"n","b":[1.0,{"y":2e0,"x":1}],"a":3}`
+	var value inspecttest.Extensible
+	if err := inspect.Unmarshal([]byte(input), &value, json.Format); err != nil {
+		t.Fatal(err)
+	}
+	// members are sorted, unknown values are written canonically too
+	expected := `{"a":3,"b":[1,{"x":1,"y":2}],"name":"n","z":"last"}`
+	writer := canonicalWriter()
+	for _, bufferSize := range []int{1, 4096} {
+		var buffer bytes.Buffer
+		writer.SetWriter(&buffer, bufferSize)
+		value.Inspect(writer)
+		writer.Flush()
+		if writer.LastError() != nil || buffer.String() != expected {
+			t.Fatal(bufferSize, "got", buffer.String(), writer.LastError())
+		}
+	}
+}
+
+type count int64
+
+func (c *count) Inspect(inspector *inspect.Inspector) {
+	inspector.Int64((*int64)(c))
+}
+
+type size uint64
+
+func (s *size) Inspect(inspector *inspect.Inspector) {
+	inspector.Uint64((*uint64)(s))
+}
+
+func TestCanonicalIntegers(t *testing.T) {
+	tests := []struct {
+		value    inspect.Inspectable
+		expected string
+	}{
+		{ptr(count(1<<53 - 1)), "9007199254740991"},
+		{ptr(count(-(1<<53 - 1))), "-9007199254740991"},
+		{ptr(count(1 << 53)), ""},
+		{ptr(count(-1 << 53)), ""},
+		{ptr(size(1<<53 - 1)), "9007199254740991"},
+		{ptr(size(1 << 63)), ""},
+	}
+	writer := canonicalWriter()
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		writer.SetWriter(&buffer, 4096)
+		test.value.Inspect(writer)
+		writer.Flush()
+		if test.expected == "" {
+			if !errors.Is(writer.LastError(), json.ErrUnsafeInteger) {
+				t.Fatal(test.value, "got", writer.LastError())
+			}
+		} else if writer.LastError() != nil || buffer.String() != test.expected {
+			t.Fatal("got", buffer.String(), writer.LastError())
+		}
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
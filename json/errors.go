@@ -1,5 +1,7 @@
 package json
 
+import "github.com/tvanomr/inspect"
+
 type jsonError int
 
 const (
@@ -11,6 +13,14 @@ const (
 	ErrObjectTooBig
 	ErrArrayTooBig
 	ErrMapTooBig
+	ErrNotABool
+	ErrNotANumber
+	ErrNotAString
+	ErrNotAnArray
+	ErrInvalidNumber
+	ErrUnsafeInteger
+	ErrRawField
+	ErrInvalidRawValue
 )
 
 var errorMessages = map[jsonError]string{
@@ -21,8 +31,26 @@ var errorMessages = map[jsonError]string{
 	ErrWrongField:          "supplied field name differs from the one in json",
 	ErrObjectTooBig:        "object contains more fields than requested",
 	ErrArrayTooBig:         "array contains more items than was read",
-	ErrMapTooBig:           "map contains more items than was read"}
+	ErrMapTooBig:           "map contains more items than was read",
+	ErrNotABool:            "value is not a boolean",
+	ErrNotANumber:          "value is not a number",
+	ErrNotAString:          "value is not a string",
+	ErrNotAnArray:          "value is not an array",
+	ErrInvalidNumber:       "NaN and infinity can't be written",
+	ErrUnsafeInteger:       "integer is too large to be written exactly in canonical JSON",
+	ErrRawField:            "fields collected from another format can't be written",
+	ErrInvalidRawValue:     "collected field value is not valid JSON"}
 
 func (j jsonError) Error() string {
 	return errorMessages[j]
 }
+
+func (j jsonError) Is(target error) bool {
+	switch j {
+	case ErrNotABool, ErrNotANumber, ErrNotAString, ErrNotAnArray:
+		return target == inspect.ErrTypeMismatch
+	case ErrObjectTooBig:
+		return target == inspect.ErrUnknownField
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package json
+
+import (
+	"io"
+	"reflect"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tvanomr/inspect"
+)
+
+// countingReader counts the bytes handed to the iterator
+// and fails once the input is longer than the allowed total
+type countingReader struct {
+	reader io.Reader
+	count  int64
+	// bytes handed over by the last Read, the iterator refills its buffer with nothing else
+	last   int64
+	limits *inspect.Limits
+	// while a string is read the input may not go past stop, 0 when there is no string
+	stop int64
+}
+
+func (c *countingReader) reset(reader io.Reader) {
+	c.reader = reader
+	c.count = 0
+	c.last = 0
+	c.stop = 0
+}
+
+// startString bounds the input until endString by the longest encoding of a string
+// of the allowed length, every byte of it escaped as \u0000 and the quotes
+func (c *countingReader) startString() {
+	if max := c.limits.MaxStringLength; max > 0 {
+		c.stop = c.count + 6*max + 2
+	}
+}
+
+func (c *countingReader) endString() {
+	c.stop = 0
+}
+
+func (c *countingReader) Read(buffer []byte) (int, error) {
+	if c.stop > 0 && c.count+int64(len(buffer)) > c.stop {
+		if c.count >= c.stop {
+			return 0, c.limits.CheckString(c.limits.MaxStringLength + 1)
+		}
+		buffer = buffer[:c.stop-c.count]
+	}
+	if max := c.limits.MaxTotalBytes; max > 0 && c.count+int64(len(buffer)) > max {
+		if c.count >= max {
+			// the input may end right at the limit
+			var next [1]byte
+			read, err := c.reader.Read(next[:])
+			if read == 0 {
+				return 0, err
+			}
+			return 0, c.limits.CheckTotal(c.count + 1)
+		}
+		buffer = buffer[:max-c.count]
+	}
+	read, err := c.reader.Read(buffer)
+	c.count += int64(read)
+	if read > 0 {
+		c.last = int64(read)
+	}
+	return read, err
+}
+
+// head is the position of an iterator in its buffer, jsoniter doesn't export it
+var head, _ = reflect.TypeOf(jsoniter.Iterator{}).FieldByName("head")
+
+// iteratorHead returns the position of iterator in its buffer, it is read
+// for every member buffered out of order so it may not format the buffer
+func iteratorHead(iterator *jsoniter.Iterator) int64 {
+	return reflect.ValueOf(iterator).Elem().FieldByIndex(head.Index).Int()
+}
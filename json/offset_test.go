@@ -0,0 +1,34 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/json"
+)
+
+func TestOffset(t *testing.T) {
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader]))
+	padding := strings.Repeat(" ", 3000)
+	for _, input := range []string{
+		`{"first":"a","second":` + padding + `5}`,
+		// second is buffered while looking for first
+		`{"second":` + padding + `5,"first":"a"}`,
+		// the unknown member fails where its value starts
+		`{"first":"a",` + padding + `"second":"b","third":5}`,
+	} {
+		reader.SetReader(bytes.NewBufferString(input))
+		var value pair
+		value.Inspect(reader)
+		var pathError *inspect.PathError
+		if !errors.As(reader.LastError(), &pathError) {
+			t.Fatal("got", reader.LastError())
+		}
+		if expected := int64(strings.IndexByte(input, '5')); pathError.Offset != expected {
+			t.Fatal("got offset", pathError.Offset, "expected", expected)
+		}
+	}
+}
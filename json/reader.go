@@ -5,90 +5,359 @@ import (
 	"io"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/tvanomr/inspect"
 )
 
+// member is an object member read ahead of time
+type member struct {
+	data []byte
+	// offset of data in the input
+	offset int64
+}
+
+type level struct {
+	isObject   bool
+	endReached bool
+	// number of array items or map keys read so far
+	count int64
+	// members skipped while looking for a property that came later in the input
+	buffered map[string]member
+	// names of the buffered members in input order
+	order []string
+	// iterator to go back to once a buffered member has been read
+	parent     *jsoniter.Iterator
+	parentBase int64
+}
+
 type Reader struct {
-	iterator        *jsoniter.Iterator
-	levels          stack[bool]
-	isObject        bool
-	endReached      bool
-	endReachedStack stack[bool]
+	iterator *jsoniter.Iterator
+	input    countingReader
+	limits   inspect.Limits
+	// input offset of the buffer being read, -1 when reading the input itself
+	base    int64
+	current level
+	levels  stack[level]
 }
 
 func (r *Reader) SetReader(reader io.Reader) {
+	for len(r.levels) > 0 {
+		r.restore()
+		r.current = r.levels.pop()
+	}
+	r.restore()
+	r.current = level{}
+	r.base = -1
+	r.input.reset(reader)
+	r.input.limits = &r.limits
 	if r.iterator == nil {
-		r.iterator = jsoniter.Parse(jsoniter.ConfigCompatibleWithStandardLibrary, reader, 1024)
+		r.iterator = jsoniter.Parse(jsoniter.ConfigCompatibleWithStandardLibrary, &r.input, 1024)
 	} else {
-		r.iterator.Reset(reader)
+		r.iterator.Reset(&r.input)
+		// Reset keeps the error of the previous input
+		r.iterator.Error = nil
+	}
+}
+
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+
+func (r *Reader) Offset() int64 {
+	head := iteratorHead(r.iterator)
+	if r.base >= 0 {
+		return r.base + head
+	}
+	return r.input.count - r.input.last + head
+}
+
+func (r *Reader) push(isObject bool) error {
+	if err := r.limits.CheckDepth(len(r.levels) + 1); err != nil {
+		return err
 	}
+	r.levels.push(r.current)
+	r.current = level{isObject: isObject}
+	return nil
+}
+
+// next counts an array item or a map key
+func (r *Reader) next() error {
+	r.current.count++
+	return r.limits.CheckArray(r.current.count)
 }
 
+func (r *Reader) pop() {
+	r.restore()
+	r.current = r.levels.pop()
+}
+
+// expect checks the type of the next value without consuming it
+func (r *Reader) expect(valueType jsoniter.ValueType, err error) error {
+	if r.iterator.WhatIsNext() != valueType {
+		if r.iterator.Error != nil {
+			return r.iterator.Error
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *Reader) Bool() (bool, error) {
+	if err := r.expect(jsoniter.BoolValue, ErrNotABool); err != nil {
+		return false, err
+	}
+	return r.iterator.ReadBool(), r.iterator.Error
+}
+func (r *Reader) Int8() (int8, error) {
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	return readNarrowInt[int16](r)
+}
 func (r *Reader) Int32() (int32, error) {
-	return r.iterator.ReadInt32(), r.iterator.Error
+	return readNarrowInt[int32](r)
 }
 func (r *Reader) Int64() (int64, error) {
+	if err := r.expect(jsoniter.NumberValue, ErrNotANumber); err != nil {
+		return 0, err
+	}
 	return r.iterator.ReadInt64(), r.iterator.Error
 }
+func (r *Reader) Uint8() (uint8, error) {
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	if err := r.expect(jsoniter.NumberValue, ErrNotANumber); err != nil {
+		return 0, err
+	}
+	return r.iterator.ReadUint64(), r.iterator.Error
+}
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := r.Int64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](result)
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := r.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
 func (r *Reader) Float32() (float32, error) {
+	if err := r.expect(jsoniter.NumberValue, ErrNotANumber); err != nil {
+		return 0, err
+	}
 	return r.iterator.ReadFloat32(), r.iterator.Error
 }
 
 func (r *Reader) Float64() (float64, error) {
+	if err := r.expect(jsoniter.NumberValue, ErrNotANumber); err != nil {
+		return 0, err
+	}
 	return r.iterator.ReadFloat64(), r.iterator.Error
 }
 
 func (r *Reader) String() (string, error) {
-	return r.iterator.ReadString(), r.iterator.Error
+	if err := r.expect(jsoniter.StringValue, ErrNotAString); err != nil {
+		return "", err
+	}
+	r.input.startString()
+	result := r.iterator.ReadString()
+	r.input.endString()
+	if r.iterator.Error != nil {
+		return "", r.iterator.Error
+	}
+	return result, r.limits.CheckString(int64(len(result)))
 }
 
 func (r *Reader) Bytes() ([]byte, error) {
-	result, err := base64.RawURLEncoding.DecodeString(r.iterator.ReadString())
+	value, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+	return base64.RawURLEncoding.DecodeString(value)
+}
+
+func (r *Reader) ByteString() ([]byte, error) {
+	if err := r.expect(jsoniter.StringValue, ErrNotAString); err != nil {
+		return nil, err
+	}
+	r.input.startString()
+	result := r.iterator.ReadStringAsSlice()
+	r.input.endString()
 	if r.iterator.Error != nil {
 		return nil, r.iterator.Error
 	}
-	if err != nil {
+	if err := r.limits.CheckString(int64(len(result))); err != nil {
 		return nil, err
 	}
-	return result, nil
+	return append([]byte(nil), result...), nil
 }
 
-func (r *Reader) ByteString() ([]byte, error) {
-	return append([]byte(nil), r.iterator.ReadStringAsSlice()...), r.iterator.Error
+func (r *Reader) IsNull() (bool, error) {
+	return r.iterator.ReadNil(), r.iterator.Error
 }
 
 func (r *Reader) StartObject() error {
-	r.levels.push(r.isObject)
-	r.isObject = true
-	return nil
+	return r.push(true)
+}
+
+// restore switches back to the input stream after a buffered member was read
+func (r *Reader) restore() {
+	if r.current.parent != nil {
+		r.iterator.Pool().ReturnIterator(r.iterator)
+		r.iterator = r.current.parent
+		r.base = r.current.parentBase
+		r.current.parent = nil
+	}
+}
+
+// findField positions the reader on the value of the named member,
+// buffering every member that precedes it in the input
+func (r *Reader) findField(name string) (bool, error) {
+	r.restore()
+	if value, ok := r.current.buffered[name]; ok {
+		delete(r.current.buffered, name)
+		r.current.parent = r.iterator
+		r.current.parentBase = r.base
+		r.base = value.offset
+		r.iterator = r.iterator.Pool().BorrowIterator(value.data)
+		return true, nil
+	}
+	for !r.current.endReached {
+		field := r.iterator.ReadObject()
+		if r.iterator.Error != nil {
+			return false, r.iterator.Error
+		}
+		if len(field) == 0 {
+			r.current.endReached = true
+			break
+		}
+		if field == name {
+			return true, nil
+		}
+		if r.current.buffered == nil {
+			r.current.buffered = make(map[string]member)
+		}
+		offset := r.Offset()
+		// trailing whitespace keeps numbers at the end of the buffer from hitting io.EOF
+		r.current.buffered[field] = member{data: append(r.iterator.SkipAndReturnBytes(), ' '), offset: offset}
+		r.current.order = append(r.current.order, field)
+		if r.iterator.Error != nil {
+			return false, r.iterator.Error
+		}
+	}
+	return false, nil
 }
 func (r *Reader) Property(name string) error {
-	if !r.isObject {
+	if !r.current.isObject {
 		return ErrNotAnObject
 	}
-	field := r.iterator.ReadObject()
-	if r.iterator.Error != nil {
-		return r.iterator.Error
-	}
-	if len(field) == 0 {
-		return ErrUnexpectedObjectEnd
+	found, err := r.findField(name)
+	if err != nil {
+		return err
 	}
-	if field != name {
-		return ErrWrongField
+	if !found {
+		return inspect.ErrNoField
 	}
 	return nil
 }
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	if !r.current.isObject {
+		return false, ErrNotAnObject
+	}
+	return r.findField(name)
+}
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if !r.current.isObject {
+		return nil, ErrNotAnObject
+	}
+	r.restore()
+	var result []inspect.RawField
+	if len(r.current.buffered) > 0 {
+		if policy == inspect.FailOnUnknown {
+			return nil, ErrObjectTooBig
+		}
+		if policy == inspect.CollectUnknown {
+			for _, name := range r.current.order {
+				if value, ok := r.current.buffered[name]; ok {
+					// the buffered value of a repeated name is its last one
+					delete(r.current.buffered, name)
+					result = append(result, inspect.RawField{Format: formatName, Name: name,
+						Value: value.data[:len(value.data)-1]})
+				}
+			}
+		}
+		r.current.buffered = nil
+	}
+	r.current.order = r.current.order[:0]
+	for !r.current.endReached {
+		field := r.iterator.ReadObject()
+		if r.iterator.Error != nil {
+			return nil, r.iterator.Error
+		}
+		if len(field) == 0 {
+			r.current.endReached = true
+			break
+		}
+		switch policy {
+		case inspect.FailOnUnknown:
+			return nil, ErrObjectTooBig
+		case inspect.SkipUnknown:
+			r.iterator.Skip()
+		case inspect.CollectUnknown:
+			result = append(result, inspect.RawField{Format: formatName, Name: field,
+				Value: r.iterator.SkipAndReturnBytes()})
+		}
+		if r.iterator.Error != nil {
+			return nil, r.iterator.Error
+		}
+	}
+	return lastOccurrences(result), nil
+}
+
+// lastOccurrences drops the members whose name comes again later in the object,
+// like most JSON parsers do
+func lastOccurrences(fields []inspect.RawField) []inspect.RawField {
+	if len(fields) < 2 {
+		return fields
+	}
+	seen := make(map[string]bool, len(fields))
+	keep := make([]bool, len(fields))
+	for i := len(fields) - 1; i >= 0; i-- {
+		keep[i] = !seen[fields[i].Name]
+		seen[fields[i].Name] = true
+	}
+	result := fields[:0]
+	for i, field := range fields {
+		if keep[i] {
+			result = append(result, field)
+		}
+	}
+	return result
+}
 func (r *Reader) EndObject() error {
-	if !r.isObject {
+	if !r.current.isObject {
 		return ErrNotAnObject
 	}
-	r.isObject = r.levels.pop()
-	field := r.iterator.ReadObject()
-	if len(field) > 0 {
-		return ErrObjectTooBig
-	}
-	return nil
+	_, err := r.UnknownFields(inspect.FailOnUnknown)
+	r.pop()
+	return err
 }
 func (r *Reader) StartArray() (length int, err error) {
+	if err := r.expect(jsoniter.ArrayValue, ErrNotAnArray); err != nil {
+		return 0, err
+	}
 	arrayHasItems := r.iterator.ReadArray()
 	if r.iterator.Error != nil {
 		return 0, r.iterator.Error
@@ -96,38 +365,44 @@ func (r *Reader) StartArray() (length int, err error) {
 	if !arrayHasItems {
 		return 0, nil
 	}
-	r.endReachedStack.push(r.endReached)
-	r.levels.push(r.isObject)
-	r.isObject = false
-	r.endReached = false
-	return -1, nil
+	if err := r.push(false); err != nil {
+		return 0, err
+	}
+	return -1, r.next()
 }
 func (r *Reader) HaveNext() (bool, error) {
 	arrayHasItems := r.iterator.ReadArray()
 	if r.iterator.Error != nil {
 		return false, r.iterator.Error
 	}
-	r.endReached = arrayHasItems
-	return arrayHasItems, nil
+	r.current.endReached = !arrayHasItems
+	if arrayHasItems {
+		return true, r.next()
+	}
+	return false, nil
 }
 func (r *Reader) EndArray() error {
-	if !r.endReached {
-		r.endReached = r.iterator.ReadArray()
-		if !r.endReached {
-			r.endReached = r.endReachedStack.pop()
-			r.isObject = r.levels.pop()
+	if !r.current.endReached {
+		arrayHasItems := r.iterator.ReadArray()
+		if r.iterator.Error != nil {
+			r.pop()
+			return r.iterator.Error
+		}
+		if arrayHasItems {
+			r.pop()
 			return ErrArrayTooBig
 		}
 	}
-	r.endReached = r.endReachedStack.pop()
-	r.isObject = r.levels.pop()
+	r.pop()
 	return nil
 }
 func (r *Reader) StartMap() (length int, err error) {
-	r.levels.push(r.isObject)
-	r.endReachedStack.push(r.endReached)
-	r.isObject = true
-	r.endReached = false
+	if err := r.expect(jsoniter.ObjectValue, ErrNotAnObject); err != nil {
+		return 0, err
+	}
+	if err := r.push(true); err != nil {
+		return 0, err
+	}
 	return -1, nil
 }
 func (r *Reader) NextKey() (string, error) {
@@ -136,20 +411,27 @@ func (r *Reader) NextKey() (string, error) {
 		return "", r.iterator.Error
 	}
 	if len(key) == 0 {
-		r.endReached = true
+		r.current.endReached = true
+		return key, nil
 	}
-	return key, nil
+	return key, r.next()
 }
 func (r *Reader) EndMap() error {
-	if !r.endReached {
+	if !r.current.endReached {
 		key := r.iterator.ReadObject()
 		if len(key) > 0 {
-			r.isObject = r.levels.pop()
-			r.endReached = r.endReachedStack.pop()
+			r.pop()
 			return ErrMapTooBig
 		}
 	}
-	r.isObject = r.levels.pop()
-	r.endReached = r.endReachedStack.pop()
+	r.pop()
 	return nil
 }
+func (r *Reader) Skip() error {
+	r.iterator.Skip()
+	return r.iterator.Error
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+}
@@ -0,0 +1,86 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/binary"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/json"
+)
+
+type pair struct {
+	first  string
+	second string
+}
+
+func (p *pair) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("pair", "two names")
+	o.String("first", &p.first, true, "first name")
+	o.String("second", &p.second, true, "second name")
+	o.End()
+}
+
+func TestDuplicateUnknownFields(t *testing.T) {
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader]))
+	for _, input := range []string{
+		`{"x":1,"x":2,"name":"a"}`,
+		`{"x":1,"name":"a","x":2}`,
+		`{"name":"a","x":1,"x":2}`,
+	} {
+		reader.SetReader(bytes.NewBufferString(input))
+		var value inspecttest.Extensible
+		value.Inspect(reader)
+		if reader.LastError() != nil {
+			t.Fatal(input, reader.LastError())
+		}
+		if len(value.Unknown) != 1 || string(value.Unknown[0].Value) != "2" {
+			t.Fatal(input, "got", value.Unknown)
+		}
+	}
+}
+
+func TestForeignRawField(t *testing.T) {
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader]))
+	reader.SetReader(bytes.NewBufferString(`{"name":"a","x":1}`))
+	var value inspecttest.Extensible
+	value.Inspect(reader)
+	if reader.LastError() != nil || value.Unknown[0].Format != "json" {
+		t.Fatal("got", value, reader.LastError())
+	}
+	var buffer bytes.Buffer
+	writer := inspect.NewInspector(new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer]))
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	if !errors.Is(writer.LastError(), binary.ErrRawField) {
+		t.Fatal("got", writer.LastError())
+	}
+	buffer.Reset()
+	writer = inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer, *json.Writer]))
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil || buffer.String() != `{"name":"a","x":1}` {
+		t.Fatal("got", buffer.String(), writer.LastError())
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte(`{"second":5,"first":"a"}`))
+	f.Add([]byte(`{"name":"a","x":[1,{"y":null}],"x":"é"}`))
+	f.Add([]byte(`{"first":"a\"b","second":"c"}  {"first":1}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		impl := new(inspect.TextReadInspector[json.Reader, *json.Reader])
+		impl.SetLimits(inspect.Limits{MaxDepth: 16})
+		reader := inspect.NewInspector(impl)
+		reader.SetReader(bytes.NewReader(data))
+		var value pair
+		value.Inspect(reader)
+		impl.SetUnknownFieldPolicy(inspect.CollectUnknown)
+		reader.SetReader(bytes.NewReader(data))
+		var collected inspecttest.Extensible
+		collected.Inspect(reader)
+	})
+}
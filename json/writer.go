@@ -9,10 +9,46 @@ import (
 	"github.com/tvanomr/inspect"
 )
 
+// Indent configures pretty printed output
+type Indent struct {
+	// written once per nesting level at the start of every line,
+	// the output stays compact when it is empty
+	Indent string
+	// line break, "\n" when empty
+	Newline string
+	// writes ": " between names and values instead of ":"
+	SpaceAfterColon bool
+}
+
 type Writer struct {
 	stream     *jsoniter.Stream
 	started    bool
 	bufferSize int
+	indent     Indent
+	// number of objects, arrays and maps open
+	depth int
+	// a name was written, its value follows on the same line
+	afterKey  bool
+	canonical bool
+	// open objects of canonical output
+	objects stack[canonicalObject]
+}
+
+// SetIndent turns on pretty printing for the following outputs
+func (w *Writer) SetIndent(indent Indent) {
+	w.indent = indent
+}
+
+// SetCanonical turns on RFC 8785 output: members sorted by name, numbers formatted
+// like ECMAScript does and only the necessary escaping, indentation is ignored.
+// Objects are kept in memory until they end.
+func (w *Writer) SetCanonical(canonical bool) {
+	w.canonical = canonical
+}
+
+// SortKeys asks the map helpers for keys in order in canonical output
+func (w *Writer) SortKeys() bool {
+	return w.canonical
 }
 
 func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
@@ -20,18 +56,123 @@ func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
 		w.stream = jsoniter.NewStream(jsoniter.ConfigCompatibleWithStandardLibrary, writer, 10)
 	} else {
 		w.stream.Reset(writer)
-		w.bufferSize = bufferSize
 	}
+	w.bufferSize = bufferSize
+	w.started = false
+	w.depth = 0
+	w.afterKey = false
+	w.objects = w.objects[:0]
 }
 
+// addComma separates a value from the previous one and starts its line
 func (w *Writer) addComma() {
+	if w.afterKey {
+		w.afterKey = false
+		return
+	}
 	if w.started {
 		w.stream.WriteMore()
 	} else {
 		w.started = true
 	}
+	// top level values are not indented
+	if w.depth > 0 {
+		w.newline()
+	}
 }
 
+func (w *Writer) newline() {
+	if len(w.indent.Indent) == 0 || w.canonical {
+		return
+	}
+	if len(w.indent.Newline) == 0 {
+		w.stream.WriteRaw("\n")
+	} else {
+		w.stream.WriteRaw(w.indent.Newline)
+	}
+	for i := 0; i < w.depth; i++ {
+		w.stream.WriteRaw(w.indent.Indent)
+	}
+}
+
+// open is called after the opening bracket of an object, an array or a map
+func (w *Writer) open() {
+	w.depth++
+	w.started = false
+}
+
+// close is called before the closing bracket, an empty value stays on one line
+func (w *Writer) close() {
+	w.depth--
+	if w.started {
+		w.newline()
+	}
+	w.started = true
+}
+
+// startObject is called after the opening brace of an object or a map
+func (w *Writer) startObject() {
+	w.open()
+	if w.canonical {
+		w.objects.push(canonicalObject{start: w.stream.Buffered()})
+	}
+}
+
+// endObject is called before the closing brace of an object or a map
+func (w *Writer) endObject() {
+	w.close()
+	if w.canonical && len(w.objects) > 0 {
+		w.stream.SetBuffer(sortMembers(w.stream.Buffer(), w.objects.pop()))
+	}
+}
+
+func (w *Writer) writeString(value string) {
+	if w.canonical {
+		w.stream.SetBuffer(appendCanonicalString(w.stream.Buffer(), value))
+	} else {
+		w.stream.WriteString(value)
+	}
+}
+
+// flushIfFull writes out the buffer once it is over bufferSize,
+// canonical objects have to stay in it until they are sorted
+func (w *Writer) flushIfFull() error {
+	if w.stream.Buffered() > w.bufferSize && len(w.objects) == 0 {
+		return w.stream.Flush()
+	}
+	return nil
+}
+
+func (w *Writer) key(name string) {
+	w.addComma()
+	if w.canonical && len(w.objects) > 0 {
+		object := &w.objects[len(w.objects)-1]
+		object.members = append(object.members, canonicalMember{name: name, start: w.stream.Buffered()})
+	}
+	w.writeString(name)
+	if w.indent.SpaceAfterColon && !w.canonical {
+		w.stream.WriteRaw(": ")
+	} else {
+		w.stream.WriteRaw(":")
+	}
+	w.afterKey = true
+}
+
+func (w *Writer) Bool(value bool) error {
+	w.addComma()
+	w.stream.WriteBool(value)
+	return nil
+}
+func (w *Writer) Int8(value int8) error {
+	w.addComma()
+	w.stream.WriteInt8(value)
+	return nil
+}
+func (w *Writer) Int16(value int16) error {
+	w.addComma()
+	w.stream.WriteInt16(value)
+	return nil
+}
 func (w *Writer) Int32(value int32) error {
 	w.addComma()
 	w.stream.WriteInt32(value)
@@ -39,85 +180,190 @@ func (w *Writer) Int32(value int32) error {
 }
 func (w *Writer) Int64(value int64) error {
 	w.addComma()
+	if w.canonical && (value > maxSafeInteger || value < -maxSafeInteger) {
+		return ErrUnsafeInteger
+	}
 	w.stream.WriteInt64(value)
 	return nil
 }
+func (w *Writer) Uint8(value uint8) error {
+	w.addComma()
+	w.stream.WriteUint8(value)
+	return nil
+}
+func (w *Writer) Uint16(value uint16) error {
+	w.addComma()
+	w.stream.WriteUint16(value)
+	return nil
+}
+func (w *Writer) Uint32(value uint32) error {
+	w.addComma()
+	w.stream.WriteUint32(value)
+	return nil
+}
+func (w *Writer) Uint64(value uint64) error {
+	w.addComma()
+	if w.canonical && value > maxSafeInteger {
+		return ErrUnsafeInteger
+	}
+	w.stream.WriteUint64(value)
+	return nil
+}
 func (w *Writer) Float32(value float32, format byte, precision int) error {
 	w.addComma()
+	if w.canonical {
+		return w.canonicalFloat(float64(value))
+	}
 	w.stream.SetBuffer(strconv.AppendFloat(w.stream.Buffer(), float64(value), format, precision, 32))
 	return nil
 }
 func (w *Writer) Float64(value float64, format byte, precision int) error {
 	w.addComma()
+	if w.canonical {
+		return w.canonicalFloat(value)
+	}
 	w.stream.SetBuffer(strconv.AppendFloat(w.stream.Buffer(), value, format, precision, 64))
 	return nil
 }
+func (w *Writer) canonicalFloat(value float64) error {
+	buffer, err := appendCanonicalFloat(w.stream.Buffer(), value)
+	w.stream.SetBuffer(buffer)
+	return err
+}
 func (w *Writer) String(value string) error {
 	w.addComma()
-	w.stream.WriteString(value)
+	w.writeString(value)
 	return nil
 }
 func (w *Writer) Bytes(value []byte) error {
 	w.addComma()
-	w.stream.WriteString(base64.RawURLEncoding.EncodeToString(value))
+	w.writeString(base64.RawURLEncoding.EncodeToString(value))
 	return nil
 }
 func (w *Writer) ByteString(value []byte) error {
 	w.addComma()
-	w.stream.WriteString(string(value))
+	w.writeString(string(value))
+	return nil
+}
+func (w *Writer) Null() error {
+	w.addComma()
+	w.stream.WriteNil()
+	return nil
+}
+
+func (w *Writer) Skip() error {
+	return w.Null()
+}
+func (w *Writer) NotNull() error {
 	return nil
 }
 func (w *Writer) StartObject() error {
 	w.addComma()
 	w.stream.WriteObjectStart()
-	w.started = false
+	w.startObject()
 	return nil
 }
 func (w *Writer) Property(name string) error {
+	w.key(name)
+	return nil
+}
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	if !present {
+		return nil
+	}
+	return w.Property(name)
+}
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	if field.Format != formatName {
+		return ErrRawField
+	}
+	w.Property(field.Name)
+	if w.canonical {
+		iterator := jsoniter.ConfigCompatibleWithStandardLibrary.BorrowIterator(field.Value)
+		defer jsoniter.ConfigCompatibleWithStandardLibrary.ReturnIterator(iterator)
+		return w.rawValue(iterator)
+	}
 	w.addComma()
-	w.stream.WriteObjectField(name)
+	// Write would flush, canonical objects have to stay in the buffer until they are sorted
+	w.stream.SetBuffer(append(w.stream.Buffer(), field.Value...))
 	return nil
 }
+
+// rawValue writes a collected value again, so canonical output sorts and formats it as well
+func (w *Writer) rawValue(iterator *jsoniter.Iterator) error {
+	var err error
+	switch iterator.WhatIsNext() {
+	case jsoniter.StringValue:
+		err = w.String(iterator.ReadString())
+	case jsoniter.NumberValue:
+		var value float64
+		value, err = iterator.ReadNumber().Float64()
+		if err != nil {
+			return ErrInvalidRawValue
+		}
+		err = w.Float64(value, 'g', -1)
+	case jsoniter.BoolValue:
+		err = w.Bool(iterator.ReadBool())
+	case jsoniter.NilValue:
+		iterator.ReadNil()
+		err = w.Null()
+	case jsoniter.ArrayValue:
+		w.StartArray(0)
+		for err == nil && iterator.ReadArray() {
+			err = w.rawValue(iterator)
+		}
+		if err == nil {
+			err = w.EndArray()
+		}
+	case jsoniter.ObjectValue:
+		w.StartObject()
+		iterator.ReadObjectCB(func(iterator *jsoniter.Iterator, name string) bool {
+			w.key(name)
+			err = w.rawValue(iterator)
+			return err == nil
+		})
+		if err == nil {
+			err = w.EndObject()
+		}
+	default:
+		return ErrInvalidRawValue
+	}
+	// a number at the end of the value runs into the end of the input
+	if err == nil && iterator.Error != nil && iterator.Error != io.EOF {
+		return ErrInvalidRawValue
+	}
+	return err
+}
 func (w *Writer) EndObject() error {
+	w.endObject()
 	w.stream.WriteObjectEnd()
-	w.started = true
-	if w.stream.Buffered() > w.bufferSize {
-		return w.stream.Flush()
-	}
-	return nil
+	return w.flushIfFull()
 }
 func (w *Writer) StartArray(length int) error {
 	w.addComma()
 	w.stream.WriteArrayStart()
-	w.started = false
+	w.open()
 	return nil
 }
 func (w *Writer) EndArray() error {
+	w.close()
 	w.stream.WriteArrayEnd()
-	w.started = true
-	if w.stream.Buffered() > w.bufferSize {
-		return w.stream.Flush()
-	}
-	return nil
+	return w.flushIfFull()
 }
 func (w *Writer) StartMap(length int) error {
 	w.addComma()
 	w.stream.WriteObjectStart()
-	w.started = false
+	w.startObject()
 	return nil
 }
 func (w *Writer) NextKey(key string) error {
-	w.addComma()
-	w.stream.WriteObjectField(key)
+	w.key(key)
 	return nil
 }
 func (w *Writer) EndMap() error {
+	w.endObject()
 	w.stream.WriteObjectEnd()
-	w.started = true
-	if w.stream.Buffered() > w.bufferSize {
-		return w.stream.Flush()
-	}
-	return nil
+	return w.flushIfFull()
 }
 
 func (w *Writer) Flush() error {
@@ -126,4 +372,5 @@ func (w *Writer) Flush() error {
 
 func init() {
 	var _ inspect.Writer = (*Writer)(nil)
+	var _ inspect.Canonical = (*Writer)(nil)
 }
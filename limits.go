@@ -0,0 +1,98 @@
+package inspect
+
+import (
+	"fmt"
+	"io"
+)
+
+// Limits bound what a reader accepts from the input, so data from untrusted
+// peers can't make it allocate without end. A zero field means no limit.
+type Limits struct {
+	// length of a string or a byte slice
+	MaxStringLength int64
+	// number of items in an array or a map
+	MaxArrayLength int64
+	// number of objects, arrays and maps open at the same time
+	MaxDepth int
+	// bytes consumed from the input since SetReader
+	MaxTotalBytes int64
+}
+
+// LimitError is returned by readers when the input goes over one of the Limits.
+// It matches ErrLimitExceeded with errors.Is.
+type LimitError struct {
+	Limit string
+	Value int64
+	Max   int64
+}
+
+func (l *LimitError) Error() string {
+	return fmt.Sprintf("%s %d exceeds the limit of %d", l.Limit, l.Value, l.Max)
+}
+
+func (l *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+func checkLimit(limit string, value int64, max int64) error {
+	if max > 0 && value > max {
+		return &LimitError{Limit: limit, Value: value, Max: max}
+	}
+	return nil
+}
+
+// CheckString is called by readers before a string or byte slice of length is allocated
+func (l *Limits) CheckString(length int64) error {
+	return checkLimit("string length", length, l.MaxStringLength)
+}
+
+// CheckArray is called by readers with the number of items of an array or a map
+// as soon as it is known
+func (l *Limits) CheckArray(length int64) error {
+	return checkLimit("array length", length, l.MaxArrayLength)
+}
+
+// CheckDepth is called by readers with the nesting depth of a new object, array or map
+func (l *Limits) CheckDepth(depth int) error {
+	return checkLimit("nesting depth", int64(depth), int64(l.MaxDepth))
+}
+
+// CheckTotal is called by readers with the number of bytes consumed from the input
+func (l *Limits) CheckTotal(total int64) error {
+	return checkLimit("input size", total, l.MaxTotalBytes)
+}
+
+// readChunk is how much ReadFull allocates ahead of the bytes actually received
+const readChunk = 64 << 10
+
+// ReadFull reads length bytes from reader for a length prefix taken from the input.
+// The buffer grows with the bytes received, so a hostile prefix fails with
+// io.ErrUnexpectedEOF at the end of the input instead of allocating length up front.
+func ReadFull(reader io.Reader, length int64) ([]byte, error) {
+	if length < 0 || int64(int(length)) != length {
+		return nil, io.ErrUnexpectedEOF
+	}
+	size := length
+	if size > readChunk {
+		size = readChunk
+	}
+	result := make([]byte, 0, size)
+	for int64(len(result)) < length {
+		if len(result) == cap(result) {
+			result = append(result, 0)[:len(result)]
+		}
+		end := cap(result)
+		if int64(end) > length {
+			end = int(length)
+		}
+		read, err := io.ReadFull(reader, result[len(result):end])
+		result = result[:len(result)+read]
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -1,5 +1,11 @@
 package inspect
 
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
 func clearMap[K comparable, V any](value map[K]V) {
 	for key := range value {
 		delete(value, key)
@@ -10,31 +16,100 @@ func makeOrClearMap[K comparable, V any](value *map[K]V, length int) {
 	if *value != nil {
 		clearMap(*value)
 	} else {
-		*value = make(map[K]V, length)
+		*value = make(map[K]V, preallocated(length))
+	}
+}
+
+// Ordered is implemented by map keys that define the order of sorted output,
+// other keys are ordered by their numbers, strings, or fields and items in turn
+type Ordered[K any] interface {
+	Less(other K) bool
+}
+
+// rangeMap calls f for every entry of value, in key order when the inspector asks for it
+func rangeMap[K comparable, V any](value map[K]V, inspector *Inspector, f func(key K, item V)) {
+	if !inspector.SortKeys() {
+		for key, item := range value {
+			f(key, item)
+		}
+		return
+	}
+	keys := make([]K, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ordered, ok := any(keys[i]).(Ordered[K]); ok {
+			return ordered.Less(keys[j])
+		}
+		return lessValue(reflect.ValueOf(&keys[i]).Elem(), reflect.ValueOf(&keys[j]).Elem())
+	})
+	for _, key := range keys {
+		f(key, value[key])
+	}
+}
+
+// lessValue orders map keys by their numbers, strings, or fields and items in turn,
+// anything else by its printed form
+func lessValue(a reflect.Value, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if lessValue(a.Field(i), b.Field(i)) {
+				return true
+			}
+			if lessValue(b.Field(i), a.Field(i)) {
+				return false
+			}
+		}
+		return false
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if lessValue(a.Index(i), b.Index(i)) {
+				return true
+			}
+			if lessValue(b.Index(i), a.Index(i)) {
+				return false
+			}
+		}
+		return false
 	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
 }
 
-func StringMap[T Inspectable](value *map[string]T, inspector *Inspector,
+func StringMap[T any, PT InspectablePtr[T]](value *map[string]T, inspector *Inspector,
 	name string, elementName string, description string) {
 
 	if !inspector.IsReading() {
 		inspector.WriteMap(name, elementName, len(*value), description)
-		for key, item := range *value {
+		rangeMap(*value, inspector, func(key string, item T) {
 			inspector.WriteNextKey(key)
-			item.Inspect(inspector)
-		}
+			PT(&item).Inspect(inspector)
+		})
+		inspector.EndMap()
 		return
 	}
 
 	length := inspector.ReadMap()
 	if length > 0 {
 		makeOrClearMap(value, length)
-		for i := 0; i < length; i++ {
+		for i := 0; i < length && !failed(inspector); i++ {
 			var item T
 			key := inspector.ReadNextKey()
-			item.Inspect(inspector)
+			PT(&item).Inspect(inspector)
 			(*value)[key] = item
 		}
+		inspector.EndMap()
 		return
 	}
 	if length == 0 {
@@ -47,40 +122,45 @@ func StringMap[T Inspectable](value *map[string]T, inspector *Inspector,
 	key := inspector.ReadNextKey()
 	for len(key) > 0 {
 		var item T
-		item.Inspect(inspector)
+		PT(&item).Inspect(inspector)
 		(*value)[key] = item
 		key = inspector.ReadNextKey()
 	}
 	inspector.EndMap()
 }
 
+func inspectPair[K any, PK InspectablePtr[K], T any, PT InspectablePtr[T]](inspector *Inspector,
+	name string, key *K, item *T) {
+
+	// numbered like the entries of protobuf maps
+	o := inspector.StartObject(name, "key value pair")
+	PropertyObject[K, PK](o.Number(1), "k", key, true, "key")
+	PropertyObject[T, PT](o.Number(2), "v", item, true, "value")
+	o.End()
+}
+
 func Map[K comparable, PK InspectablePtr[K], T any, PT InspectablePtr[T]](value *map[K]T, inspector *Inspector,
 	name string, keyName string, elementName string, description string) {
 
 	itemName := name + ".item"
 	if !inspector.IsReading() {
 		inspector.WriteArray(name, itemName, len(*value), description)
-		for key, item := range *value {
-			o := inspector.StartObject(itemName, "key value pair")
-			PK(&key).Inspect(o.Property("k", true, "key"))
-			PT(&item).Inspect(o.Property("v", true, "value"))
-			o.End()
-		}
+		rangeMap(*value, inspector, func(key K, item T) {
+			inspectPair[K, PK, T, PT](inspector, itemName, &key, &item)
+		})
 		inspector.EndArray()
 		return
 	}
 	length := inspector.ReadArray()
 	if length > 0 {
 		makeOrClearMap(value, length)
-		for i := 0; i < length; i++ {
+		for i := 0; i < length && !failed(inspector); i++ {
 			var key K
 			var item T
-			o := inspector.StartObject(itemName, "key value pair")
-			PK(&key).Inspect(o.Property("k", true, "key"))
-			PT(&item).Inspect(o.Property("v", true, "value"))
-			o.End()
+			inspectPair[K, PK, T, PT](inspector, itemName, &key, &item)
 			(*value)[key] = item
 		}
+		inspector.EndArray()
 		return
 	}
 	if length == 0 {
@@ -90,22 +170,13 @@ func Map[K comparable, PK InspectablePtr[K], T any, PT InspectablePtr[T]](value
 		return
 	}
 	makeOrClearMap(value, 1)
-	var key K
-	var item T
-	o := inspector.StartObject(itemName, "key value pair")
-	PK(&key).Inspect(o.Property("k", true, "key"))
-	PT(&item).Inspect(o.Property("v", true, "value"))
-	o.End()
-	(*value)[key] = item
-	for inspector.HaveNext() {
+	for hasItem := true; hasItem; hasItem = inspector.HaveNext() {
 		var key K
 		var item T
-		o := inspector.StartObject(itemName, "key value pair")
-		PK(&key).Inspect(o.Property("k", true, "key"))
-		PT(&item).Inspect(o.Property("v", true, "value"))
-		o.End()
+		inspectPair[K, PK, T, PT](inspector, itemName, &key, &item)
 		(*value)[key] = item
 	}
+	inspector.EndArray()
 }
 
 func MapPtr[K comparable, PK InspectablePtr[K], T any, PT InspectablePtr[T]](value *map[K]PT, inspector *Inspector,
@@ -114,27 +185,22 @@ func MapPtr[K comparable, PK InspectablePtr[K], T any, PT InspectablePtr[T]](val
 	itemName := name + ".item"
 	if !inspector.IsReading() {
 		inspector.WriteArray(name, itemName, len(*value), description)
-		for key, item := range *value {
-			o := inspector.StartObject(itemName, "key value pair")
-			PK(&key).Inspect(o.Property("k", true, "key"))
-			item.Inspect(o.Property("v", true, "value"))
-			o.End()
-		}
+		rangeMap(*value, inspector, func(key K, item PT) {
+			inspectPair[K, PK, T, PT](inspector, itemName, &key, item)
+		})
 		inspector.EndArray()
 		return
 	}
 	length := inspector.ReadArray()
 	if length > 0 {
 		makeOrClearMap(value, length)
-		for i := 0; i < length; i++ {
+		for i := 0; i < length && !failed(inspector); i++ {
 			var key K
 			item := PT(new(T))
-			o := inspector.StartObject(itemName, "key value pair")
-			PK(&key).Inspect(o.Property("k", true, "key"))
-			item.Inspect(o.Property("v", true, "value"))
-			o.End()
+			inspectPair[K, PK, T, PT](inspector, itemName, &key, item)
 			(*value)[key] = item
 		}
+		inspector.EndArray()
 		return
 	}
 	if length == 0 {
@@ -144,20 +210,11 @@ func MapPtr[K comparable, PK InspectablePtr[K], T any, PT InspectablePtr[T]](val
 		return
 	}
 	makeOrClearMap(value, 1)
-	var key K
-	item := PT(new(T))
-	o := inspector.StartObject(itemName, "key value pair")
-	PK(&key).Inspect(o.Property("k", true, "key"))
-	item.Inspect(o.Property("v", true, "value"))
-	o.End()
-	(*value)[key] = item
-	for inspector.HaveNext() {
+	for hasItem := true; hasItem; hasItem = inspector.HaveNext() {
 		var key K
 		item := PT(new(T))
-		o := inspector.StartObject(itemName, "key value pair")
-		PK(&key).Inspect(o.Property("k", true, "key"))
-		item.Inspect(o.Property("v", true, "value"))
-		o.End()
+		inspectPair[K, PK, T, PT](inspector, itemName, &key, item)
 		(*value)[key] = item
 	}
+	inspector.EndArray()
 }
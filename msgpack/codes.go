@@ -0,0 +1,81 @@
+package msgpack
+
+// first bytes of MessagePack values
+const (
+	codePositiveFixintMax byte = 0x7f
+	codeFixmap            byte = 0x80
+	codeFixmapMax         byte = 0x8f
+	codeFixarray          byte = 0x90
+	codeFixarrayMax       byte = 0x9f
+	codeFixstr            byte = 0xa0
+	codeFixstrMax         byte = 0xbf
+	codeNil               byte = 0xc0
+	codeFalse             byte = 0xc2
+	codeTrue              byte = 0xc3
+	codeBin8              byte = 0xc4
+	codeBin16             byte = 0xc5
+	codeBin32             byte = 0xc6
+	codeExt8              byte = 0xc7
+	codeExt16             byte = 0xc8
+	codeExt32             byte = 0xc9
+	codeFloat32           byte = 0xca
+	codeFloat64           byte = 0xcb
+	codeUint8             byte = 0xcc
+	codeUint16            byte = 0xcd
+	codeUint32            byte = 0xce
+	codeUint64            byte = 0xcf
+	codeInt8              byte = 0xd0
+	codeInt16             byte = 0xd1
+	codeInt32             byte = 0xd2
+	codeInt64             byte = 0xd3
+	codeFixext1           byte = 0xd4
+	codeFixext2           byte = 0xd5
+	codeFixext4           byte = 0xd6
+	codeFixext8           byte = 0xd7
+	codeFixext16          byte = 0xd8
+	codeStr8              byte = 0xd9
+	codeStr16             byte = 0xda
+	codeStr32             byte = 0xdb
+	codeArray16           byte = 0xdc
+	codeArray32           byte = 0xdd
+	codeMap16             byte = 0xde
+	codeMap32             byte = 0xdf
+	codeNegativeFixintMin byte = 0xe0
+)
+
+func isFixmap(code byte) bool {
+	return code >= codeFixmap && code <= codeFixmapMax
+}
+
+func isFixarray(code byte) bool {
+	return code >= codeFixarray && code <= codeFixarrayMax
+}
+
+func isFixstr(code byte) bool {
+	return code >= codeFixstr && code <= codeFixstrMax
+}
+
+func isString(code byte) bool {
+	return isFixstr(code) || code == codeStr8 || code == codeStr16 || code == codeStr32
+}
+
+func isBin(code byte) bool {
+	return code == codeBin8 || code == codeBin16 || code == codeBin32
+}
+
+func isInt(code byte) bool {
+	return code <= codePositiveFixintMax || code >= codeNegativeFixintMin ||
+		(code >= codeUint8 && code <= codeInt64)
+}
+
+func isFloat(code byte) bool {
+	return code == codeFloat32 || code == codeFloat64
+}
+
+func isMap(code byte) bool {
+	return isFixmap(code) || code == codeMap16 || code == codeMap32
+}
+
+func isArray(code byte) bool {
+	return isFixarray(code) || code == codeArray16 || code == codeArray32
+}
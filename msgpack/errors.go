@@ -0,0 +1,50 @@
+package msgpack
+
+import "github.com/tvanomr/inspect"
+
+type msgpackError int
+
+const (
+	ErrShortWrite msgpackError = iota
+	ErrNotAnObject
+	ErrNotABool
+	ErrNotANumber
+	ErrNotAString
+	ErrNotBytes
+	ErrNotAnArray
+	ErrNotAMap
+	ErrObjectTooBig
+	ErrInvalidCode
+	ErrTooLong
+	ErrNoObject
+	ErrRawField
+)
+
+var errorMessages = map[msgpackError]string{
+	ErrShortWrite:   "short write",
+	ErrNotAnObject:  "value is not an object",
+	ErrNotABool:     "value is not a boolean",
+	ErrNotANumber:   "value is not a number",
+	ErrNotAString:   "value is not a string",
+	ErrNotBytes:     "value is neither bin nor a string",
+	ErrNotAnArray:   "value is not an array",
+	ErrNotAMap:      "value is not a map",
+	ErrObjectTooBig: "object contains more fields than requested",
+	ErrInvalidCode:  "invalid MessagePack type code",
+	ErrTooLong:      "value is too long for MessagePack",
+	ErrNoObject:     "Property() call outside of object",
+	ErrRawField:     "fields collected from another format can't be written"}
+
+func (m msgpackError) Error() string {
+	return errorMessages[m]
+}
+
+func (m msgpackError) Is(target error) bool {
+	switch m {
+	case ErrNotABool, ErrNotANumber, ErrNotAString, ErrNotBytes, ErrNotAnArray, ErrNotAMap:
+		return target == inspect.ErrTypeMismatch
+	case ErrObjectTooBig:
+		return target == inspect.ErrUnknownField
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package msgpack
+
+import "github.com/tvanomr/inspect"
+
+// Format is MessagePack for inspect.Marshal, inspect.Unmarshal, inspect.Encode and inspect.Decode
+var Format inspect.Format = inspect.BinaryFormat[Reader, *Reader, Writer, *Writer]{}
+
+// formatName tags the fields collected by Reader, Writer accepts only those
+const formatName = "msgpack"
+
+func init() {
+	inspect.RegisterFormat(formatName, Format, "application/msgpack", "application/x-msgpack")
+}
@@ -0,0 +1,59 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/msgpack"
+)
+
+type byteValue uint8
+
+func (b *byteValue) Inspect(inspector *inspect.Inspector) {
+	inspector.Uint8((*uint8)(b))
+}
+
+func TestMembers(t *testing.T) {
+	// {"count":300,"name":"a"} with members out of order and one unknown
+	input := []byte{0x83, 0xa5, 'c', 'o', 'u', 'n', 't', 0xcd, 0x01, 0x2c,
+		0xa5, 'e', 'x', 't', 'r', 'a', 0x92, 0xc0, 0xcb, 0, 0, 0, 0, 0, 0, 0, 0,
+		0xa4, 'n', 'a', 'm', 'e', 0xa1, 'a'}
+	impl := new(inspect.BinaryReadInspector[msgpack.Reader, *msgpack.Reader])
+	impl.SetUnknownFieldPolicy(inspect.CollectUnknown)
+	reader := inspect.NewInspector(impl)
+	reader.SetReader(bytes.NewBuffer(input))
+	var value inspecttest.Extensible
+	value.Inspect(reader)
+	if reader.LastError() != nil || value.Name != "a" || len(value.Unknown) != 2 ||
+		!bytes.Equal(value.Unknown[1].Value, input[16:27]) {
+		t.Fatal("got", value, reader.LastError())
+	}
+
+	data, err := inspect.Marshal(&value, msgpack.Format)
+	expected := append([]byte{0x83, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'a'}, input[1:27]...)
+	if err != nil || !bytes.Equal(data, expected) {
+		t.Fatal("got", data, err)
+	}
+
+	reader.SetReader(bytes.NewBuffer([]byte{0x82, 0xa5, 'c', 'o', 'u', 'n', 't', 0xd0, 0xff, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'a'}))
+	count := inspecttest.Settings{Mandatory: true}
+	count.Inspect(reader)
+	if reader.LastError() != nil || count.Count != -1 {
+		t.Fatal("got", count, reader.LastError())
+	}
+	var small byteValue
+	if err := inspect.Unmarshal([]byte{0xcd, 0x01, 0x2c}, &small, msgpack.Format); !errors.Is(err, inspect.ErrOverflow) {
+		t.Fatal("got", err, "expected", inspect.ErrOverflow)
+	}
+}
+
+func TestNested(t *testing.T) {
+	inspecttest.Nested(t, msgpack.Format.NewWriter(), msgpack.Format.NewReader())
+}
+
+func TestSkip(t *testing.T) {
+	inspecttest.Skip(t, msgpack.Format.NewWriter(), msgpack.Format.NewReader())
+}
@@ -0,0 +1,533 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/tvanomr/inspect"
+)
+
+// member is an object member read ahead of time
+type member struct {
+	data []byte
+	// offset of data in the input
+	offset int64
+}
+
+type level struct {
+	isObject bool
+	// members not read from the source yet
+	remaining int64
+	// members skipped while looking for a property that came later in the input
+	buffered map[string]member
+	// names of the buffered members in input order
+	order []string
+	// source to go back to once a buffered member has been read
+	parent *source
+}
+
+type Reader struct {
+	input   source
+	current *source
+	limits  inspect.Limits
+	level   level
+	levels  stack[level]
+}
+
+func (r *Reader) SetReader(reader io.Reader) {
+	r.input.reset(reader)
+	r.input.limits = &r.limits
+	r.current = &r.input
+	r.level = level{}
+	r.levels = r.levels[:0]
+}
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+func (r *Reader) Offset() int64 {
+	return r.current.offset()
+}
+
+// readCode consumes the type code of the next value when it passes check,
+// otherwise the value is left in the input and err is returned
+func (r *Reader) readCode(check func(code byte) bool, err error) (byte, error) {
+	code, peekErr := r.current.peek()
+	if peekErr != nil {
+		return 0, peekErr
+	}
+	if !check(code) {
+		return 0, err
+	}
+	return r.current.ReadByte()
+}
+
+// readSize reads a big endian unsigned integer of size bytes
+func (r *Reader) readSize(size int64) (uint64, error) {
+	data, err := r.current.read(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(data[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(data)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(data)), nil
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
+
+func (r *Reader) Bool() (bool, error) {
+	code, err := r.readCode(func(code byte) bool {
+		return code == codeFalse || code == codeTrue
+	}, ErrNotABool)
+	return code == codeTrue, err
+}
+func (r *Reader) Int8() (int8, error) {
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	return readNarrowInt[int16](r)
+}
+func (r *Reader) Int32() (int32, error) {
+	return readNarrowInt[int32](r)
+}
+
+// readInt reads an integer of any size, negative tells which of the results holds it
+func (r *Reader) readInt() (value int64, unsigned uint64, negative bool, err error) {
+	code, err := r.readCode(isInt, ErrNotANumber)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	switch {
+	case code <= codePositiveFixintMax:
+		return 0, uint64(code), false, nil
+	case code >= codeNegativeFixintMin:
+		return int64(int8(code)), 0, true, nil
+	case code >= codeUint8 && code <= codeUint64:
+		unsigned, err = r.readSize(1 << (code - codeUint8))
+		return 0, unsigned, false, err
+	}
+	size := int64(1 << (code - codeInt8))
+	unsigned, err = r.readSize(size)
+	switch size {
+	case 1:
+		value = int64(int8(unsigned))
+	case 2:
+		value = int64(int16(unsigned))
+	case 4:
+		value = int64(int32(unsigned))
+	default:
+		value = int64(unsigned)
+	}
+	if value >= 0 {
+		return 0, uint64(value), false, err
+	}
+	return value, 0, true, err
+}
+func (r *Reader) Int64() (int64, error) {
+	value, unsigned, negative, err := r.readInt()
+	if err != nil || negative {
+		return value, err
+	}
+	if unsigned > math.MaxInt64 {
+		return 0, &inspect.OverflowError{Type: "int64", Value: strconv.FormatUint(unsigned, 10)}
+	}
+	return int64(unsigned), nil
+}
+func (r *Reader) Uint8() (uint8, error) {
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	value, unsigned, negative, err := r.readInt()
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		return 0, &inspect.OverflowError{Type: "uint64", Value: strconv.FormatInt(value, 10)}
+	}
+	return unsigned, nil
+}
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := r.Int64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](result)
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := r.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
+
+// readFloat accepts integers as well, other implementations write whole numbers that way
+func (r *Reader) readFloat() (float64, error) {
+	code, err := r.current.peek()
+	if err != nil {
+		return 0, err
+	}
+	if isInt(code) {
+		value, unsigned, negative, err := r.readInt()
+		if negative {
+			return float64(value), err
+		}
+		return float64(unsigned), err
+	}
+	code, err = r.readCode(isFloat, ErrNotANumber)
+	if err != nil {
+		return 0, err
+	}
+	if code == codeFloat32 {
+		bits, err := r.readSize(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	}
+	bits, err := r.readSize(8)
+	return math.Float64frombits(bits), err
+}
+func (r *Reader) Float32() (float32, error) {
+	result, err := r.readFloat()
+	return float32(result), err
+}
+func (r *Reader) Float64() (float64, error) {
+	return r.readFloat()
+}
+
+// stringLength reads the header of a str or bin value
+func (r *Reader) stringLength(code byte) (int64, error) {
+	var length uint64
+	var err error
+	switch code {
+	case codeStr8, codeBin8:
+		length, err = r.readSize(1)
+	case codeStr16, codeBin16:
+		length, err = r.readSize(2)
+	case codeStr32, codeBin32:
+		length, err = r.readSize(4)
+	default:
+		length = uint64(code - codeFixstr)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(length), r.limits.CheckString(int64(length))
+}
+
+// readString reads a str value, or a bin one when allowBin is set,
+// the result is only valid until the next read
+func (r *Reader) readString(allowBin bool, mismatch error) ([]byte, error) {
+	code, err := r.readCode(func(code byte) bool {
+		return isString(code) || (allowBin && isBin(code))
+	}, mismatch)
+	if err != nil {
+		return nil, err
+	}
+	length, err := r.stringLength(code)
+	if err != nil {
+		return nil, err
+	}
+	return r.current.read(length)
+}
+func (r *Reader) String() (string, error) {
+	result, err := r.readString(false, ErrNotAString)
+	return string(result), err
+}
+func (r *Reader) Bytes() ([]byte, error) {
+	result, err := r.readString(true, ErrNotBytes)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), result...), nil
+}
+func (r *Reader) ByteString() ([]byte, error) {
+	return r.Bytes()
+}
+func (r *Reader) IsNull() (bool, error) {
+	code, err := r.current.peek()
+	if err != nil || code != codeNil {
+		return false, err
+	}
+	_, err = r.current.ReadByte()
+	return true, err
+}
+
+// containerLength reads the header of an array or a map
+func (r *Reader) containerLength(code byte) (int64, error) {
+	var length uint64
+	var err error
+	switch code {
+	case codeArray16, codeMap16:
+		length, err = r.readSize(2)
+	case codeArray32, codeMap32:
+		length, err = r.readSize(4)
+	default:
+		length = uint64(code & 0x0f)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(length), r.limits.CheckArray(int64(length))
+}
+func (r *Reader) push(isObject bool, remaining int64) error {
+	if err := r.limits.CheckDepth(len(r.levels) + 1); err != nil {
+		return err
+	}
+	r.levels.push(r.level)
+	r.level = level{isObject: isObject, remaining: remaining}
+	return nil
+}
+func (r *Reader) pop() {
+	r.restore()
+	if len(r.levels) > 0 {
+		r.level = r.levels.pop()
+	}
+}
+
+// restore switches back to the parent source after a buffered member was read
+func (r *Reader) restore() {
+	if r.level.parent != nil {
+		r.current = r.level.parent
+		r.level.parent = nil
+	}
+}
+func (r *Reader) StartObject() error {
+	code, err := r.readCode(isMap, ErrNotAnObject)
+	if err != nil {
+		return err
+	}
+	length, err := r.containerLength(code)
+	if err != nil {
+		return err
+	}
+	return r.push(true, length)
+}
+
+// findField positions the reader on the value of the named member,
+// buffering every member that precedes it in the input
+func (r *Reader) findField(name string) (bool, error) {
+	r.restore()
+	if value, ok := r.level.buffered[name]; ok {
+		delete(r.level.buffered, name)
+		r.level.parent = r.current
+		r.current = &source{data: value.data, base: value.offset, limits: &r.limits}
+		return true, nil
+	}
+	for r.level.remaining > 0 {
+		field, err := r.String()
+		if err != nil {
+			return false, err
+		}
+		r.level.remaining--
+		if field == name {
+			return true, nil
+		}
+		if r.level.buffered == nil {
+			r.level.buffered = make(map[string]member)
+		}
+		offset := r.current.offset()
+		data, err := r.recordValue()
+		if err != nil {
+			return false, err
+		}
+		r.level.buffered[field] = member{data: data, offset: offset}
+		r.level.order = append(r.level.order, field)
+	}
+	return false, nil
+}
+func (r *Reader) Property(name string) error {
+	if !r.level.isObject {
+		return ErrNoObject
+	}
+	found, err := r.findField(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return inspect.ErrNoField
+	}
+	return nil
+}
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	if !r.level.isObject {
+		return false, ErrNoObject
+	}
+	return r.findField(name)
+}
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if !r.level.isObject {
+		return nil, ErrNoObject
+	}
+	r.restore()
+	var result []inspect.RawField
+	if len(r.level.buffered) > 0 {
+		if policy == inspect.FailOnUnknown {
+			return nil, ErrObjectTooBig
+		}
+		if policy == inspect.CollectUnknown {
+			for _, name := range r.level.order {
+				if value, ok := r.level.buffered[name]; ok {
+					result = append(result, inspect.RawField{Format: formatName, Name: name, Value: value.data})
+				}
+			}
+		}
+		r.level.buffered = nil
+	}
+	r.level.order = r.level.order[:0]
+	for r.level.remaining > 0 {
+		field, err := r.String()
+		if err != nil {
+			return nil, err
+		}
+		r.level.remaining--
+		switch policy {
+		case inspect.FailOnUnknown:
+			return nil, ErrObjectTooBig
+		case inspect.SkipUnknown:
+			err = r.Skip()
+		case inspect.CollectUnknown:
+			var data []byte
+			data, err = r.recordValue()
+			result = append(result, inspect.RawField{Format: formatName, Name: field, Value: data})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+func (r *Reader) EndObject() error {
+	if !r.level.isObject {
+		return ErrNoObject
+	}
+	_, err := r.UnknownFields(inspect.FailOnUnknown)
+	r.pop()
+	return err
+}
+func (r *Reader) StartArray() (length int, err error) {
+	code, err := r.readCode(isArray, ErrNotAnArray)
+	if err != nil {
+		return 0, err
+	}
+	result, err := r.containerLength(code)
+	if err != nil || result == 0 {
+		return 0, err
+	}
+	return int(result), r.push(false, 0)
+}
+func (r *Reader) HaveNext() (bool, error) {
+	return false, nil
+}
+func (r *Reader) EndArray() error {
+	r.pop()
+	return nil
+}
+func (r *Reader) StartMap() (length int, err error) {
+	code, err := r.readCode(isMap, ErrNotAMap)
+	if err != nil {
+		return 0, err
+	}
+	result, err := r.containerLength(code)
+	if err != nil || result == 0 {
+		return 0, err
+	}
+	return int(result), r.push(false, 0)
+}
+func (r *Reader) NextKey() (string, error) {
+	return r.String()
+}
+func (r *Reader) EndMap() error {
+	r.pop()
+	return nil
+}
+
+// recordValue skips the next value and returns its encoding
+func (r *Reader) recordValue() ([]byte, error) {
+	var data []byte
+	r.current.record = &data
+	err := r.Skip()
+	r.current.record = nil
+	return data, err
+}
+
+// Skip discards the next value, nested values are counted rather than recursed into
+func (r *Reader) Skip() error {
+	for pending := int64(1); pending > 0; pending-- {
+		code, err := r.current.ReadByte()
+		if err != nil {
+			return err
+		}
+		var size uint64
+		switch {
+		case code <= codePositiveFixintMax || code >= codeNegativeFixintMin ||
+			code == codeNil || code == codeFalse || code == codeTrue:
+		case isFixmap(code):
+			pending += 2 * int64(code&0x0f)
+		case isFixarray(code):
+			pending += int64(code & 0x0f)
+		case isFixstr(code):
+			size = uint64(code - codeFixstr)
+		case code == codeBin8 || code == codeStr8:
+			size, err = r.readSize(1)
+		case code == codeBin16 || code == codeStr16:
+			size, err = r.readSize(2)
+		case code == codeBin32 || code == codeStr32:
+			size, err = r.readSize(4)
+		case code == codeExt8:
+			size, err = r.readSize(1)
+			size++
+		case code == codeExt16:
+			size, err = r.readSize(2)
+			size++
+		case code == codeExt32:
+			size, err = r.readSize(4)
+			size++
+		case code == codeFloat32:
+			size = 4
+		case code == codeFloat64:
+			size = 8
+		case code >= codeUint8 && code <= codeUint64:
+			size = 1 << (code - codeUint8)
+		case code >= codeInt8 && code <= codeInt64:
+			size = 1 << (code - codeInt8)
+		case code >= codeFixext1 && code <= codeFixext16:
+			size = 1 + 1<<(code-codeFixext1)
+		case code == codeArray16 || code == codeMap16 || code == codeArray32 || code == codeMap32:
+			length, err := r.containerLength(code)
+			if err != nil {
+				return err
+			}
+			if code == codeMap16 || code == codeMap32 {
+				length *= 2
+			}
+			pending += length
+		default:
+			return ErrInvalidCode
+		}
+		if err != nil {
+			return err
+		}
+		if size > 0 {
+			if _, err := r.current.read(int64(size)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+}
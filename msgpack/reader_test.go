@@ -0,0 +1,131 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/msgpack"
+)
+
+func TestIntegers(t *testing.T) {
+	tests := []struct {
+		value   inspecttest.Integer
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0xcc, 0x80}},
+		{256, []byte{0xcd, 0x01, 0x00}},
+		{65536, []byte{0xce, 0x00, 0x01, 0x00, 0x00}},
+		{1 << 32, []byte{0xcf, 0, 0, 0, 1, 0, 0, 0, 0}},
+		{-1, []byte{0xff}},
+		{-32, []byte{0xe0}},
+		{-33, []byte{0xd0, 0xdf}},
+		{-129, []byte{0xd1, 0xff, 0x7f}},
+		{-32769, []byte{0xd2, 0xff, 0xff, 0x7f, 0xff}},
+		{-1<<31 - 1, []byte{0xd3, 0xff, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xff}},
+	}
+	for _, test := range tests {
+		data, err := inspect.Marshal(&test.value, msgpack.Format)
+		if err != nil || !bytes.Equal(data, test.encoded) {
+			t.Fatal(test.value, "got", data, err)
+		}
+		var result inspecttest.Integer
+		if err := inspect.Unmarshal(test.encoded, &result, msgpack.Format); err != nil || result != test.value {
+			t.Fatal(test.value, "got", result, err)
+		}
+	}
+	// wider encodings are accepted too
+	for _, input := range [][]byte{{0xd3, 0, 0, 0, 0, 0, 0, 0, 5}, {0xcd, 0, 5}, {0xcc, 5}} {
+		var result inspecttest.Integer
+		if err := inspect.Unmarshal(input, &result, msgpack.Format); err != nil || result != 5 {
+			t.Fatal(input, "got", result, err)
+		}
+	}
+}
+
+func TestLengths(t *testing.T) {
+	for _, test := range []struct {
+		length int
+		header []byte
+	}{
+		{31, []byte{0xbf}},
+		{32, []byte{0xd9, 32}},
+		{255, []byte{0xd9, 255}},
+		{256, []byte{0xda, 0x01, 0x00}},
+		{65536, []byte{0xdb, 0, 1, 0, 0}},
+	} {
+		value := inspecttest.Text(strings.Repeat("a", test.length))
+		data, err := inspect.Marshal(&value, msgpack.Format)
+		if err != nil || !bytes.Equal(data[:len(test.header)], test.header) || len(data) != len(test.header)+test.length {
+			t.Fatal(test.length, "got", data[:len(test.header)], err)
+		}
+		var result inspecttest.Text
+		if err := inspect.Unmarshal(data, &result, msgpack.Format); err != nil || result != value {
+			t.Fatal(test.length, "got", len(result), err)
+		}
+	}
+
+	list := make(inspecttest.Integers, 16)
+	data, err := inspect.Marshal(&list, msgpack.Format)
+	if err != nil || !bytes.Equal(data[:3], []byte{0xdc, 0, 16}) {
+		t.Fatal("got", data, err)
+	}
+	var result inspecttest.Integers
+	if err := inspect.Unmarshal(data, &result, msgpack.Format); err != nil || len(result) != 16 {
+		t.Fatal("got", result, err)
+	}
+}
+
+func TestHostileLength(t *testing.T) {
+	// str32 and bin32 claiming 4 GiB
+	var value inspecttest.Text
+	err := inspect.Unmarshal([]byte{0xdb, 0xff, 0xff, 0xff, 0xff, 'a'}, &value, msgpack.Format)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("got", err)
+	}
+	var data inspecttest.Blob
+	err = inspect.Unmarshal([]byte{0xc6, 0xff, 0xff, 0xff, 0xff, 'a'}, &data, msgpack.Format)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("got", err)
+	}
+	// array32 claiming 4 billion items
+	var list inspecttest.Integers
+	if err := inspect.Unmarshal([]byte{0xdd, 0xff, 0xff, 0xff, 0xff, 1}, &list, msgpack.Format); err == nil {
+		t.Fatal("got", list)
+	}
+	// headers followed by a little data, far less than they announce
+	for _, test := range []struct {
+		input []byte
+		value inspect.Inspectable
+	}{
+		{[]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, new(inspecttest.Numbers)},
+		{[]byte{0xdf, 0xff, 0xff, 0xff, 0xff}, new(inspecttest.Counts)},
+		{[]byte{0xc6, 0xff, 0xff, 0xff, 0xff}, new(inspecttest.Blob)},
+	} {
+		for _, limits := range []inspect.Limits{{}, {MaxTotalBytes: 1024}} {
+			impl := new(inspect.BinaryReadInspector[msgpack.Reader, *msgpack.Reader])
+			impl.SetLimits(limits)
+			reader := inspect.NewInspector(impl)
+			reader.SetReader(bytes.NewBuffer(append(test.input, make([]byte, 64)...)))
+			test.value.Inspect(reader)
+			if reader.LastError() == nil {
+				t.Fatal(test.input, limits, "read without an error")
+			}
+		}
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte{0x93, 0x01, 0xd0, 0xdf, 0xcd, 0x01, 0x00})
+	f.Add([]byte{0xdc, 0x00, 0x02, 0xca, 0x40, 0xa0, 0, 0, 0xc0})
+	f.Add([]byte{0xdb, 0xff, 0xff, 0xff, 0xff, 'a'})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inspecttest.Fuzz(data, msgpack.Format, new(inspecttest.Integers), new(inspecttest.Text))
+	})
+}
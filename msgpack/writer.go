@@ -0,0 +1,258 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/tvanomr/inspect"
+)
+
+// object is an open object, its map header is written once the number of members is known
+type object struct {
+	start int
+	count int
+}
+
+type Writer struct {
+	writer     io.Writer
+	bufferSize int
+	buffer     []byte
+	objects    stack[object]
+}
+
+func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
+	w.writer = writer
+	w.bufferSize = bufferSize
+	w.buffer = w.buffer[:0]
+	w.objects = w.objects[:0]
+}
+
+// write checks whether the buffer can be written out, it has to stay
+// in memory while an object is open because of the object header
+func (w *Writer) write() error {
+	if len(w.objects) == 0 && len(w.buffer) > w.bufferSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// appendCode appends a type code followed by value as a big endian integer of size bytes
+func appendCode(buffer []byte, code byte, value uint64, size int) []byte {
+	var data [9]byte
+	data[0] = code
+	switch size {
+	case 1:
+		data[1] = byte(value)
+	case 2:
+		binary.BigEndian.PutUint16(data[1:], uint16(value))
+	case 4:
+		binary.BigEndian.PutUint32(data[1:], uint32(value))
+	case 8:
+		binary.BigEndian.PutUint64(data[1:], value)
+	}
+	return append(buffer, data[:1+size]...)
+}
+
+func (w *Writer) writeCode(code byte, value uint64, size int) error {
+	w.buffer = appendCode(w.buffer, code, value, size)
+	return w.write()
+}
+
+// writeHeader writes the header of a value with length, using the fixed size
+// form when length is below fixLimit
+func (w *Writer) writeHeader(length int, fix byte, fixLimit int, code8 byte, code16 byte, code32 byte) error {
+	switch {
+	case length < fixLimit:
+		return w.writeCode(fix|byte(length), 0, 0)
+	case length <= math.MaxUint8 && code8 != 0:
+		return w.writeCode(code8, uint64(length), 1)
+	case length <= math.MaxUint16:
+		return w.writeCode(code16, uint64(length), 2)
+	case uint64(length) <= math.MaxUint32:
+		return w.writeCode(code32, uint64(length), 4)
+	}
+	return ErrTooLong
+}
+
+func (w *Writer) Bool(value bool) error {
+	if value {
+		return w.writeCode(codeTrue, 0, 0)
+	}
+	return w.writeCode(codeFalse, 0, 0)
+}
+func (w *Writer) Int8(value int8) error {
+	return w.Int64(int64(value))
+}
+func (w *Writer) Int16(value int16) error {
+	return w.Int64(int64(value))
+}
+func (w *Writer) Int32(value int32) error {
+	return w.Int64(int64(value))
+}
+
+// Int64 writes the shortest form that holds value
+func (w *Writer) Int64(value int64) error {
+	switch {
+	case value >= 0:
+		return w.Uint64(uint64(value))
+	case value >= -32:
+		return w.writeCode(byte(value), 0, 0)
+	case value >= math.MinInt8:
+		return w.writeCode(codeInt8, uint64(value), 1)
+	case value >= math.MinInt16:
+		return w.writeCode(codeInt16, uint64(value), 2)
+	case value >= math.MinInt32:
+		return w.writeCode(codeInt32, uint64(value), 4)
+	}
+	return w.writeCode(codeInt64, uint64(value), 8)
+}
+func (w *Writer) Uint8(value uint8) error {
+	return w.Uint64(uint64(value))
+}
+func (w *Writer) Uint16(value uint16) error {
+	return w.Uint64(uint64(value))
+}
+func (w *Writer) Uint32(value uint32) error {
+	return w.Uint64(uint64(value))
+}
+
+// Uint64 writes the shortest form that holds value
+func (w *Writer) Uint64(value uint64) error {
+	switch {
+	case value <= uint64(codePositiveFixintMax):
+		return w.writeCode(byte(value), 0, 0)
+	case value <= math.MaxUint8:
+		return w.writeCode(codeUint8, value, 1)
+	case value <= math.MaxUint16:
+		return w.writeCode(codeUint16, value, 2)
+	case value <= math.MaxUint32:
+		return w.writeCode(codeUint32, value, 4)
+	}
+	return w.writeCode(codeUint64, value, 8)
+}
+func (w *Writer) Float32(value float32, format byte, precision int) error {
+	return w.writeCode(codeFloat32, uint64(math.Float32bits(value)), 4)
+}
+func (w *Writer) Float64(value float64, format byte, precision int) error {
+	return w.writeCode(codeFloat64, math.Float64bits(value), 8)
+}
+func (w *Writer) String(value string) error {
+	if err := w.writeHeader(len(value), codeFixstr, 32, codeStr8, codeStr16, codeStr32); err != nil {
+		return err
+	}
+	w.buffer = append(w.buffer, value...)
+	return w.write()
+}
+func (w *Writer) Bytes(value []byte) error {
+	if err := w.writeHeader(len(value), 0, 0, codeBin8, codeBin16, codeBin32); err != nil {
+		return err
+	}
+	w.buffer = append(w.buffer, value...)
+	return w.write()
+}
+func (w *Writer) ByteString(value []byte) error {
+	if err := w.writeHeader(len(value), codeFixstr, 32, codeStr8, codeStr16, codeStr32); err != nil {
+		return err
+	}
+	w.buffer = append(w.buffer, value...)
+	return w.write()
+}
+func (w *Writer) Null() error {
+	return w.writeCode(codeNil, 0, 0)
+}
+
+func (w *Writer) Skip() error {
+	return w.Null()
+}
+func (w *Writer) NotNull() error {
+	return nil
+}
+func (w *Writer) StartObject() error {
+	w.objects.push(object{start: len(w.buffer)})
+	// a fixmap header fits most objects, a longer header is made room for on EndObject
+	w.buffer = append(w.buffer, codeFixmap)
+	return nil
+}
+func (w *Writer) Property(name string) error {
+	if len(w.objects) == 0 {
+		return ErrNoObject
+	}
+	w.objects[len(w.objects)-1].count++
+	return w.String(name)
+}
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	if !present {
+		return nil
+	}
+	return w.Property(name)
+}
+
+// RawProperty writes a member collected by a MessagePack reader
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	if field.Format != formatName {
+		return ErrRawField
+	}
+	if err := w.Property(field.Name); err != nil {
+		return err
+	}
+	w.buffer = append(w.buffer, field.Value...)
+	return w.write()
+}
+func (w *Writer) EndObject() error {
+	if len(w.objects) == 0 {
+		return ErrNoObject
+	}
+	object := w.objects.pop()
+	var header []byte
+	switch {
+	case object.count <= int(codeFixmapMax-codeFixmap):
+		w.buffer[object.start] = codeFixmap | byte(object.count)
+		return w.write()
+	case object.count <= math.MaxUint16:
+		header = appendCode(nil, codeMap16, uint64(object.count), 2)
+	default:
+		header = appendCode(nil, codeMap32, uint64(object.count), 4)
+	}
+	end := len(w.buffer)
+	w.buffer = append(w.buffer, header[1:]...)
+	copy(w.buffer[object.start+len(header):], w.buffer[object.start+1:end])
+	copy(w.buffer[object.start:], header)
+	return w.write()
+}
+func (w *Writer) StartArray(length int) error {
+	return w.writeHeader(length, codeFixarray, 16, 0, codeArray16, codeArray32)
+}
+func (w *Writer) EndArray() error {
+	return nil
+}
+func (w *Writer) StartMap(length int) error {
+	return w.writeHeader(length, codeFixmap, 16, 0, codeMap16, codeMap32)
+}
+func (w *Writer) NextKey(key string) error {
+	return w.String(key)
+}
+func (w *Writer) EndMap() error {
+	return nil
+}
+func (w *Writer) SortKeys() bool {
+	return false
+}
+func (w *Writer) Flush() error {
+	if len(w.objects) > 0 || len(w.buffer) == 0 {
+		return nil
+	}
+	written, err := w.writer.Write(w.buffer)
+	if err != nil {
+		return err
+	}
+	if written < len(w.buffer) {
+		return ErrShortWrite
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func init() {
+	var _ inspect.Writer = (*Writer)(nil)
+}
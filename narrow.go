@@ -0,0 +1,34 @@
+package inspect
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type SignedInt interface {
+	int8 | int16 | int32 | int64
+}
+
+type UnsignedInt interface {
+	uint8 | uint16 | uint32 | uint64
+}
+
+// NarrowInt converts value to a smaller signed type, returning an *OverflowError
+// instead of silently wrapping when it does not fit.
+func NarrowInt[T SignedInt](value int64) (T, error) {
+	result := T(value)
+	if int64(result) != value {
+		return 0, &OverflowError{Type: fmt.Sprintf("%T", result), Value: strconv.FormatInt(value, 10)}
+	}
+	return result, nil
+}
+
+// NarrowUint converts value to a smaller unsigned type, returning an *OverflowError
+// instead of silently wrapping when it does not fit.
+func NarrowUint[T UnsignedInt](value uint64) (T, error) {
+	result := T(value)
+	if uint64(result) != value {
+		return 0, &OverflowError{Type: fmt.Sprintf("%T", result), Value: strconv.FormatUint(value, 10)}
+	}
+	return result, nil
+}
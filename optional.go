@@ -0,0 +1,122 @@
+package inspect
+
+type OptionalState byte
+
+const (
+	// the property was not sent
+	Absent OptionalState = iota
+	// the property was sent as an explicit null
+	Null
+	// the property was sent with a value
+	Present
+)
+
+// Optional holds a value that can be absent, explicitly null or present,
+// e.g. for PATCH-style updates where "not sent" and "null" differ
+type Optional[T any] struct {
+	State OptionalState
+	Value T
+}
+
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{State: Present, Value: value}
+}
+
+func (o *Optional[T]) Set(value T) {
+	o.State = Present
+	o.Value = value
+}
+
+func (o *Optional[T]) SetNull() {
+	var empty T
+	o.State = Null
+	o.Value = empty
+}
+
+func (o *Optional[T]) Clear() {
+	var empty T
+	o.State = Absent
+	o.Value = empty
+}
+
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.State == Present
+}
+
+func PropertyOptional[T any, PT InspectablePtr[T]](object *ObjectInspector, name string,
+	value *Optional[T], description string) {
+
+	propertyOptional(object, name, value, description, func(inspector *Inspector, value *T) {
+		PT(value).Inspect(inspector)
+	})
+}
+
+// Scalar lists the built-in types that PropertyOptionalScalar reads and writes directly
+type Scalar interface {
+	bool | int | int8 | int16 | int32 | int64 | uint | uint8 | uint16 | uint32 | uint64 |
+		float32 | float64 | string | []byte
+}
+
+// PropertyOptionalScalar is PropertyOptional for built-in types, floats are written
+// in the shortest form that reads back the same value
+func PropertyOptionalScalar[T Scalar](object *ObjectInspector, name string,
+	value *Optional[T], description string) {
+
+	propertyOptional(object, name, value, description, inspectScalar[T])
+}
+
+func inspectScalar[T Scalar](inspector *Inspector, value *T) {
+	switch value := any(value).(type) {
+	case *bool:
+		inspector.Bool(value)
+	case *int:
+		inspector.Int(value)
+	case *int8:
+		inspector.Int8(value)
+	case *int16:
+		inspector.Int16(value)
+	case *int32:
+		inspector.Int32(value)
+	case *int64:
+		inspector.Int64(value)
+	case *uint:
+		inspector.Uint(value)
+	case *uint8:
+		inspector.Uint8(value)
+	case *uint16:
+		inspector.Uint16(value)
+	case *uint32:
+		inspector.Uint32(value)
+	case *uint64:
+		inspector.Uint64(value)
+	case *float32:
+		inspector.Float32(value, 'g', -1)
+	case *float64:
+		inspector.Float64(value, 'g', -1)
+	case *string:
+		inspector.String(value)
+	case *[]byte:
+		inspector.Bytes(value)
+	}
+}
+
+func propertyOptional[T any](object *ObjectInspector, name string, value *Optional[T], description string,
+	inspect func(inspector *Inspector, value *T)) {
+
+	present := value.State != Absent
+	inspector := object.Optional(name, &present, description)
+	if inspector == nil {
+		if object.IsReading() && object.impl.LastError() == nil {
+			value.Clear()
+		}
+		return
+	}
+	isNull := value.State == Null
+	inspector.Null(&isNull)
+	if isNull {
+		value.SetNull()
+		return
+	}
+	value.State = Present
+	inspect(inspector, &value.Value)
+}
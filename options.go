@@ -0,0 +1,61 @@
+package inspect
+
+// Option adjusts the inspector created by Encode, Decode, Marshal and Unmarshal,
+// options that don't apply to the direction or to the format are ignored
+type Option func(impl InspectorInterface)
+
+// WithLimits bounds what Decode and Unmarshal accept from the input, see Limits
+func WithLimits(limits Limits) Option {
+	return func(impl InspectorInterface) {
+		if reader, ok := impl.(interface{ SetLimits(limits Limits) }); ok {
+			reader.SetLimits(limits)
+		}
+	}
+}
+
+// WithUnknownFields sets what Decode and Unmarshal do with members nobody asked for,
+// FailOnUnknown when the option is not given
+func WithUnknownFields(policy UnknownFieldPolicy) Option {
+	return func(impl InspectorInterface) {
+		if reader, ok := impl.(interface {
+			SetUnknownFieldPolicy(policy UnknownFieldPolicy)
+		}); ok {
+			reader.SetUnknownFieldPolicy(policy)
+		}
+	}
+}
+
+// WithCollectErrors makes Decode and Unmarshal go on after recoverable errors
+// and return all of them in an ErrorList
+func WithCollectErrors() Option {
+	return func(impl InspectorInterface) {
+		if reader, ok := impl.(interface{ SetCollectErrors(collect bool) }); ok {
+			reader.SetCollectErrors(true)
+		}
+	}
+}
+
+// WithSortKeys makes Encode and Marshal write map keys in order
+func WithSortKeys() Option {
+	return func(impl InspectorInterface) {
+		if writer, ok := impl.(interface{ SetSortKeys(sortKeys bool) }); ok {
+			writer.SetSortKeys(true)
+		}
+	}
+}
+
+// WithCanonical makes Encode and Marshal write the canonical form of formats
+// that have one, see Canonical
+func WithCanonical() Option {
+	return func(impl InspectorInterface) {
+		if writer, ok := impl.(interface{ SetCanonical(canonical bool) }); ok {
+			writer.SetCanonical(true)
+		}
+	}
+}
+
+func applyOptions(inspector *Inspector, options []Option) {
+	for _, option := range options {
+		option(inspector.impl)
+	}
+}
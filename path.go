@@ -0,0 +1,99 @@
+package inspect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathError is returned by reading inspectors, it tells where in the input
+// the wrapped error happened, e.g. $.orders[312].items[4].price
+type PathError struct {
+	Path   string
+	Offset int64
+	Err    error
+}
+
+func (p *PathError) Error() string {
+	return fmt.Sprintf("%s (offset %d): %v", p.Path, p.Offset, p.Err)
+}
+
+func (p *PathError) Unwrap() error {
+	return p.Err
+}
+
+type pathKind byte
+
+const (
+	pathObject pathKind = iota
+	pathArray
+	pathMap
+)
+
+type pathElement struct {
+	kind pathKind
+	// property name or map key
+	name string
+	// number of array items read so far
+	index int
+}
+
+// path is the logical position of a reading inspector
+type path []pathElement
+
+func (p *path) push(kind pathKind) {
+	*p = append(*p, pathElement{kind: kind})
+}
+
+func (p *path) pop() {
+	if len(*p) > 0 {
+		*p = (*p)[:len(*p)-1]
+	}
+}
+
+func (p path) setName(name string) {
+	if len(p) > 0 && p[len(p)-1].kind != pathArray {
+		p[len(p)-1].name = name
+	}
+}
+
+// endValue moves to the next item after a whole array item was read
+func (p path) endValue() {
+	if len(p) > 0 && p[len(p)-1].kind == pathArray {
+		p[len(p)-1].index++
+	}
+}
+
+func isIdentifier(name string) bool {
+	for i, c := range name {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return len(name) > 0
+}
+
+func (p path) String() string {
+	var builder strings.Builder
+	builder.WriteByte('$')
+	for _, element := range p {
+		if element.kind == pathArray {
+			builder.WriteByte('[')
+			builder.WriteString(strconv.Itoa(element.index))
+			builder.WriteByte(']')
+			continue
+		}
+		if len(element.name) == 0 {
+			continue
+		}
+		if isIdentifier(element.name) {
+			builder.WriteByte('.')
+			builder.WriteString(element.name)
+		} else {
+			builder.WriteByte('[')
+			builder.WriteString(strconv.Quote(element.name))
+			builder.WriteByte(']')
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,37 @@
+package inspect
+
+// PropertyObject inspects a nested object stored in the named property
+func PropertyObject[T any, PT InspectablePtr[T]](object *ObjectInspector, name string, value *T,
+	mandatory bool, description string) {
+
+	if inspector := object.Property(name, mandatory, description); inspector != nil {
+		PT(value).Inspect(inspector)
+	}
+}
+
+// PropertyArray inspects a slice stored in the named property, see Array
+func PropertyArray[PT InspectablePtr[T], T any](object *ObjectInspector, name string, value *[]T,
+	elementName string, mandatory bool, description string) {
+
+	if inspector := object.Property(name, mandatory, description); inspector != nil {
+		Array[PT](value, inspector, name, elementName, description)
+	}
+}
+
+// PropertyArrayPtr inspects a slice of pointers stored in the named property, see ArrayPtr
+func PropertyArrayPtr[T any, PT InspectablePtr[T]](object *ObjectInspector, name string, value *[]PT,
+	elementName string, mandatory bool, description string) {
+
+	if inspector := object.Property(name, mandatory, description); inspector != nil {
+		ArrayPtr(value, inspector, name, elementName, description)
+	}
+}
+
+// PropertyMap inspects a map with string keys stored in the named property, see StringMap
+func PropertyMap[T any, PT InspectablePtr[T]](object *ObjectInspector, name string, value *map[string]T,
+	elementName string, mandatory bool, description string) {
+
+	if inspector := object.Property(name, mandatory, description); inspector != nil {
+		StringMap[T, PT](value, inspector, name, elementName, description)
+	}
+}
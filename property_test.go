@@ -0,0 +1,590 @@
+package inspect_test
+
+import (
+	"bytes"
+	encoding "encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/binary"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/json"
+)
+
+// partialSettings writes settings with the count property left out
+type partialSettings inspecttest.Settings
+
+func (s *partialSettings) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("settings", "settings with a single property")
+	o.String("name", &s.Name, true, "name")
+	present := false
+	o.Optional("count", &present, "count")
+	o.End()
+}
+
+func TestPropertyMatrixJSON(t *testing.T) {
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader]))
+	tests := []struct {
+		input     string
+		mandatory bool
+		err       error
+		count     int
+	}{
+		{`{"name":"a","count":3}`, true, nil, 3},
+		{`{"name":"a","count":3}`, false, nil, 3},
+		{`{"count":3,"name":"a"}`, false, nil, 3},
+		{`{"name":"a"}`, true, inspect.ErrNoField, 5},
+		{`{"name":"a"}`, false, nil, 5},
+		{`{"count":3}`, false, inspect.ErrNoField, 5},
+	}
+	for _, test := range tests {
+		value := inspecttest.Settings{Mandatory: test.mandatory, Count: 5}
+		reader.SetReader(bytes.NewBufferString(test.input))
+		value.Inspect(reader)
+		if !errors.Is(reader.LastError(), test.err) {
+			t.Fatal(test.input, test.mandatory, "got", reader.LastError(), "expected", test.err)
+		}
+		if value.Count != test.count {
+			t.Fatal(test.input, test.mandatory, "got", value.Count, "expected", test.count)
+		}
+	}
+}
+
+func TestPropertyMatrixBinary(t *testing.T) {
+	writer := inspect.NewInspector(new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer]))
+	reader := inspect.NewInspector(new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader]))
+	tests := []struct {
+		value     inspect.Inspectable
+		mandatory bool
+		count     int
+	}{
+		{&inspecttest.Settings{Mandatory: true, Name: "a", Count: 3}, true, 3},
+		{&inspecttest.Settings{Mandatory: false, Name: "a", Count: 3}, false, 3},
+		{&partialSettings{Name: "a"}, false, 5},
+	}
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		writer.SetWriter(&buffer, 10)
+		test.value.Inspect(writer)
+		writer.Flush()
+		if writer.LastError() != nil {
+			t.Fatal(writer.LastError())
+		}
+		value := inspecttest.Settings{Mandatory: test.mandatory, Count: 5}
+		reader.SetReader(&buffer)
+		value.Inspect(reader)
+		if reader.LastError() != nil {
+			t.Fatal(test.mandatory, reader.LastError())
+		}
+		if value.Name != "a" || value.Count != test.count {
+			t.Fatal(test.mandatory, "got", value, "expected count", test.count)
+		}
+	}
+}
+
+func TestNestedJSON(t *testing.T) {
+	inspecttest.Nested(t, inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer, *json.Writer])),
+		inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader])))
+}
+
+func TestNestedBinary(t *testing.T) {
+	inspecttest.Nested(t, inspect.NewInspector(new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer])),
+		inspect.NewInspector(new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader])))
+}
+
+func TestUnknownFieldsJSON(t *testing.T) {
+	input := `{"extra":{"a":[1,2]},"name":"x","more":true}`
+	impl := new(inspect.TextReadInspector[json.Reader, *json.Reader])
+	reader := inspect.NewInspector(impl)
+	for _, test := range []struct {
+		policy inspect.UnknownFieldPolicy
+		err    error
+	}{{inspect.FailOnUnknown, json.ErrObjectTooBig}, {inspect.SkipUnknown, nil}, {inspect.CollectUnknown, nil}} {
+		impl.SetUnknownFieldPolicy(test.policy)
+		reader.SetReader(bytes.NewBufferString(input))
+		var value inspecttest.Settings
+		value.Inspect(reader)
+		if !errors.Is(reader.LastError(), test.err) {
+			t.Fatal(test.policy, "got", reader.LastError(), "expected", test.err)
+		}
+	}
+
+	impl.SetUnknownFieldPolicy(inspect.FailOnUnknown)
+	reader.SetReader(bytes.NewBufferString(input))
+	var value inspecttest.Extensible
+	value.Inspect(reader)
+	if reader.LastError() != nil {
+		t.Fatal(reader.LastError())
+	}
+	if value.Name != "x" || len(value.Unknown) != 2 {
+		t.Fatal("got", value)
+	}
+
+	writer := inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer, *json.Writer]))
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	expected := `{"name":"x","extra":{"a":[1,2]},"more":true}`
+	if writer.LastError() != nil || buffer.String() != expected {
+		t.Fatal("got", buffer.String(), writer.LastError(), "expected", expected)
+	}
+}
+
+func TestUnknownFieldsBinary(t *testing.T) {
+	// positional properties leave nothing to collect
+	impl := new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader])
+	reader := inspect.NewInspector(impl)
+	reader.SetReader(bytes.NewReader([]byte{2, 'x'}))
+	var value inspecttest.Extensible
+	value.Inspect(reader)
+	if !errors.Is(reader.LastError(), inspect.ErrUnknownFieldsUnsupported) {
+		t.Fatal("got", reader.LastError(), "expected", inspect.ErrUnknownFieldsUnsupported)
+	}
+	// while the check at the end of every object passes with any policy
+	for _, policy := range []inspect.UnknownFieldPolicy{inspect.FailOnUnknown, inspect.CollectUnknown} {
+		impl.SetUnknownFieldPolicy(policy)
+		reader.SetReader(bytes.NewReader([]byte{2, 'x', 0}))
+		var result inspecttest.Settings
+		result.Inspect(reader)
+		if reader.LastError() != nil || result.Name != "x" {
+			t.Fatal(policy, "got", result, reader.LastError())
+		}
+	}
+}
+
+func TestSkipJSON(t *testing.T) {
+	inspecttest.Skip(t, inspect.NewInspector(new(inspect.TextWriteInspector[json.Writer, *json.Writer])),
+		inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader])))
+}
+
+func TestSkipBinary(t *testing.T) {
+	// property values prefixed by their length can be skipped
+	writer := new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer])
+	writer.Writer().SetFramed(true)
+	reader := new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader])
+	reader.Reader().SetFramed(true)
+	inspecttest.Skip(t, inspect.NewInspector(writer), inspect.NewInspector(reader))
+}
+
+func TestPathError(t *testing.T) {
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[json.Reader, *json.Reader]))
+	input := `{"id":1,"customer":{"id":2,"flags":{"enabled":true,"visible":false}},` +
+		`"items":[{"name":"a","price":1},{"name":"b","price":"x"}],"notes":[],"tags":{}}`
+	reader.SetReader(bytes.NewBufferString(input))
+	var value inspecttest.Order
+	value.Inspect(reader)
+	var pathError *inspect.PathError
+	if !errors.As(reader.LastError(), &pathError) {
+		t.Fatal("got", reader.LastError(), "expected a path error")
+	}
+	if pathError.Path != "$.items[1].price" || pathError.Offset != int64(bytes.Index([]byte(input), []byte(`"x"`))) {
+		t.Fatal("got", pathError.Path, pathError.Offset)
+	}
+
+	reader.SetReader(bytes.NewBufferString(`{"id":1,"customer":{"flags":{"visible":false},"id":2}}`))
+	value.Inspect(reader)
+	if !errors.As(reader.LastError(), &pathError) || !errors.Is(reader.LastError(), inspect.ErrNoField) {
+		t.Fatal("got", reader.LastError(), "expected a missing field")
+	}
+	if pathError.Path != "$.customer.flags.enabled" || pathError.Offset != 45 {
+		t.Fatal("got", pathError.Path, pathError.Offset)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	readInspector := new(inspect.TextReadInspector[json.Reader, *json.Reader])
+	readInspector.SetCollectErrors(true)
+	reader := inspect.NewInspector(readInspector)
+	input := `{"id":1,"customer":{"id":2,"flags":{"visible":false}},` +
+		`"items":[{"name":"a","price":1,"extra":0},{"name":"b","price":"x"}],"notes":[],"tags":{"t":3}}`
+	reader.SetReader(bytes.NewBufferString(input))
+	var value inspecttest.Order
+	value.Inspect(reader)
+	var list inspect.ErrorList
+	if !errors.As(reader.LastError(), &list) {
+		t.Fatal("got", reader.LastError(), "expected an error list")
+	}
+	expected := []struct {
+		path string
+		err  error
+	}{
+		{"$.customer.flags.enabled", inspect.ErrNoField},
+		{"$.items[0].extra", inspect.ErrUnknownField},
+		{"$.items[1].price", inspect.ErrTypeMismatch},
+	}
+	if len(list) != len(expected) {
+		t.Fatal("got", list)
+	}
+	for i, err := range list {
+		var pathError *inspect.PathError
+		if !errors.As(err, &pathError) || pathError.Path != expected[i].path || !errors.Is(err, expected[i].err) {
+			t.Fatal("got", err, "expected", expected[i].path, expected[i].err)
+		}
+	}
+	if value.Tags["t"] != 3 || len(value.Items) != 2 || value.Items[1].Name != "b" {
+		t.Fatal("reading did not go on after errors", value)
+	}
+	// called directly, as errors.Is and errors.As do before Go 1.20
+	var pathError *inspect.PathError
+	if !list.Is(inspect.ErrTypeMismatch) || list.Is(inspect.ErrLimitExceeded) ||
+		!list.As(&pathError) || pathError.Path != expected[0].path {
+		t.Fatal("got", list)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	readInspector := new(inspect.TextReadInspector[json.Reader, *json.Reader])
+	reader := inspect.NewInspector(readInspector)
+	input := `{"id":1,"customer":{"id":2,"flags":{"enabled":true,"visible":false}},` +
+		`"items":[{"name":"a","price":1},{"name":"bcd","price":2}],"notes":[],"tags":{"t":3}}`
+	tests := []inspect.Limits{
+		{},
+		{MaxStringLength: 2},
+		{MaxArrayLength: 1},
+		{MaxDepth: 2},
+		{MaxTotalBytes: int64(len(input)) - 1},
+	}
+	for i, limits := range tests {
+		readInspector.SetLimits(limits)
+		reader.SetReader(bytes.NewBufferString(input))
+		var value inspecttest.Order
+		value.Inspect(reader)
+		if (i == 0) != (reader.LastError() == nil) || (i > 0 && !errors.Is(reader.LastError(), inspect.ErrLimitExceeded)) {
+			t.Fatal(limits, "got", reader.LastError())
+		}
+	}
+	readInspector.SetLimits(inspect.Limits{MaxTotalBytes: int64(len(input))})
+	reader.SetReader(bytes.NewBufferString(input))
+	var value inspecttest.Order
+	value.Inspect(reader)
+	if reader.LastError() != nil {
+		t.Fatal("input at the limit got", reader.LastError())
+	}
+
+	// a name claiming to be a terabyte long
+	hostile := make([]byte, encoding.MaxVarintLen64)
+	hostile = hostile[:encoding.PutVarint(hostile, 1<<40)]
+	binaryInspector := new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader])
+	binaryReader := inspect.NewInspector(binaryInspector)
+	for _, limits := range []inspect.Limits{{MaxStringLength: 1024}, {MaxTotalBytes: 1024}} {
+		binaryInspector.SetLimits(limits)
+		binaryReader.SetReader(bytes.NewBuffer(hostile))
+		var result inspecttest.Settings
+		result.Inspect(binaryReader)
+		if !errors.Is(binaryReader.LastError(), inspect.ErrLimitExceeded) {
+			t.Fatal(limits, "got", binaryReader.LastError())
+		}
+	}
+}
+
+// countingInput tells how much of the input a reader consumed
+type countingInput struct {
+	reader io.Reader
+	count  int
+}
+
+func (c *countingInput) Read(buffer []byte) (int, error) {
+	read, err := c.reader.Read(buffer)
+	c.count += read
+	return read, err
+}
+
+func TestHostileLengths(t *testing.T) {
+	varint := func(value int64) []byte {
+		buffer := make([]byte, encoding.MaxVarintLen64)
+		return buffer[:encoding.PutVarint(buffer, value)]
+	}
+	tests := []struct {
+		name  string
+		input []byte
+		value inspect.Inspectable
+	}{
+		{"array", varint(1 << 34), new(inspecttest.Numbers)},
+		{"map", varint(1 << 34), new(inspecttest.Counts)},
+		{"bytes", varint(1 << 40), new(inspecttest.Blob)},
+	}
+	binaryInspector := new(inspect.BinaryReadInspector[binary.Reader, *binary.Reader])
+	binaryReader := inspect.NewInspector(binaryInspector)
+	for _, test := range tests {
+		for _, limits := range []inspect.Limits{{}, {MaxTotalBytes: 1024}} {
+			binaryInspector.SetLimits(limits)
+			// the header followed by a little data, far less than it announces
+			binaryReader.SetReader(bytes.NewBuffer(append(test.input, make([]byte, 64)...)))
+			test.value.Inspect(binaryReader)
+			if binaryReader.LastError() == nil {
+				t.Fatal(test.name, limits, "read without an error")
+			}
+		}
+	}
+
+	// a string is cut short once it is longer than any string of the allowed length
+	readInspector := new(inspect.TextReadInspector[json.Reader, *json.Reader])
+	readInspector.SetLimits(inspect.Limits{MaxStringLength: 16})
+	reader := inspect.NewInspector(readInspector)
+	input := &countingInput{reader: io.MultiReader(strings.NewReader(`"`), zeros{})}
+	reader.SetReader(input)
+	var value string
+	reader.String(&value)
+	if !errors.Is(reader.LastError(), inspect.ErrLimitExceeded) || input.count > 4096 {
+		t.Fatal("got", reader.LastError(), "after", input.count, "bytes")
+	}
+}
+
+// zeros is an endless input of the character 0
+type zeros struct{}
+
+func (zeros) Read(buffer []byte) (int, error) {
+	for i := range buffer {
+		buffer[i] = '0'
+	}
+	return len(buffer), nil
+}
+
+func TestMarshal(t *testing.T) {
+	value := inspecttest.Order{
+		ID:       3,
+		Customer: inspecttest.Wrapper{ID: 4, Flags: inspecttest.Flags{Enabled: true}},
+		Items:    []inspecttest.Item{{Name: "apple", Price: 1.5}},
+		Notes:    []*inspecttest.Item{},
+		Tags:     map[string]inspecttest.IntValue{"a": 1},
+	}
+	for _, format := range []inspect.Format{json.Format, binary.Format} {
+		data, err := inspect.Marshal(&value, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result inspecttest.Order
+		if err := inspect.Unmarshal(data, &result, format); err != nil {
+			t.Fatal(err)
+		}
+		if !result.Equal(&value) {
+			t.Fatal("got", result, "expected", value)
+		}
+	}
+	var result inspecttest.Order
+	err := inspect.Decode(bytes.NewBufferString(`{"id":1}`), &result, json.Format)
+	if !errors.Is(err, inspect.ErrNoField) {
+		t.Fatal("got", err, "expected", inspect.ErrNoField)
+	}
+}
+
+func TestMarshalOptions(t *testing.T) {
+	input := []byte(`{"id":1,"customer":{"id":2,"flags":{"visible":false}},` +
+		`"items":[{"name":"abc","price":1,"extra":0}],"notes":[],"tags":{"b":2,"a":1}}`)
+	var value inspecttest.Order
+	err := inspect.Unmarshal(input, &value, json.Format, inspect.WithLimits(inspect.Limits{MaxDepth: 2}))
+	if !errors.Is(err, inspect.ErrLimitExceeded) {
+		t.Fatal("got", err, "expected", inspect.ErrLimitExceeded)
+	}
+	err = inspect.Unmarshal(input, &value, json.Format, inspect.WithCollectErrors())
+	var list inspect.ErrorList
+	if !errors.As(err, &list) || len(list) != 2 {
+		t.Fatal("got", err, "expected two errors")
+	}
+	err = inspect.Unmarshal(input, &value, json.Format, inspect.WithCollectErrors(),
+		inspect.WithUnknownFields(inspect.SkipUnknown))
+	if !errors.Is(err, inspect.ErrNoField) || errors.Is(err, inspect.ErrUnknownField) {
+		t.Fatal("got", err, "expected", inspect.ErrNoField)
+	}
+
+	// options of the other direction are ignored
+	for _, option := range []inspect.Option{inspect.WithSortKeys(), inspect.WithCanonical()} {
+		data, err := inspect.Marshal(&value, json.Format, option, inspect.WithLimits(inspect.Limits{MaxDepth: 1}))
+		if err != nil || !strings.Contains(string(data), `"tags":{"a":1,"b":2}`) {
+			t.Fatal("got", string(data), err)
+		}
+	}
+	data, err := inspect.Marshal(&value, json.Format, inspect.WithCanonical())
+	if err != nil || !strings.HasPrefix(string(data), `{"customer":{"flags":`) {
+		t.Fatal("got", string(data), err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	if format, ok := inspect.FormatByName("json"); !ok || format != json.Format {
+		t.Fatal("json is not registered")
+	}
+	if format, ok := inspect.FormatByMIMEType("Application/JSON; charset=utf-8"); !ok || format != json.Format {
+		t.Fatal("application/json is not registered")
+	}
+	if format, ok := inspect.FormatByMIMEType("application/x-inspect"); !ok || format != binary.Format {
+		t.Fatal("application/x-inspect is not registered")
+	}
+	if _, ok := inspect.FormatByName("edn"); ok {
+		t.Fatal("edn is registered")
+	}
+	names := inspect.Formats()
+	if len(names) < 2 || !sort.StringsAreSorted(names) {
+		t.Fatal("got", names)
+	}
+}
+
+func TestIndentJSON(t *testing.T) {
+	writeInspector := new(inspect.TextWriteInspector[json.Writer, *json.Writer])
+	writeInspector.Writer().SetIndent(json.Indent{Indent: "  ", SpaceAfterColon: true})
+	writer := inspect.NewInspector(writeInspector)
+	value := inspecttest.Order{
+		ID:       3,
+		Customer: inspecttest.Wrapper{ID: 4, Flags: inspecttest.Flags{Enabled: true}},
+		Items:    []inspecttest.Item{{Name: "apple", Price: 1.5}},
+		Notes:    []*inspecttest.Item{},
+		Tags:     map[string]inspecttest.IntValue{"a": 1},
+	}
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil {
+		t.Fatal(writer.LastError())
+	}
+	expected := `{
+  "id": 3,
+  "customer": {
+    "id": 4,
+    "flags": {
+      "enabled": true,
+      "visible": false
+    }
+  },
+  "items": [
+    {
+      "name": "apple",
+      "price": 1.5
+    }
+  ],
+  "notes": [],
+  "tags": {
+    "a": 1
+  }
+}`
+	if buffer.String() != expected {
+		t.Fatal("got", buffer.String())
+	}
+	var result inspecttest.Order
+	if err := inspect.Unmarshal(buffer.Bytes(), &result, json.Format); err != nil || !result.Equal(&value) {
+		t.Fatal("got", result, err)
+	}
+}
+
+type signed struct {
+	text   string
+	values []float64
+	tags   map[string]inspecttest.IntValue
+}
+
+func (s *signed) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("signed", "payload with members out of order")
+	o.String("z", &s.text, true, "text")
+	// written only
+	if values := o.Property("values", true, "numbers"); values != nil {
+		values.WriteArray("values", "value", len(s.values), "numbers")
+		for i := range s.values {
+			values.Float64(&s.values[i], 'g', 3)
+		}
+		values.EndArray()
+	}
+	inspect.PropertyMap(o, "a", &s.tags, "tag", true, "tags")
+	o.End()
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	writeInspector := new(inspect.TextWriteInspector[json.Writer, *json.Writer])
+	writeInspector.Writer().SetCanonical(true)
+	writeInspector.Writer().SetIndent(json.Indent{Indent: "  "})
+	writer := inspect.NewInspector(writeInspector)
+	value := signed{
+		text:   "< \n\x01>",
+		values: []float64{0.1, 1e21, 1e-7, 123456789.5, -0.0, 5},
+		tags:   map[string]inspecttest.IntValue{"b": 2, "a": 1, "€": 4, "\U0001F600": 3, "c": 5, "\ufb33": 6},
+	}
+	// UTF-16 order puts U+FB33 after the surrogates of U+1F600
+	expected := `{"a":{"a":1,"b":2,"c":5,"€":4,"` + "\U0001F600" + `":3,"` + "\ufb33" + `":6},` +
+		`"values":[0.1,1e+21,1e-7,123456789.5,0,5],"z":"<` + " " + `\n\u0001>"}`
+	for i := 0; i < 3; i++ {
+		var buffer bytes.Buffer
+		writer.SetWriter(&buffer, 1)
+		value.Inspect(writer)
+		writer.Flush()
+		if writer.LastError() != nil {
+			t.Fatal(writer.LastError())
+		}
+		if buffer.String() != expected {
+			t.Fatal("got", buffer.String())
+		}
+	}
+}
+
+// rank sorts in reverse
+type rank int
+
+func (r *rank) Inspect(inspector *inspect.Inspector) {
+	inspector.Int((*int)(r))
+}
+
+func (r rank) Less(other rank) bool {
+	return r > other
+}
+
+type catalog struct {
+	byID   map[inspecttest.IntValue]*inspecttest.Item
+	byRank map[rank]inspecttest.IntValue
+	byName map[string]inspecttest.IntValue
+}
+
+func (c *catalog) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("catalog", "maps of all kinds")
+	if byID := o.Property("byID", true, "items by id"); byID != nil {
+		inspect.MapPtr[inspecttest.IntValue, *inspecttest.IntValue, inspecttest.Item, *inspecttest.Item](&c.byID, byID, "byID", "id", "item", "items by id")
+	}
+	if byRank := o.Property("byRank", true, "ids by rank"); byRank != nil {
+		inspect.Map[rank, *rank, inspecttest.IntValue, *inspecttest.IntValue](&c.byRank, byRank, "byRank", "rank", "id", "ids by rank")
+	}
+	inspect.PropertyMap(o, "byName", &c.byName, "id", true, "ids by name")
+	o.End()
+}
+
+func TestSortKeys(t *testing.T) {
+	value := catalog{byID: map[inspecttest.IntValue]*inspecttest.Item{}, byRank: map[rank]inspecttest.IntValue{}, byName: map[string]inspecttest.IntValue{}}
+	for i := 0; i < 20; i++ {
+		value.byID[inspecttest.IntValue(i)] = &inspecttest.Item{Name: string(rune('a' + i))}
+		value.byRank[rank(i)] = inspecttest.IntValue(i)
+		value.byName[string(rune('a'+i))] = inspecttest.IntValue(i)
+	}
+	jsonInspector := new(inspect.TextWriteInspector[json.Writer, *json.Writer])
+	binaryInspector := new(inspect.BinaryWriteInspector[binary.Writer, *binary.Writer])
+	jsonInspector.SetSortKeys(true)
+	binaryInspector.SetSortKeys(true)
+	for _, writer := range []*inspect.Inspector{inspect.NewInspector(jsonInspector), inspect.NewInspector(binaryInspector)} {
+		var first []byte
+		for i := 0; i < 5; i++ {
+			var buffer bytes.Buffer
+			writer.SetWriter(&buffer, 10)
+			value.Inspect(writer)
+			writer.Flush()
+			if writer.LastError() != nil {
+				t.Fatal(writer.LastError())
+			}
+			if first == nil {
+				first = buffer.Bytes()
+			} else if !bytes.Equal(first, buffer.Bytes()) {
+				t.Fatal("output differs", first, buffer.Bytes())
+			}
+		}
+	}
+	var buffer bytes.Buffer
+	writer := inspect.NewInspector(jsonInspector)
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	output := buffer.String()
+	if !strings.Contains(output, `"byID":[{"k":0,"v":{"name":"a","price":0}},{"k":1,`) ||
+		!strings.Contains(output, `"byRank":[{"k":19,"v":19},{"k":18,`) ||
+		!strings.Contains(output, `"byName":{"a":0,"b":1,`) {
+		t.Fatal("got", output)
+	}
+}
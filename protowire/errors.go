@@ -0,0 +1,50 @@
+package protowire
+
+import "github.com/tvanomr/inspect"
+
+type protoError int
+
+const (
+	ErrShortWrite protoError = iota
+	ErrNoFieldNumber
+	ErrInvalidFieldNumber
+	ErrNotAMessage
+	ErrNestedArray
+	ErrMixedArray
+	ErrWireType
+	ErrInvalidVarint
+	ErrTruncated
+	ErrObjectTooBig
+	ErrNotSkippable
+	ErrUnsupportedWireType
+	ErrRawField
+)
+
+var errorMessages = map[protoError]string{
+	ErrShortWrite:          "short write",
+	ErrNoFieldNumber:       "property has no field number",
+	ErrInvalidFieldNumber:  "field number out of range",
+	ErrNotAMessage:         "protobuf values have to be inside a message",
+	ErrNestedArray:         "protobuf has no arrays of arrays",
+	ErrMixedArray:          "packed scalars and other values in one repeated field",
+	ErrWireType:            "field has another wire type",
+	ErrInvalidVarint:       "invalid varint",
+	ErrTruncated:           "field is cut short",
+	ErrObjectTooBig:        "message contains more fields than requested",
+	ErrNotSkippable:        "packed items can't be skipped",
+	ErrUnsupportedWireType: "groups are not supported",
+	ErrRawField:            "fields collected from another format can't be written"}
+
+func (p protoError) Error() string {
+	return errorMessages[p]
+}
+
+func (p protoError) Is(target error) bool {
+	switch p {
+	case ErrWireType:
+		return target == inspect.ErrTypeMismatch
+	case ErrObjectTooBig:
+		return target == inspect.ErrUnknownField
+	}
+	return false
+}
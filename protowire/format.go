@@ -0,0 +1,14 @@
+package protowire
+
+import "github.com/tvanomr/inspect"
+
+// Format is the protobuf wire format for inspect.Marshal, inspect.Unmarshal, inspect.Encode and inspect.Decode,
+// properties need field numbers set with ObjectInspector.Number
+var Format inspect.Format = inspect.BinaryFormat[Reader, *Reader, Writer, *Writer]{}
+
+// formatName tags the fields collected by Reader, Writer accepts only those
+const formatName = "protowire"
+
+func init() {
+	inspect.RegisterFormat(formatName, Format, "application/x-protobuf")
+}
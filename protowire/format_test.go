@@ -0,0 +1,53 @@
+package protowire_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/protowire"
+)
+
+func TestNumberedProperties(t *testing.T) {
+	value := inspecttest.Shipment{ID: 150, Label: "box", Sizes: inspecttest.Numbers{3, 270, -1},
+		Parts:  []inspecttest.Part{{Name: "lid", Weight: 0.5}, {Name: "base", Weight: 2}},
+		Stock:  map[string]inspecttest.IntValue{"a": 1},
+		Prices: map[inspecttest.IntValue]inspecttest.Real{7: 1.25}}
+	data, err := inspect.Marshal(&value, protowire.Format)
+	if err != nil || !bytes.HasPrefix(data, []byte{0x08, 0x96, 0x01, 0x12, 0x03, 'b', 'o', 'x'}) {
+		t.Fatal("got", data, err)
+	}
+	// sizes are packed, -1 takes ten bytes like int32 in protobuf
+	if !bytes.HasPrefix(data[8:], []byte{0x1a, 0x0d, 0x03, 0x8e, 0x02, 0xff}) {
+		t.Fatal("got", data[8:])
+	}
+	var result inspecttest.Shipment
+	if err := inspect.Unmarshal(data, &result, protowire.Format); err != nil || result.ID != 150 ||
+		result.Label != "box" || len(result.Sizes) != 3 || result.Sizes[2] != -1 || len(result.Parts) != 2 ||
+		result.Parts[1] != value.Parts[1] || result.Stock["a"] != 1 || result.Prices[7] != 1.25 {
+		t.Fatal("got", result, err)
+	}
+
+	// missing fields read as default values, fields may come in any order
+	result = inspecttest.Shipment{}
+	if err := inspect.Unmarshal([]byte{0x12, 0x01, 'x', 0x08, 0x05}, &result, protowire.Format); err != nil ||
+		result.ID != 5 || result.Label != "x" || len(result.Sizes) != 0 || len(result.Parts) != 0 {
+		t.Fatal("got", result, err)
+	}
+
+	impl := new(inspect.BinaryReadInspector[protowire.Reader, *protowire.Reader])
+	impl.SetUnknownFieldPolicy(inspect.FailOnUnknown)
+	reader := inspect.NewInspector(impl)
+	// a part with an unknown field 3
+	reader.SetReader(bytes.NewBuffer([]byte{0x0a, 0x03, 'l', 'i', 'd', 0x18, 0x01}))
+	var small inspecttest.Part
+	small.Inspect(reader)
+	if !errors.Is(reader.LastError(), inspect.ErrUnknownField) {
+		t.Fatal("got", reader.LastError(), "expected", inspect.ErrUnknownField)
+	}
+	if _, err := inspect.Marshal(&inspecttest.Settings{}, protowire.Format); !errors.Is(err, protowire.ErrNoFieldNumber) {
+		t.Fatal("got", err, "expected", protowire.ErrNoFieldNumber)
+	}
+}
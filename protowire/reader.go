@@ -0,0 +1,536 @@
+package protowire
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/tvanomr/inspect"
+)
+
+// field is a field of a message found in the input
+type field struct {
+	number   int
+	wireType byte
+	// start of the tag
+	tag int
+	// bytes of the value, without the length prefix for length-delimited fields
+	start int
+	end   int
+}
+
+type readContext struct {
+	kind contextKind
+	// fields of a message and whether they were asked for
+	fields []field
+	read   []bool
+	// occurrences of the property being read, the last one counts for single values;
+	// a missing property reads as the default value of its type
+	selected []field
+	// occurrences of a repeated field or entries of a map
+	items []field
+	index int
+	// rest of the packed scalars of items[index-1]
+	packed    int
+	packedEnd int
+}
+
+// Reader reads a whole message into memory before giving out its fields,
+// as protobuf fields may come in any order and repeat
+type Reader struct {
+	reader  io.Reader
+	limits  inspect.Limits
+	data    []byte
+	offset  int
+	number  int
+	current readContext
+	// enclosing contexts
+	contexts stack[readContext]
+}
+
+func (r *Reader) SetReader(reader io.Reader) {
+	r.reader = reader
+	r.data = r.data[:0]
+	r.offset = 0
+	r.number = 0
+	r.current = readContext{kind: contextTop}
+	r.contexts = r.contexts[:0]
+}
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+func (r *Reader) Offset() int64 {
+	return int64(r.offset)
+}
+func (r *Reader) FieldNumber(number int) {
+	r.number = number
+}
+
+// maxMessageSize bounds the input when Limits.MaxTotalBytes is not set,
+// like the default of the protobuf parsers
+const maxMessageSize = 64 << 20
+
+// load reads the top level message until the end of the input
+func (r *Reader) load() error {
+	limits := inspect.Limits{MaxTotalBytes: r.limits.MaxTotalBytes}
+	if limits.MaxTotalBytes <= 0 {
+		limits.MaxTotalBytes = maxMessageSize
+	}
+	data, err := io.ReadAll(io.LimitReader(r.reader, limits.MaxTotalBytes+1))
+	if err != nil {
+		return err
+	}
+	r.data = data
+	r.offset = 0
+	return limits.CheckTotal(int64(len(data)))
+}
+
+// parse splits data[start:end] into fields
+func (r *Reader) parse(start int, end int) ([]field, error) {
+	var result []field
+	for position := start; position < end; {
+		tag, length := binary.Uvarint(r.data[position:end])
+		if length <= 0 {
+			return nil, ErrInvalidVarint
+		}
+		item := field{number: int(tag >> 3), wireType: byte(tag & 7), tag: position}
+		if tag>>3 == 0 || tag>>3 > maxFieldNumber {
+			return nil, ErrInvalidFieldNumber
+		}
+		item.start = position + length
+		switch item.wireType {
+		case wireVarint:
+			_, length = binary.Uvarint(r.data[item.start:end])
+			if length <= 0 {
+				return nil, ErrInvalidVarint
+			}
+			item.end = item.start + length
+		case wireFixed64:
+			item.end = item.start + 8
+		case wireFixed32:
+			item.end = item.start + 4
+		case wireBytes:
+			size, length := binary.Uvarint(r.data[item.start:end])
+			if length <= 0 {
+				return nil, ErrInvalidVarint
+			}
+			if size > uint64(end-item.start-length) {
+				return nil, ErrTruncated
+			}
+			item.start += length
+			item.end = item.start + int(size)
+		default:
+			return nil, ErrUnsupportedWireType
+		}
+		if item.end > end {
+			return nil, ErrTruncated
+		}
+		result = append(result, item)
+		position = item.end
+	}
+	return result, nil
+}
+
+func (r *Reader) push(value readContext) error {
+	r.contexts.push(r.current)
+	r.current = value
+	return r.limits.CheckDepth(len(r.contexts) - 1)
+}
+func (r *Reader) pop() {
+	if len(r.contexts) > 0 {
+		r.current = r.contexts.pop()
+	}
+}
+
+// next finds the value that the following read consumes, scalars of repeated fields
+// may be packed; ok is false when a missing property is read
+func (r *Reader) next(wireType byte) (value field, ok bool, err error) {
+	switch r.current.kind {
+	case contextTop:
+		return field{}, false, ErrNotAMessage
+	case contextArray:
+		packable := wireType != wireBytes
+		if r.current.packed == r.current.packedEnd || !packable {
+			if r.current.index >= len(r.current.items) {
+				return field{}, false, io.ErrUnexpectedEOF
+			}
+			value = r.current.items[r.current.index]
+			if value.wireType != wireBytes || !packable {
+				if value.wireType != wireType {
+					return field{}, false, ErrWireType
+				}
+				r.current.index++
+				r.offset = value.start
+				return value, true, nil
+			}
+			r.current.index++
+			r.current.packed = value.start
+			r.current.packedEnd = value.end
+		}
+		return r.unpack(wireType)
+	}
+	if len(r.current.selected) == 0 {
+		return field{}, false, nil
+	}
+	value = r.current.selected[len(r.current.selected)-1]
+	if value.wireType != wireType {
+		return field{}, false, ErrWireType
+	}
+	r.current.selected = nil
+	r.offset = value.start
+	return value, true, nil
+}
+
+// unpack takes the next scalar of packed data
+func (r *Reader) unpack(wireType byte) (field, bool, error) {
+	start := r.current.packed
+	if start == r.current.packedEnd {
+		// an empty packed field
+		return field{}, false, nil
+	}
+	length := 0
+	switch wireType {
+	case wireVarint:
+		_, length = binary.Uvarint(r.data[start:r.current.packedEnd])
+		if length <= 0 {
+			return field{}, false, ErrInvalidVarint
+		}
+	case wireFixed32:
+		length = 4
+	case wireFixed64:
+		length = 8
+	}
+	if start+length > r.current.packedEnd {
+		return field{}, false, ErrTruncated
+	}
+	r.current.packed += length
+	r.offset = start
+	return field{wireType: wireType, start: start, end: start + length}, true, nil
+}
+
+func (r *Reader) varint() (uint64, error) {
+	value, ok, err := r.next(wireVarint)
+	if !ok {
+		return 0, err
+	}
+	result, _ := binary.Uvarint(r.data[value.start:value.end])
+	return result, nil
+}
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := r.varint()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](int64(result))
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := r.varint()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
+
+func (r *Reader) Bool() (bool, error) {
+	result, err := r.varint()
+	return result != 0, err
+}
+func (r *Reader) Int8() (int8, error) {
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	return readNarrowInt[int16](r)
+}
+func (r *Reader) Int32() (int32, error) {
+	return readNarrowInt[int32](r)
+}
+func (r *Reader) Int64() (int64, error) {
+	result, err := r.varint()
+	return int64(result), err
+}
+func (r *Reader) Uint8() (uint8, error) {
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	return r.varint()
+}
+func (r *Reader) Float32() (float32, error) {
+	value, ok, err := r.next(wireFixed32)
+	if !ok {
+		return 0, err
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(r.data[value.start:value.end])), nil
+}
+func (r *Reader) Float64() (float64, error) {
+	value, ok, err := r.next(wireFixed64)
+	if !ok {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(r.data[value.start:value.end])), nil
+}
+func (r *Reader) String() (string, error) {
+	buffer, err := r.ByteString()
+	if err != nil {
+		return "", err
+	}
+	return string(buffer), nil
+}
+func (r *Reader) Bytes() ([]byte, error) {
+	value, ok, err := r.next(wireBytes)
+	if !ok {
+		return nil, err
+	}
+	if err := r.limits.CheckString(int64(value.end - value.start)); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), r.data[value.start:value.end]...), nil
+}
+func (r *Reader) ByteString() ([]byte, error) {
+	return r.Bytes()
+}
+
+// IsNull returns true for a missing property, the writer leaves nulls out
+func (r *Reader) IsNull() (bool, error) {
+	switch r.current.kind {
+	case contextTop, contextArray:
+		return false, nil
+	}
+	if len(r.current.selected) == 0 {
+		return true, nil
+	}
+	return false, nil
+}
+
+// StartObject reads the whole input at the top level, a missing nested message is empty
+func (r *Reader) StartObject() error {
+	var fields []field
+	if r.current.kind == contextTop {
+		if err := r.load(); err != nil {
+			return err
+		}
+		var err error
+		if fields, err = r.parse(0, len(r.data)); err != nil {
+			return err
+		}
+	} else {
+		value, ok, err := r.next(wireBytes)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if fields, err = r.parse(value.start, value.end); err != nil {
+				return err
+			}
+		}
+	}
+	return r.push(readContext{kind: contextMessage, fields: fields, read: make([]bool, len(fields))})
+}
+
+// Property takes the number set by FieldNumber, a missing field reads as its default value
+func (r *Reader) Property(name string) error {
+	_, err := r.OptionalProperty(name)
+	return err
+}
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	number := r.number
+	r.number = 0
+	if r.current.kind != contextMessage {
+		return false, ErrNotAMessage
+	}
+	if number == 0 {
+		return false, ErrNoFieldNumber
+	}
+	r.current.selected = r.current.selected[:0]
+	for i, item := range r.current.fields {
+		if item.number == number {
+			r.current.selected = append(r.current.selected, item)
+			r.current.read[i] = true
+		}
+	}
+	return len(r.current.selected) > 0, nil
+}
+
+// UnknownFields returns the fields not asked for by number, with their tags
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if r.current.kind != contextMessage {
+		return nil, ErrNotAMessage
+	}
+	var result []inspect.RawField
+	for i, item := range r.current.fields {
+		if r.current.read[i] {
+			continue
+		}
+		if policy == inspect.FailOnUnknown {
+			r.offset = item.tag
+			return nil, ErrObjectTooBig
+		}
+		r.current.read[i] = true
+		if policy == inspect.CollectUnknown {
+			result = append(result, inspect.RawField{Format: formatName, Name: strconv.Itoa(item.number),
+				Value: append([]byte(nil), r.data[item.tag:item.end]...)})
+		}
+	}
+	return result, nil
+}
+func (r *Reader) EndObject() error {
+	if r.current.kind != contextMessage {
+		return ErrNotAMessage
+	}
+	r.pop()
+	if r.current.kind == contextTop {
+		r.data = r.data[:0]
+	}
+	return nil
+}
+
+// takeAll consumes every occurrence of the property being read
+func (r *Reader) takeAll() ([]field, error) {
+	switch r.current.kind {
+	case contextTop:
+		return nil, ErrNotAMessage
+	case contextArray:
+		return nil, ErrNestedArray
+	}
+	result := r.current.selected
+	r.current.selected = nil
+	if len(result) > 0 {
+		r.offset = result[0].tag
+	}
+	return result, nil
+}
+
+// StartArray returns the number of occurrences of a repeated field when none of them
+// is length-delimited, otherwise -1 as those may hold packed scalars counted only while read
+func (r *Reader) StartArray() (length int, err error) {
+	items, err := r.takeAll()
+	if err != nil || len(items) == 0 {
+		return 0, err
+	}
+	if len(items) == 1 && items[0].wireType == wireBytes && items[0].start == items[0].end {
+		// empty packed scalars
+		return 0, nil
+	}
+	length = len(items)
+	for _, item := range items {
+		if item.wireType == wireBytes {
+			length = -1
+			break
+		}
+	}
+	if length > 0 {
+		if err := r.limits.CheckArray(int64(length)); err != nil {
+			return 0, err
+		}
+	}
+	if err := r.push(readContext{kind: contextArray, items: items}); err != nil {
+		return 0, err
+	}
+	return length, nil
+}
+func (r *Reader) HaveNext() (bool, error) {
+	if r.current.kind != contextArray {
+		return false, nil
+	}
+	if r.current.packed < r.current.packedEnd || r.current.index < len(r.current.items) {
+		return true, r.limits.CheckArray(int64(r.current.index + 1))
+	}
+	return false, nil
+}
+func (r *Reader) EndArray() error {
+	if r.current.kind != contextArray {
+		return ErrNestedArray
+	}
+	r.pop()
+	return nil
+}
+
+// StartMap returns the number of entries, each entry is a message with the key as field 1
+func (r *Reader) StartMap() (length int, err error) {
+	items, err := r.takeAll()
+	if err != nil || len(items) == 0 {
+		return 0, err
+	}
+	if err := r.limits.CheckArray(int64(len(items))); err != nil {
+		return 0, err
+	}
+	if err := r.push(readContext{kind: contextMap, items: items}); err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// NextKey selects field 2 of the entry for the value that follows
+func (r *Reader) NextKey() (string, error) {
+	if r.current.kind != contextMap {
+		return "", ErrNotAMessage
+	}
+	if r.current.index >= len(r.current.items) {
+		return "", nil
+	}
+	entry := r.current.items[r.current.index]
+	r.current.index++
+	r.offset = entry.start
+	if entry.wireType != wireBytes {
+		return "", ErrWireType
+	}
+	fields, err := r.parse(entry.start, entry.end)
+	if err != nil {
+		return "", err
+	}
+	var key string
+	r.current.selected = r.current.selected[:0]
+	for _, item := range fields {
+		switch item.number {
+		case 1:
+			if item.wireType != wireBytes {
+				return "", ErrWireType
+			}
+			key = string(r.data[item.start:item.end])
+		case 2:
+			r.current.selected = append(r.current.selected, item)
+		}
+	}
+	return key, nil
+}
+func (r *Reader) EndMap() error {
+	if r.current.kind != contextMap {
+		return ErrNotAMessage
+	}
+	r.pop()
+	return nil
+}
+
+// Skip drops the property being read or the next item of a repeated field
+func (r *Reader) Skip() error {
+	switch r.current.kind {
+	case contextTop:
+		if err := r.load(); err != nil {
+			return err
+		}
+		r.data = r.data[:0]
+	case contextArray:
+		if r.current.packed < r.current.packedEnd {
+			return ErrNotSkippable
+		}
+		if r.current.index < len(r.current.items) {
+			r.current.index++
+		}
+	default:
+		r.current.selected = nil
+	}
+	return nil
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+	var _ inspect.FieldNumbered = (*Reader)(nil)
+}
@@ -0,0 +1,103 @@
+package protowire_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/protowire"
+)
+
+type number int32
+
+func (n *number) Inspect(inspector *inspect.Inspector) {
+	inspector.Int32((*int32)(n))
+}
+
+type name string
+
+func (n *name) Inspect(inspector *inspect.Inspector) {
+	inspector.String((*string)(n))
+}
+
+type sample struct {
+	numbers []number
+	names   []name
+	counts  map[string]number
+}
+
+func (s *sample) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("sample", "repeated fields and a map")
+	inspect.PropertyArray[*number](o.Number(1), "numbers", &s.numbers, "number", true, "numbers")
+	inspect.PropertyArray[*name](o.Number(2), "names", &s.names, "name", true, "names")
+	inspect.PropertyMap[number](o.Number(3), "counts", &s.counts, "count", true, "counts")
+	o.End()
+}
+
+func TestRepeatedFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		numbers []number
+		names   []name
+		counts  map[string]number
+	}{
+		{"interleaved", []byte{0x08, 0x01, 0x12, 0x01, 'a', 0x08, 0x02, 0x12, 0x01, 'b', 0x08, 0x03},
+			[]number{1, 2, 3}, []name{"a", "b"}, nil},
+		{"packed and unpacked", []byte{0x08, 0x01, 0x0a, 0x02, 0x02, 0x03, 0x08, 0x04},
+			[]number{1, 2, 3, 4}, nil, nil},
+		{"empty packed", []byte{0x0a, 0x00}, nil, nil, nil},
+		{"entry without a value", []byte{0x1a, 0x03, 0x0a, 0x01, 'a'}, nil, nil, map[string]number{"a": 0}},
+		{"entry without a key", []byte{0x1a, 0x02, 0x10, 0x05}, nil, nil, map[string]number{"": 5}},
+		{"empty entry", []byte{0x1a, 0x00}, nil, nil, map[string]number{"": 0}},
+		{"repeated key", []byte{0x1a, 0x07, 0x0a, 0x01, 'a', 0x10, 0x01, 0x10, 0x02}, nil, nil, map[string]number{"a": 2}},
+	}
+	for _, test := range tests {
+		var value sample
+		if err := inspect.Unmarshal(test.input, &value, protowire.Format); err != nil {
+			t.Fatal(test.name, err)
+		}
+		if len(value.numbers) != len(test.numbers) || len(value.names) != len(test.names) ||
+			len(value.counts) != len(test.counts) {
+			t.Fatal(test.name, "got", value)
+		}
+		for i := range test.numbers {
+			if value.numbers[i] != test.numbers[i] {
+				t.Fatal(test.name, "got", value.numbers)
+			}
+		}
+		for i := range test.names {
+			if value.names[i] != test.names[i] {
+				t.Fatal(test.name, "got", value.names)
+			}
+		}
+		for key, count := range test.counts {
+			if found, ok := value.counts[key]; !ok || found != count {
+				t.Fatal(test.name, "got", value.counts)
+			}
+		}
+	}
+}
+
+func TestHostileLength(t *testing.T) {
+	// names claiming to be a terabyte long
+	input := []byte{0x12, 0x80, 0x80, 0x80, 0x80, 0x80, 0x20, 'a'}
+	var value sample
+	if err := inspect.Unmarshal(input, &value, protowire.Format); !errors.Is(err, protowire.ErrTruncated) {
+		t.Fatal("got", err)
+	}
+	err := inspect.Unmarshal(make([]byte, 2048), &value, protowire.Format,
+		inspect.WithLimits(inspect.Limits{MaxTotalBytes: 1024}))
+	if !errors.Is(err, inspect.ErrLimitExceeded) {
+		t.Fatal("got", err)
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte{0x08, 0x01, 0x0a, 0x02, 0x02, 0x03, 0x12, 0x01, 'a', 0x1a, 0x05, 0x0a, 0x01, 'a', 0x10, 0x05})
+	f.Add([]byte{0x1a, 0x00, 0x0a, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inspecttest.Fuzz(data, protowire.Format, new(sample), new(inspecttest.Shipment))
+	})
+}
@@ -0,0 +1,23 @@
+package protowire
+
+import "encoding/binary"
+
+// wire types of the protobuf encoding
+const (
+	wireVarint  byte = 0
+	wireFixed64 byte = 1
+	wireBytes   byte = 2
+	wireFixed32 byte = 5
+)
+
+// largest field number protobuf allows
+const maxFieldNumber = 1<<29 - 1
+
+func appendVarint(buffer []byte, value uint64) []byte {
+	var data [binary.MaxVarintLen64]byte
+	return append(buffer, data[:binary.PutUvarint(data[:], value)]...)
+}
+
+func appendTag(buffer []byte, number int, wireType byte) []byte {
+	return appendVarint(buffer, uint64(number)<<3|uint64(wireType))
+}
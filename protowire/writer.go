@@ -0,0 +1,341 @@
+package protowire
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/tvanomr/inspect"
+)
+
+type contextKind byte
+
+const (
+	// outside of any message
+	contextTop contextKind = iota
+	contextMessage
+	// a repeated field
+	contextArray
+	// a map field, its entries are messages with the key as field 1 and the value as field 2
+	contextMap
+)
+
+type context struct {
+	kind contextKind
+	// field number of an array or a map
+	field int
+	// start of the open length prefix: of the message, of the packed scalars
+	// of an array, of the open entry of a map; -1 when there is none
+	frame int
+}
+
+type Writer struct {
+	writer     io.Writer
+	bufferSize int
+	buffer     []byte
+	// number set for the next property
+	number int
+	// number of the property being written in the current message
+	field      int
+	current    context
+	contexts   stack[context]
+	openFrames int
+}
+
+func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
+	w.writer = writer
+	w.bufferSize = bufferSize
+	w.buffer = w.buffer[:0]
+	w.number = 0
+	w.field = 0
+	w.current = context{kind: contextTop, frame: -1}
+	w.contexts = w.contexts[:0]
+	w.openFrames = 0
+}
+func (w *Writer) FieldNumber(number int) {
+	w.number = number
+}
+
+// write checks whether the buffer can be written out, it has to stay
+// in memory while a length prefix is open
+func (w *Writer) write() error {
+	if w.openFrames == 0 && len(w.buffer) > w.bufferSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+func (w *Writer) openFrame() int {
+	w.openFrames++
+	// one byte is enough for the length of most values, longer ones are shifted on close
+	w.buffer = append(w.buffer, 0)
+	return len(w.buffer) - 1
+}
+
+func (w *Writer) closeFrame(start int) {
+	w.openFrames--
+	var data [binary.MaxVarintLen64]byte
+	length := binary.PutUvarint(data[:], uint64(len(w.buffer)-start-1))
+	if length > 1 {
+		end := len(w.buffer)
+		w.buffer = append(w.buffer, data[1:length]...)
+		copy(w.buffer[start+length:], w.buffer[start+1:end])
+	}
+	copy(w.buffer[start:], data[:length])
+}
+
+// header writes the tag of the next value, scalars of repeated fields are packed
+func (w *Writer) header(wireType byte) error {
+	switch w.current.kind {
+	case contextTop:
+		return ErrNotAMessage
+	case contextMessage:
+		if w.field == 0 {
+			return ErrNoFieldNumber
+		}
+		w.buffer = appendTag(w.buffer, w.field, wireType)
+	case contextArray:
+		if wireType != wireBytes {
+			if w.current.frame < 0 {
+				w.buffer = appendTag(w.buffer, w.current.field, wireBytes)
+				w.current.frame = w.openFrame()
+			}
+			return nil
+		}
+		if w.current.frame >= 0 {
+			return ErrMixedArray
+		}
+		w.buffer = appendTag(w.buffer, w.current.field, wireBytes)
+	case contextMap:
+		w.buffer = appendTag(w.buffer, 2, wireType)
+	}
+	return nil
+}
+
+func (w *Writer) varint(value uint64) error {
+	if err := w.header(wireVarint); err != nil {
+		return err
+	}
+	w.buffer = appendVarint(w.buffer, value)
+	return w.write()
+}
+
+func (w *Writer) Bool(value bool) error {
+	if value {
+		return w.varint(1)
+	}
+	return w.varint(0)
+}
+
+// signed integers are written like the int32 and int64 protobuf types
+func (w *Writer) Int8(value int8) error {
+	return w.varint(uint64(value))
+}
+func (w *Writer) Int16(value int16) error {
+	return w.varint(uint64(value))
+}
+func (w *Writer) Int32(value int32) error {
+	return w.varint(uint64(value))
+}
+func (w *Writer) Int64(value int64) error {
+	return w.varint(uint64(value))
+}
+func (w *Writer) Uint8(value uint8) error {
+	return w.varint(uint64(value))
+}
+func (w *Writer) Uint16(value uint16) error {
+	return w.varint(uint64(value))
+}
+func (w *Writer) Uint32(value uint32) error {
+	return w.varint(uint64(value))
+}
+func (w *Writer) Uint64(value uint64) error {
+	return w.varint(value)
+}
+func (w *Writer) Float32(value float32, format byte, precision int) error {
+	if err := w.header(wireFixed32); err != nil {
+		return err
+	}
+	var data [4]byte
+	binary.LittleEndian.PutUint32(data[:], math.Float32bits(value))
+	w.buffer = append(w.buffer, data[:]...)
+	return w.write()
+}
+func (w *Writer) Float64(value float64, format byte, precision int) error {
+	if err := w.header(wireFixed64); err != nil {
+		return err
+	}
+	var data [8]byte
+	binary.LittleEndian.PutUint64(data[:], math.Float64bits(value))
+	w.buffer = append(w.buffer, data[:]...)
+	return w.write()
+}
+func (w *Writer) String(value string) error {
+	if err := w.header(wireBytes); err != nil {
+		return err
+	}
+	w.buffer = appendVarint(w.buffer, uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+	return w.write()
+}
+func (w *Writer) Bytes(value []byte) error {
+	if err := w.header(wireBytes); err != nil {
+		return err
+	}
+	w.buffer = appendVarint(w.buffer, uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+	return w.write()
+}
+func (w *Writer) ByteString(value []byte) error {
+	return w.Bytes(value)
+}
+
+// Null leaves the field out, protobuf has no nulls
+func (w *Writer) Null() error {
+	return nil
+}
+
+func (w *Writer) Skip() error {
+	return w.Null()
+}
+func (w *Writer) NotNull() error {
+	return nil
+}
+
+func (w *Writer) push(value context) {
+	w.contexts.push(w.current)
+	w.current = value
+}
+
+// pop closes the open length prefix of the current context
+func (w *Writer) pop() error {
+	if w.current.frame >= 0 {
+		w.closeFrame(w.current.frame)
+	}
+	if len(w.contexts) > 0 {
+		w.current = w.contexts.pop()
+	}
+	return w.write()
+}
+
+// StartObject starts the top level message or a nested one as a length-delimited field
+func (w *Writer) StartObject() error {
+	if w.current.kind == contextTop {
+		w.push(context{kind: contextMessage, frame: -1})
+		return nil
+	}
+	if err := w.header(wireBytes); err != nil {
+		return err
+	}
+	w.push(context{kind: contextMessage, frame: w.openFrame()})
+	return nil
+}
+
+// Property takes the number set by FieldNumber, the name is not written
+func (w *Writer) Property(name string) error {
+	number := w.number
+	w.number = 0
+	w.field = 0
+	if w.current.kind != contextMessage {
+		return ErrNotAMessage
+	}
+	if number == 0 {
+		return ErrNoFieldNumber
+	}
+	if number < 0 || number > maxFieldNumber {
+		return ErrInvalidFieldNumber
+	}
+	w.field = number
+	return nil
+}
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	if !present {
+		w.number = 0
+		w.field = 0
+		return nil
+	}
+	return w.Property(name)
+}
+
+// RawProperty writes fields collected by a protowire reader, their tags included
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	if field.Format != formatName {
+		return ErrRawField
+	}
+	if w.current.kind != contextMessage {
+		return ErrNotAMessage
+	}
+	w.buffer = append(w.buffer, field.Value...)
+	return w.write()
+}
+func (w *Writer) EndObject() error {
+	return w.pop()
+}
+
+// StartArray starts a repeated field, numbers are packed into a single length-delimited value
+func (w *Writer) StartArray(length int) error {
+	if w.current.kind == contextArray {
+		return ErrNestedArray
+	}
+	field := w.field
+	if w.current.kind == contextMap {
+		field = 2
+	} else if w.current.kind != contextMessage {
+		return ErrNotAMessage
+	} else if field == 0 {
+		return ErrNoFieldNumber
+	}
+	w.push(context{kind: contextArray, field: field, frame: -1})
+	return nil
+}
+func (w *Writer) EndArray() error {
+	return w.pop()
+}
+func (w *Writer) StartMap(length int) error {
+	if w.current.kind != contextMessage {
+		return ErrNotAMessage
+	}
+	if w.field == 0 {
+		return ErrNoFieldNumber
+	}
+	w.push(context{kind: contextMap, field: w.field, frame: -1})
+	return nil
+}
+
+// NextKey starts a map entry, the value that follows is its field 2
+func (w *Writer) NextKey(key string) error {
+	if w.current.frame >= 0 {
+		w.closeFrame(w.current.frame)
+	}
+	w.buffer = appendTag(w.buffer, w.current.field, wireBytes)
+	w.current.frame = w.openFrame()
+	w.buffer = appendTag(w.buffer, 1, wireBytes)
+	w.buffer = appendVarint(w.buffer, uint64(len(key)))
+	w.buffer = append(w.buffer, key...)
+	return nil
+}
+func (w *Writer) EndMap() error {
+	return w.pop()
+}
+func (w *Writer) SortKeys() bool {
+	return false
+}
+func (w *Writer) Flush() error {
+	if w.openFrames > 0 || len(w.buffer) == 0 {
+		return nil
+	}
+	written, err := w.writer.Write(w.buffer)
+	if err != nil {
+		return err
+	}
+	if written < len(w.buffer) {
+		return ErrShortWrite
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func init() {
+	var _ inspect.Writer = (*Writer)(nil)
+	var _ inspect.FieldNumbered = (*Writer)(nil)
+}
@@ -1,449 +1,644 @@
 package inspect
 
 import (
+	"errors"
 	"io"
 	"strconv"
 )
 
-type ReadInspector[R Reader] struct {
-	reader    R
-	lastError error
+type ReaderPtr[T any] interface {
+	*T
+	Reader
 }
 
-func (r *ReadInspector[R]) LastError() error {
-	return r.lastError
+type ReadInspector[R any, PR ReaderPtr[R]] struct {
+	reader             R
+	lastError          error
+	unknownFieldPolicy UnknownFieldPolicy
+	path               path
+	collectErrors      bool
+	// recoverable errors kept when collecting errors
+	errors ErrorList
 }
 
-func (r *ReadInspector[R]) SetWriter(io.Writer) {
-	if r.lastError == nil {
-		r.lastError = ErrReaderCantWrite
+// LastError returns an ErrorList when errors are collected and more than one has occurred
+func (r *ReadInspector[R, PR]) LastError() error {
+	if len(r.errors) == 0 {
+		return r.lastError
+	}
+	result := append(ErrorList(nil), r.errors...)
+	if r.lastError != nil {
+		result = append(result, r.lastError)
+	}
+	if len(result) == 1 {
+		return result[0]
 	}
+	return result
 }
 
-func (r *ReadInspector[R]) SetReader(reader io.Reader) {
-	r.reader.SetReader(reader)
-	r.lastError = nil
+// failed tells the array and map helpers that reading has stopped
+func (r *ReadInspector[R, PR]) failed() bool {
+	return r.lastError != nil
 }
 
-func (r *ReadInspector[R]) Int32(value *int32) {
-	if r.lastError != nil {
-		return
-	}
-	var result int32
-	result, r.lastError = r.reader.Int32()
+func (r *ReadInspector[R, PR]) SetWriter(writer io.Writer, bufferSize int) {
 	if r.lastError == nil {
-		*value = result
+		r.fail(ErrReaderCantWrite)
 	}
 }
 
-func (r *ReadInspector[R]) Int64(value *int64) {
-	if r.lastError != nil {
+// SetUnknownFieldPolicy sets the policy for objects that don't call UnknownFields,
+// CollectUnknown behaves like SkipUnknown there as there is nowhere to keep the members
+func (r *ReadInspector[R, PR]) SetUnknownFieldPolicy(policy UnknownFieldPolicy) {
+	r.unknownFieldPolicy = policy
+}
+
+// SetCollectErrors makes reading go on after missing mandatory properties, type mismatches
+// of scalar values and unknown properties, LastError then reports all of them
+func (r *ReadInspector[R, PR]) SetCollectErrors(collect bool) {
+	r.collectErrors = collect
+}
+
+// SetLimits bounds string lengths, array lengths, nesting and input size
+// accepted by the reader, going over them fails with ErrLimitExceeded
+func (r *ReadInspector[R, PR]) SetLimits(limits Limits) {
+	PR(&r.reader).SetLimits(limits)
+}
+
+// Reader gives access to the backend, e.g. for its input options
+func (r *ReadInspector[R, PR]) Reader() PR {
+	return &r.reader
+}
+
+func (r *ReadInspector[R, PR]) SetReader(reader io.Reader) {
+	PR(&r.reader).SetReader(reader)
+	r.lastError = nil
+	r.errors = r.errors[:0]
+	r.path = r.path[:0]
+}
+
+// fail keeps err along with the place in the input where it happened
+func (r *ReadInspector[R, PR]) fail(err error) {
+	pathError := &PathError{Path: r.path.String(), Offset: PR(&r.reader).Offset(), Err: err}
+	if r.collectErrors && isRecoverable(err) {
+		r.errors = append(r.errors, pathError)
+		// a value of the wrong type is still in the input
+		if errors.Is(err, ErrTypeMismatch) {
+			if err := PR(&r.reader).Skip(); err != nil {
+				r.lastError = &PathError{Path: r.path.String(), Offset: PR(&r.reader).Offset(), Err: err}
+			}
+		}
 		return
 	}
-	var result int64
-	result, r.lastError = r.reader.Int64()
+	r.lastError = pathError
+}
+
+// check records err if there is one, returns true when there is none
+func (r *ReadInspector[R, PR]) check(err error) bool {
+	if err != nil {
+		r.fail(err)
+		return false
+	}
+	return true
+}
+
+// done is check for operations that complete a value,
+// a value that failed with a recoverable error is complete as well
+func (r *ReadInspector[R, PR]) done(err error) bool {
+	result := r.check(err)
 	if r.lastError == nil {
-		*value = result
+		r.path.endValue()
 	}
+	return result
 }
 
-func (r *ReadInspector[R]) Int(value *int) {
+func (r *ReadInspector[R, PR]) Bool(value *bool) {
 	if r.lastError != nil {
 		return
 	}
-	if strconv.IntSize == 64 {
-		var result int64
-		result, r.lastError = r.reader.Int64()
-		if r.lastError == nil {
-			*value = int(result)
-		}
-	} else if strconv.IntSize == 32 {
-		var result int32
-		result, r.lastError = r.reader.Int32()
-		if r.lastError == nil {
-			*value = int(result)
-		}
+	result, err := PR(&r.reader).Bool()
+	if r.done(err) {
+		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) Float32(value *float32, format byte, precision int) {
+func (r *ReadInspector[R, PR]) Int8(value *int8) {
 	if r.lastError != nil {
 		return
 	}
-	var result float32
-	result, r.lastError = r.reader.Float32()
-	if r.lastError == nil {
+	result, err := PR(&r.reader).Int8()
+	if r.done(err) {
 		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) Float64(value *float64, format byte, precision int) {
+func (r *ReadInspector[R, PR]) Int16(value *int16) {
 	if r.lastError != nil {
 		return
 	}
-	var result float64
-	result, r.lastError = r.reader.Float64()
-	if r.lastError == nil {
+	result, err := PR(&r.reader).Int16()
+	if r.done(err) {
 		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) String(value *string) {
+func (r *ReadInspector[R, PR]) Int32(value *int32) {
 	if r.lastError != nil {
 		return
 	}
-	var result string
-	result, r.lastError = r.reader.String()
-	if r.lastError == nil {
+	result, err := PR(&r.reader).Int32()
+	if r.done(err) {
 		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) Bytes(value *[]byte) {
+func (r *ReadInspector[R, PR]) Int64(value *int64) {
 	if r.lastError != nil {
 		return
 	}
-	var result []byte
-	result, r.lastError = r.reader.Bytes()
-	if r.lastError == nil {
+	result, err := PR(&r.reader).Int64()
+	if r.done(err) {
 		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) ByteString(value *[]byte) {
+func (r *ReadInspector[R, PR]) Int(value *int) {
 	if r.lastError != nil {
 		return
 	}
-	var result []byte
-	result, r.lastError = r.reader.ByteString()
-	if r.lastError == nil {
-		*value = result
+	if strconv.IntSize == 64 {
+		result, err := PR(&r.reader).Int64()
+		if r.done(err) {
+			*value = int(result)
+		}
+	} else if strconv.IntSize == 32 {
+		result, err := PR(&r.reader).Int32()
+		if r.done(err) {
+			*value = int(result)
+		}
 	}
 }
 
-func (r *ReadInspector[R]) StartObject(name string, description string) {
+func (r *ReadInspector[R, PR]) Uint8(value *uint8) {
 	if r.lastError != nil {
 		return
 	}
-	r.lastError = r.reader.StartObject()
+	result, err := PR(&r.reader).Uint8()
+	if r.done(err) {
+		*value = result
+	}
 }
 
-func (r *ReadInspector[R]) Property(name string, mandatory bool, description string) bool {
+func (r *ReadInspector[R, PR]) Uint16(value *uint16) {
 	if r.lastError != nil {
-		return false
+		return
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
-		}
+	result, err := PR(&r.reader).Uint16()
+	if r.done(err) {
+		*value = result
 	}
-	return false
 }
 
-func (r *ReadInspector[R]) PropertyInt32(name string, value *int32, mandatory bool, description string) {
+func (r *ReadInspector[R, PR]) Uint32(value *uint32) {
 	if r.lastError != nil {
 		return
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
-		}
-		return
-	}
-	var result int32
-	result, r.lastError = r.reader.Int32()
-	if r.lastError != nil {
+	result, err := PR(&r.reader).Uint32()
+	if r.done(err) {
 		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) PropertyInt64(name string, value *int64, mandatory bool, description string) {
+func (r *ReadInspector[R, PR]) Uint64(value *uint64) {
 	if r.lastError != nil {
 		return
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
-		}
-		return
-	}
-	var result int64
-	result, r.lastError = r.reader.Int64()
-	if r.lastError != nil {
+	result, err := PR(&r.reader).Uint64()
+	if r.done(err) {
 		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) PropertyInt(name string, value *int, mandatory bool, description string) {
+func (r *ReadInspector[R, PR]) Uint(value *uint) {
 	if r.lastError != nil {
 		return
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
-		}
-		return
-	}
 	if strconv.IntSize == 64 {
-		var result int64
-		result, r.lastError = r.reader.Int64()
-		if r.lastError != nil {
-			*value = int(result)
+		result, err := PR(&r.reader).Uint64()
+		if r.done(err) {
+			*value = uint(result)
 		}
 	} else if strconv.IntSize == 32 {
-		var result int32
-		result, r.lastError = r.reader.Int32()
-		if r.lastError != nil {
-			*value = int(result)
+		result, err := PR(&r.reader).Uint32()
+		if r.done(err) {
+			*value = uint(result)
 		}
 	}
 }
 
-func (r *ReadInspector[R]) PropertyFloat32(name string, value *float32, format byte, precision int, mandatory bool, description string) {
+func (r *ReadInspector[R, PR]) Float32(value *float32, format byte, precision int) {
 	if r.lastError != nil {
 		return
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
-		}
-		return
-	}
-	var result float32
-	result, r.lastError = r.reader.Float32()
-	if r.lastError != nil {
+	result, err := PR(&r.reader).Float32()
+	if r.done(err) {
 		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) PropertyFloat64(name string, value *float64, format byte, precision int, mandatory bool, description string) {
+func (r *ReadInspector[R, PR]) Float64(value *float64, format byte, precision int) {
 	if r.lastError != nil {
 		return
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
-		}
-		return
+	result, err := PR(&r.reader).Float64()
+	if r.done(err) {
+		*value = result
 	}
-	var result float64
-	result, r.lastError = r.reader.Float64()
+}
+
+func (r *ReadInspector[R, PR]) String(value *string) {
 	if r.lastError != nil {
+		return
+	}
+	result, err := PR(&r.reader).String()
+	if r.done(err) {
 		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) PropertyString(name string, value *string, mandatory bool, description string) {
+func (r *ReadInspector[R, PR]) Bytes(value *[]byte) {
 	if r.lastError != nil {
 		return
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
-		}
-		return
+	result, err := PR(&r.reader).Bytes()
+	if r.done(err) {
+		*value = result
 	}
-	var result string
-	result, r.lastError = r.reader.String()
+}
+
+func (r *ReadInspector[R, PR]) ByteString(value *[]byte) {
 	if r.lastError != nil {
+		return
+	}
+	result, err := PR(&r.reader).ByteString()
+	if r.done(err) {
 		*value = result
 	}
 }
 
-func (r *ReadInspector[R]) PropertyBytes(name string, value *[]byte, mandatory bool, description string) {
+func (r *ReadInspector[R, PR]) Null(isNull *bool) {
 	if r.lastError != nil {
 		return
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
+	result, err := PR(&r.reader).IsNull()
+	if r.check(err) {
+		*isNull = result
+		if result {
+			r.path.endValue()
 		}
+	}
+}
+
+func (r *ReadInspector[R, PR]) StartObject(name string, description string) {
+	if r.lastError != nil {
 		return
 	}
-	var result []byte
-	result, r.lastError = r.reader.Bytes()
+	if r.check(PR(&r.reader).StartObject()) {
+		r.path.push(pathObject)
+	}
+}
+
+// property positions the reader on the named property, returns false
+// when it is missing from the input or reading has failed
+func (r *ReadInspector[R, PR]) property(name string, mandatory bool) bool {
 	if r.lastError != nil {
-		*value = result
+		return false
+	}
+	r.path.setName(name)
+	if mandatory {
+		return r.check(PR(&r.reader).Property(name))
+	}
+	present, err := PR(&r.reader).OptionalProperty(name)
+	return r.check(err) && present
+}
+
+func (r *ReadInspector[R, PR]) FieldNumber(number int) {
+	if numbered, ok := any(&r.reader).(FieldNumbered); ok {
+		numbered.FieldNumber(number)
+	}
+}
+
+func (r *ReadInspector[R, PR]) UseVarint() {
+	if varints, ok := any(&r.reader).(Varints); ok {
+		varints.UseVarint()
 	}
 }
 
-func (r *ReadInspector[R]) PropertyByteString(name string, value *[]byte, mandatory bool, description string) {
+func (r *ReadInspector[R, PR]) UseAttribute() {
+	if attributes, ok := any(&r.reader).(Attributes); ok {
+		attributes.UseAttribute()
+	}
+}
+
+func (r *ReadInspector[R, PR]) Property(name string, mandatory bool, description string) bool {
+	return r.property(name, mandatory)
+}
+
+func (r *ReadInspector[R, PR]) OptionalProperty(name string, present *bool, description string) bool {
 	if r.lastError != nil {
-		return
+		return false
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
+	r.path.setName(name)
+	result, err := PR(&r.reader).OptionalProperty(name)
+	if !r.check(err) {
+		return false
+	}
+	*present = result
+	return result
+}
+
+func (r *ReadInspector[R, PR]) PropertyBool(name string, value *bool, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Bool(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyInt8(name string, value *int8, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Int8(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyInt16(name string, value *int16, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Int16(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyInt32(name string, value *int32, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Int32(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyInt64(name string, value *int64, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Int64(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyInt(name string, value *int, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Int(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyUint8(name string, value *uint8, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Uint8(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyUint16(name string, value *uint16, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Uint16(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyUint32(name string, value *uint32, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Uint32(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyUint64(name string, value *uint64, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Uint64(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyUint(name string, value *uint, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Uint(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyFloat32(name string, value *float32, format byte, precision int, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Float32(value, format, precision)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyFloat64(name string, value *float64, format byte, precision int, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Float64(value, format, precision)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyString(name string, value *string, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.String(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyBytes(name string, value *[]byte, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Bytes(value)
+	}
+}
+
+func (r *ReadInspector[R, PR]) PropertyByteString(name string, value *[]byte, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.ByteString(value)
+	}
+}
+
+// unknownFields reports every unknown field separately when collecting errors,
+// the check at the end of an object passes for readers that can't tell unknown fields
+func (r *ReadInspector[R, PR]) unknownFields(policy UnknownFieldPolicy, atEnd bool) []RawField {
+	r.path.setName("")
+	if policy != FailOnUnknown || !r.collectErrors {
+		result, err := PR(&r.reader).UnknownFields(policy)
+		if atEnd && err == ErrUnknownFieldsUnsupported {
+			err = nil
 		}
-		return
+		r.check(err)
+		return result
+	}
+	result, err := PR(&r.reader).UnknownFields(CollectUnknown)
+	if atEnd && err == ErrUnknownFieldsUnsupported {
+		return nil
+	}
+	if r.check(err) {
+		for _, field := range result {
+			r.path.setName(field.Name)
+			r.fail(ErrUnknownField)
+		}
+		r.path.setName("")
 	}
-	var result []byte
-	result, r.lastError = r.reader.ByteString()
+	return nil
+}
+
+func (r *ReadInspector[R, PR]) UnknownFields(policy UnknownFieldPolicy, fields *[]RawField) {
 	if r.lastError != nil {
-		*value = result
+		return
+	}
+	result := r.unknownFields(policy, false)
+	if r.lastError == nil && fields != nil {
+		*fields = result
 	}
 }
 
-func (r *ReadInspector[R]) EndObject() {
+func (r *ReadInspector[R, PR]) EndObject() {
 	if r.lastError != nil {
 		return
 	}
-	r.lastError = r.reader.EndObject()
+	policy := r.unknownFieldPolicy
+	if policy == CollectUnknown {
+		policy = SkipUnknown
+	}
+	r.unknownFields(policy, true)
+	if r.lastError == nil && r.check(PR(&r.reader).EndObject()) {
+		r.path.pop()
+		r.path.endValue()
+	}
 }
 
-func (r *ReadInspector[R]) ReadArray(name string, elementName string) int {
+func (r *ReadInspector[R, PR]) ReadArray() int {
 	if r.lastError != nil {
 		return 0
 	}
-	var result int
-	result, r.lastError = r.reader.StartArray()
-	if r.lastError != nil {
+	result, err := PR(&r.reader).StartArray()
+	if !r.check(err) || result == 0 {
+		if r.lastError == nil {
+			r.path.endValue()
+		}
 		return 0
 	}
+	r.path.push(pathArray)
 	return result
 }
 
-func (r *ReadInspector[R]) WriteArray(name string, elementName string, length int, description string) {
+func (r *ReadInspector[R, PR]) WriteArray(name string, elementName string, length int, description string) {
 	if r.lastError == nil {
-		r.lastError = ErrReaderCantWrite
+		r.fail(ErrReaderCantWrite)
 	}
 }
 
-func (r *ReadInspector[R]) HaveNext() bool {
+func (r *ReadInspector[R, PR]) HaveNext() bool {
 	if r.lastError != nil {
 		return false
 	}
-	var result bool
-	result, r.lastError = r.reader.HaveNext()
-	if r.lastError != nil {
-		return false
-	}
-	return result
+	result, err := PR(&r.reader).HaveNext()
+	return r.check(err) && result
 }
 
-func (r *ReadInspector[R]) EndArray() {
-	if r.lastError == nil {
-		r.lastError = r.reader.EndArray()
+func (r *ReadInspector[R, PR]) EndArray() {
+	if r.lastError == nil && r.check(PR(&r.reader).EndArray()) {
+		r.path.pop()
+		r.path.endValue()
 	}
 }
 
-func (r *ReadInspector[R]) ReadMap(name string, elementName string, description string) int {
+func (r *ReadInspector[R, PR]) ReadMap() int {
 	if r.lastError != nil {
 		return 0
 	}
-	var result int
-	result, r.lastError = r.reader.StartMap()
-	if r.lastError != nil {
+	result, err := PR(&r.reader).StartMap()
+	if !r.check(err) || result == 0 {
+		if r.lastError == nil {
+			r.path.endValue()
+		}
 		return 0
 	}
+	r.path.push(pathMap)
 	return result
 }
 
-func (r *ReadInspector[R]) WriteMap(name string, elementName string, length int, description string) {
+func (r *ReadInspector[R, PR]) WriteMap(name string, elementName string, length int, description string) {
 	if r.lastError == nil {
-		r.lastError = ErrReaderCantWrite
+		r.fail(ErrReaderCantWrite)
 	}
 }
 
-func (r *ReadInspector[R]) ReadNextKey() string {
+func (r *ReadInspector[R, PR]) ReadNextKey() string {
 	if r.lastError != nil {
 		return ""
 	}
-	var key string
-	key, r.lastError = r.reader.NextKey()
-	if r.lastError != nil {
+	key, err := PR(&r.reader).NextKey()
+	if !r.check(err) {
 		return ""
 	}
+	r.path.setName(key)
 	return key
 }
 
-func (r *ReadInspector[R]) WriteNextKey(key string) {
+func (r *ReadInspector[R, PR]) WriteNextKey(key string) {
 	if r.lastError == nil {
-		r.lastError = ErrReaderCantWrite
+		r.fail(ErrReaderCantWrite)
 	}
 }
 
-func (r *ReadInspector[R]) EndMap() {
+func (r *ReadInspector[R, PR]) EndMap() {
+	if r.lastError == nil && r.check(PR(&r.reader).EndMap()) {
+		r.path.pop()
+		r.path.endValue()
+	}
+}
+
+func (r *ReadInspector[R, PR]) Skip() {
 	if r.lastError == nil {
-		r.lastError = r.reader.EndMap()
+		r.done(PR(&r.reader).Skip())
 	}
 }
 
-func (r *ReadInspector[R]) IsReading() bool {
+func (r *ReadInspector[R, PR]) IsReading() bool {
 	return true
 }
 
-type TextReadInspector[R Reader] struct {
-	ReadInspector[R]
+func (r *ReadInspector[R, PR]) SortKeys() bool {
+	return false
 }
 
-func (r *TextReadInspector[R]) Value(value RawValue) {
-	if r.lastError != nil {
-		return
-	}
-	var data []byte
-	data, r.lastError = r.reader.ByteString()
-	if r.lastError == nil {
-		r.lastError = value.UnmarshalText(data)
-	}
+func (r *ReadInspector[R, PR]) Flush() {
 }
 
-func (r *TextReadInspector[R]) PropertyValue(name string, value RawValue, mandatory bool, description string) {
+type TextReadInspector[R any, PR ReaderPtr[R]] struct {
+	ReadInspector[R, PR]
+}
+
+func (r *TextReadInspector[R, PR]) Value(value RawValue) {
 	if r.lastError != nil {
 		return
 	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
-		}
-		return
+	data, err := PR(&r.reader).ByteString()
+	if r.check(err) {
+		r.done(value.UnmarshalText(data))
 	}
-	var data []byte
-	data, r.lastError = r.reader.ByteString()
-	if r.lastError != nil {
-		r.lastError = value.UnmarshalText(data)
+}
+
+func (r *TextReadInspector[R, PR]) PropertyValue(name string, value RawValue, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Value(value)
 	}
 }
 
-type BinaryReadInspector[R Reader] struct {
-	ReadInspector[R]
+type BinaryReadInspector[R any, PR ReaderPtr[R]] struct {
+	ReadInspector[R, PR]
 }
 
-func (r *BinaryReadInspector[R]) Value(value RawValue) {
+func (r *BinaryReadInspector[R, PR]) Value(value RawValue) {
 	if r.lastError != nil {
 		return
 	}
-	var data []byte
-	data, r.lastError = r.reader.Bytes()
-	if r.lastError == nil {
-		r.lastError = value.GobDecode(data)
+	data, err := PR(&r.reader).Bytes()
+	if r.check(err) {
+		r.done(value.GobDecode(data))
 	}
 }
 
-func (r *BinaryReadInspector[R]) PropertyValue(name string, value RawValue, mandatory bool, description string) {
-	if r.lastError != nil {
-		return
-	}
-	err := r.reader.Property(name)
-	if err != nil {
-		if mandatory || err != ErrNoField {
-			r.lastError = err
-		}
-		return
-	}
-	var data []byte
-	data, r.lastError = r.reader.Bytes()
-	if r.lastError != nil {
-		r.lastError = value.GobDecode(data)
+func (r *BinaryReadInspector[R, PR]) PropertyValue(name string, value RawValue, mandatory bool, description string) {
+	if r.property(name, mandatory) {
+		r.Value(value)
 	}
 }
@@ -0,0 +1,68 @@
+package inspect
+
+import (
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// registry of the formats backends make available at runtime
+var registry = struct {
+	sync.RWMutex
+	byName     map[string]Format
+	byMIMEType map[string]Format
+}{byName: map[string]Format{}, byMIMEType: map[string]Format{}}
+
+// RegisterFormat makes format available under name and its MIME types, backends
+// call it from init, so importing one is enough to select it at runtime.
+// It panics when the name or a MIME type is already taken.
+func RegisterFormat(name string, format Format, mimeTypes ...string) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.byName[name]; ok {
+		panic("inspect: format " + name + " registered twice")
+	}
+	for _, mimeType := range mimeTypes {
+		if _, ok := registry.byMIMEType[strings.ToLower(mimeType)]; ok {
+			panic("inspect: MIME type " + mimeType + " registered twice")
+		}
+	}
+	registry.byName[name] = format
+	for _, mimeType := range mimeTypes {
+		registry.byMIMEType[strings.ToLower(mimeType)] = format
+	}
+}
+
+// FormatByName returns the format registered under name
+func FormatByName(name string) (Format, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	format, ok := registry.byName[name]
+	return format, ok
+}
+
+// FormatByMIMEType returns the format registered for a MIME type,
+// parameters like in "application/json; charset=utf-8" are ignored
+func FormatByMIMEType(mimeType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return nil, false
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	format, ok := registry.byMIMEType[mediaType]
+	return format, ok
+}
+
+// Formats returns the names of the registered formats in alphabetical order
+func Formats() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	result := make([]string, 0, len(registry.byName))
+	for name := range registry.byName {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
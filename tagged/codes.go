@@ -0,0 +1,67 @@
+package tagged
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// type tags written before every value
+const (
+	tagNull byte = iota
+	tagFalse
+	tagTrue
+	// zigzag varint
+	tagInt
+	// varint
+	tagUint
+	// little endian IEEE 754
+	tagFloat32
+	tagFloat64
+	// varint length followed by the bytes
+	tagString
+	tagBytes
+	// varint count followed by the items, map items are a string key and a value
+	tagArray
+	tagMap
+	// keys and values of the properties followed by keyEnd
+	tagObject
+)
+
+// the low two bits of a property key tell what follows, the rest is a number
+const (
+	// the rest is the field number given to ObjectInspector.Number
+	keyNumber = 0
+	// the rest is the index of a name defined before
+	keyReference = 1
+	// the rest is the length of a name that follows and gets the next index
+	keyDefinition = 2
+	// the rest is the length of a name that follows and gets no index
+	keyLiteral = 3
+	// key that ends an object, field number 0 is never used
+	keyEnd = 0
+)
+
+// names after this many are written in full wherever they occur
+const maxNames = 1 << 12
+
+func appendVarint(buffer []byte, value uint64) []byte {
+	var data [binary.MaxVarintLen64]byte
+	return append(buffer, data[:binary.PutUvarint(data[:], value)]...)
+}
+
+func appendFloat32(buffer []byte, value float32) []byte {
+	var data [4]byte
+	binary.LittleEndian.PutUint32(data[:], math.Float32bits(value))
+	return append(buffer, data[:]...)
+}
+
+func appendFloat64(buffer []byte, value float64) []byte {
+	var data [8]byte
+	binary.LittleEndian.PutUint64(data[:], math.Float64bits(value))
+	return append(buffer, data[:]...)
+}
+
+func appendName(buffer []byte, kind uint64, name string) []byte {
+	buffer = appendVarint(buffer, uint64(len(name))<<2|kind)
+	return append(buffer, name...)
+}
@@ -0,0 +1,48 @@
+package tagged
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/tvanomr/inspect"
+)
+
+// countingReader keeps track of the number of bytes consumed from the input
+// and fails once more than the allowed total is consumed
+type countingReader struct {
+	reader *bufio.Reader
+	offset int64
+	limits *inspect.Limits
+}
+
+func (c *countingReader) reset(reader io.Reader) {
+	if c.reader == nil {
+		c.reader = bufio.NewReader(reader)
+	} else {
+		c.reader.Reset(reader)
+	}
+	c.offset = 0
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	if err := c.limits.CheckTotal(c.offset + 1); err != nil {
+		return 0, err
+	}
+	result, err := c.reader.ReadByte()
+	if err == nil {
+		c.offset++
+	}
+	return result, err
+}
+
+func (c *countingReader) Read(buffer []byte) (int, error) {
+	if max := c.limits.MaxTotalBytes; max > 0 && c.offset+int64(len(buffer)) > max {
+		if c.offset >= max {
+			return 0, c.limits.CheckTotal(c.offset + 1)
+		}
+		buffer = buffer[:max-c.offset]
+	}
+	read, err := c.reader.Read(buffer)
+	c.offset += int64(read)
+	return read, err
+}
@@ -0,0 +1,56 @@
+package tagged
+
+import "github.com/tvanomr/inspect"
+
+type taggedError int
+
+const (
+	ErrShortWrite taggedError = iota
+	ErrInvalidTag
+	ErrInvalidVarint
+	ErrInvalidKey
+	ErrUnknownName
+	ErrLengthMismatch
+	ErrNoProperty
+	ErrNotABool
+	ErrNotANumber
+	ErrNotAString
+	ErrNotBytes
+	ErrNotAnArray
+	ErrNotAMap
+	ErrNotAnObject
+	ErrObjectTooBig
+	ErrRawField
+)
+
+var errorMessages = map[taggedError]string{
+	ErrShortWrite:     "short write",
+	ErrInvalidTag:     "invalid type tag",
+	ErrInvalidVarint:  "varint overflows 64 bits",
+	ErrInvalidKey:     "invalid property key",
+	ErrUnknownName:    "property key refers to a name that wasn't defined",
+	ErrLengthMismatch: "value doesn't fill its length prefix",
+	ErrNoProperty:     "value read without selecting a property",
+	ErrNotABool:       "value is not a boolean",
+	ErrNotANumber:     "value is not a number",
+	ErrNotAString:     "value is not a string",
+	ErrNotBytes:       "value is neither bytes nor a string",
+	ErrNotAnArray:     "value is not an array",
+	ErrNotAMap:        "value is not a map",
+	ErrNotAnObject:    "value is not an object",
+	ErrObjectTooBig:   "object contains more fields than requested",
+	ErrRawField:       "fields collected from another format can't be written"}
+
+func (t taggedError) Error() string {
+	return errorMessages[t]
+}
+
+func (t taggedError) Is(target error) bool {
+	switch t {
+	case ErrNotABool, ErrNotANumber, ErrNotAString, ErrNotBytes, ErrNotAnArray, ErrNotAMap, ErrNotAnObject:
+		return target == inspect.ErrTypeMismatch
+	case ErrObjectTooBig:
+		return target == inspect.ErrUnknownField
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package tagged
+
+import "github.com/tvanomr/inspect"
+
+// Format is the tagged binary encoding for inspect.Marshal, inspect.Unmarshal, inspect.Encode and inspect.Decode
+var Format inspect.Format = inspect.BinaryFormat[Reader, *Reader, Writer, *Writer]{}
+
+// formatName tags the fields collected by Reader, Writer accepts only those
+const formatName = "tagged"
+
+func init() {
+	inspect.RegisterFormat(formatName, Format, "application/x-inspect-tagged")
+}
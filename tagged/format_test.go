@@ -0,0 +1,77 @@
+package tagged_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/json"
+	"github.com/tvanomr/inspect/tagged"
+)
+
+func TestRoundTrip(t *testing.T) {
+	value := inspecttest.Order{
+		ID:       3,
+		Customer: inspecttest.Wrapper{ID: 4, Flags: inspecttest.Flags{Enabled: true}},
+		Items:    []inspecttest.Item{{Name: "apple", Price: 1.5}, {Name: "pear", Price: 2}},
+		Notes:    []*inspecttest.Item{},
+		Tags:     map[string]inspecttest.IntValue{"a": 1},
+	}
+	data, err := inspect.Marshal(&value, tagged.Format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// names are written in full once
+	text, _ := inspect.Marshal(&value, json.Format)
+	if bytes.Count(data, []byte("price")) != 1 || len(data) >= len(text) {
+		t.Fatal("got", len(data), "bytes", data)
+	}
+	var result inspecttest.Order
+	if err := inspect.Unmarshal(data, &result, tagged.Format); err != nil || !result.Equal(&value) {
+		t.Fatal("got", result, err)
+	}
+
+	numbered := inspecttest.Shipment{ID: 150, Label: "box", Sizes: inspecttest.Numbers{3, -1},
+		Parts: []inspecttest.Part{{Name: "lid", Weight: 0.5}},
+		Stock: map[string]inspecttest.IntValue{"a": 1}, Prices: map[inspecttest.IntValue]inspecttest.Real{7: 1.25}}
+	data, err = inspect.Marshal(&numbered, tagged.Format)
+	if err != nil || bytes.Contains(data, []byte("label")) {
+		t.Fatal("got", data, err)
+	}
+	var parsed inspecttest.Shipment
+	if err := inspect.Unmarshal(data, &parsed, tagged.Format); err != nil || parsed.ID != 150 ||
+		parsed.Label != "box" || len(parsed.Sizes) != 2 || parsed.Parts[0] != numbered.Parts[0] || parsed.Prices[7] != 1.25 {
+		t.Fatal("got", parsed, err)
+	}
+	// a part has a string where a shipment has its id
+	data, _ = inspect.Marshal(&inspecttest.Part{Name: "lid", Weight: 0.5}, tagged.Format)
+	if err := inspect.Unmarshal(data, &parsed, tagged.Format); !errors.Is(err, inspect.ErrTypeMismatch) {
+		t.Fatal("got", err, "expected", inspect.ErrTypeMismatch)
+	}
+
+	// unknown fields are kept and written to another stream, missing ones are detected
+	old := inspecttest.RecordV1{Name: "a", Legacy: "b", Count: 7}
+	data, _ = inspect.Marshal(&old, tagged.Format)
+	var kept inspecttest.Extensible
+	if err := inspect.Unmarshal(data, &kept, tagged.Format); err != nil || len(kept.Unknown) != 2 {
+		t.Fatal("got", kept, err)
+	}
+	data, _ = inspect.Marshal(&kept, tagged.Format)
+	var restored inspecttest.RecordV1
+	if err := inspect.Unmarshal(data, &restored, tagged.Format); err != nil || restored != old {
+		t.Fatal("got", restored, err)
+	}
+	if err := inspect.Unmarshal(data, &result, tagged.Format); !errors.Is(err, inspect.ErrNoField) {
+		t.Fatal("got", err, "expected", inspect.ErrNoField)
+	}
+}
+
+func TestNested(t *testing.T) {
+	inspecttest.Nested(t, tagged.Format.NewWriter(), tagged.Format.NewReader())
+}
+
+func TestSkip(t *testing.T) {
+	inspecttest.Skip(t, tagged.Format.NewWriter(), tagged.Format.NewReader())
+}
@@ -0,0 +1,644 @@
+package tagged
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/tvanomr/inspect"
+)
+
+type contextKind byte
+
+const (
+	contextObject contextKind = iota
+	contextArray
+	contextMap
+)
+
+// field is a property of an object with the position of its value
+type field struct {
+	name string
+	// 0 for properties written with a name
+	number int
+	start  int
+	read   bool
+}
+
+type readContext struct {
+	kind   contextKind
+	fields []field
+	// a property value is selected in an object
+	selected bool
+	// end of the object
+	end int
+}
+
+// Reader reads one length prefixed value at a time and indexes
+// the properties of every object it enters, so they can come in any order
+type Reader struct {
+	reader countingReader
+	limits inspect.Limits
+	// the top level value being read and where it starts in the input
+	data   []byte
+	base   int64
+	loaded bool
+	// position of the next value in data and of the last one started for Offset
+	position int
+	offset   int64
+	// names defined so far in the input
+	names []string
+	// field number of the following property, 0 when there is none
+	number   int
+	current  *readContext
+	contexts stack[readContext]
+}
+
+func (r *Reader) SetReader(reader io.Reader) {
+	r.reader.reset(reader)
+	r.reader.limits = &r.limits
+	r.data = r.data[:0]
+	r.base = 0
+	r.loaded = false
+	r.position = 0
+	r.offset = 0
+	r.names = r.names[:0]
+	r.number = 0
+	r.current = nil
+	r.contexts = r.contexts[:0]
+}
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+func (r *Reader) FieldNumber(number int) {
+	r.number = number
+}
+
+// load reads the next top level value and the names it defines
+func (r *Reader) load() error {
+	length, err := binary.ReadUvarint(&r.reader)
+	if err != nil {
+		return err
+	}
+	if err := r.limits.CheckTotal(r.reader.offset + int64(length)); err != nil {
+		return err
+	}
+	r.base = r.reader.offset
+	if uint64(cap(r.data)) >= length {
+		r.data = r.data[:length]
+		if _, err := io.ReadFull(&r.reader, r.data); err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	} else if r.data, err = inspect.ReadFull(&r.reader, int64(length)); err != nil {
+		return err
+	}
+	r.position = 0
+	end, err := r.walk(0, nil, true, 1)
+	if err != nil {
+		return err
+	}
+	if end != len(r.data) {
+		r.offset = r.base + int64(end)
+		return ErrLengthMismatch
+	}
+	r.loaded = true
+	return nil
+}
+
+// varint reads a varint at position, next is the position after it
+func (r *Reader) varint(position int) (value uint64, next int, err error) {
+	value, length := binary.Uvarint(r.data[position:])
+	if length == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	if length < 0 {
+		r.offset = r.base + int64(position)
+		return 0, 0, ErrInvalidVarint
+	}
+	return value, position + length, nil
+}
+
+// length reads a length and checks that as many bytes follow,
+// counts are checked the same way as every item takes at least a byte
+func (r *Reader) length(position int, check func(length int64) error) (length int, next int, err error) {
+	value, next, err := r.varint(position)
+	if err != nil {
+		return 0, 0, err
+	}
+	if value > uint64(len(r.data)-next) {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	return int(value), next, check(int64(value))
+}
+
+// key reads a property key, names are added to the table when define is set,
+// number is 0 for keys with a name
+func (r *Reader) key(position int, define bool) (name string, number int, next int, err error) {
+	value, next, err := r.varint(position)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	switch value & 3 {
+	case keyNumber:
+		if value>>2 == 0 || value>>2 > math.MaxInt32 {
+			return "", 0, 0, ErrInvalidKey
+		}
+		return "", int(value >> 2), next, nil
+	case keyReference:
+		if value>>2 >= uint64(len(r.names)) {
+			r.offset = r.base + int64(position)
+			return "", 0, 0, ErrUnknownName
+		}
+		return r.names[value>>2], 0, next, nil
+	}
+	if value>>2 > uint64(len(r.data)-next) {
+		return "", 0, 0, io.ErrUnexpectedEOF
+	}
+	end := next + int(value>>2)
+	if err := r.limits.CheckString(int64(end - next)); err != nil {
+		return "", 0, 0, err
+	}
+	name = string(r.data[next:end])
+	if define && value&3 == keyDefinition {
+		r.names = append(r.names, name)
+	}
+	return name, 0, end, nil
+}
+
+// walk finds the end of the value at position, it copies the value to out when it is set
+// with every property name written in full, so the copy doesn't depend on the name table
+func (r *Reader) walk(position int, out *[]byte, define bool, depth int) (int, error) {
+	if position >= len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	start := position
+	tag := r.data[position]
+	position++
+	var err error
+	var count int
+	switch tag {
+	case tagNull, tagFalse, tagTrue:
+	case tagInt, tagUint:
+		_, position, err = r.varint(position)
+	case tagFloat32, tagFloat64:
+		size := 4
+		if tag == tagFloat64 {
+			size = 8
+		}
+		if position+size > len(r.data) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		position += size
+	case tagString, tagBytes:
+		count, position, err = r.length(position, r.limits.CheckString)
+		position += count
+	case tagArray, tagMap:
+		if err := r.limits.CheckDepth(depth); err != nil {
+			return 0, err
+		}
+		if count, position, err = r.length(position, r.limits.CheckArray); err != nil {
+			return 0, err
+		}
+		if out != nil {
+			*out = append(*out, r.data[start:position]...)
+		}
+		for i := 0; i < count; i++ {
+			if tag == tagMap {
+				var length int
+				start = position
+				if length, position, err = r.length(position, r.limits.CheckString); err != nil {
+					return 0, err
+				}
+				position += length
+				if out != nil {
+					*out = append(*out, r.data[start:position]...)
+				}
+			}
+			if position, err = r.walk(position, out, define, depth+1); err != nil {
+				return 0, err
+			}
+		}
+		return position, nil
+	case tagObject:
+		if err := r.limits.CheckDepth(depth); err != nil {
+			return 0, err
+		}
+		if out != nil {
+			*out = append(*out, tagObject)
+		}
+		for {
+			if position >= len(r.data) {
+				return 0, io.ErrUnexpectedEOF
+			}
+			if r.data[position] == keyEnd {
+				if out != nil {
+					*out = append(*out, keyEnd)
+				}
+				return position + 1, nil
+			}
+			name, number, next, err := r.key(position, define)
+			if err != nil {
+				return 0, err
+			}
+			if out != nil {
+				if number == 0 {
+					*out = appendName(*out, keyLiteral, name)
+				} else {
+					*out = append(*out, r.data[position:next]...)
+				}
+			}
+			if position, err = r.walk(next, out, define, depth+1); err != nil {
+				return 0, err
+			}
+		}
+	default:
+		r.offset = r.base + int64(start)
+		return 0, ErrInvalidTag
+	}
+	if err != nil {
+		return 0, err
+	}
+	if out != nil {
+		*out = append(*out, r.data[start:position]...)
+	}
+	return position, nil
+}
+
+// peek returns the position and the tag of the next value without consuming it,
+// at the top level the next value is loaded from the input
+func (r *Reader) peek() (int, byte, error) {
+	if r.current == nil {
+		if !r.loaded {
+			if err := r.load(); err != nil {
+				return 0, 0, err
+			}
+		}
+	} else if r.current.kind == contextObject && !r.current.selected {
+		return 0, 0, ErrNoProperty
+	}
+	if r.position >= len(r.data) {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	r.offset = r.base + int64(r.position)
+	return r.position, r.data[r.position], nil
+}
+
+// advance consumes the value peek returned, next is the position after it
+func (r *Reader) advance(next int) {
+	r.position = next
+	if r.current == nil {
+		r.loaded = false
+	} else if r.current.kind == contextObject {
+		r.current.selected = false
+	}
+}
+
+func (r *Reader) Bool() (bool, error) {
+	position, tag, err := r.peek()
+	if err != nil {
+		return false, err
+	}
+	if tag != tagFalse && tag != tagTrue {
+		return false, ErrNotABool
+	}
+	r.advance(position + 1)
+	return tag == tagTrue, nil
+}
+
+// readInt reads an int or a uint, negative is set for the former
+func (r *Reader) readInt() (value uint64, negative bool, err error) {
+	position, tag, err := r.peek()
+	if err != nil {
+		return 0, false, err
+	}
+	if tag != tagInt && tag != tagUint {
+		return 0, false, ErrNotANumber
+	}
+	value, next, err := r.varint(position + 1)
+	if err != nil {
+		return 0, false, err
+	}
+	r.advance(next)
+	if tag == tagUint {
+		return value, false, nil
+	}
+	signed := int64(value>>1) ^ -int64(value&1)
+	if signed < 0 {
+		return uint64(signed), true, nil
+	}
+	return uint64(signed), false, nil
+}
+func (r *Reader) Int8() (int8, error) {
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	return readNarrowInt[int16](r)
+}
+func (r *Reader) Int32() (int32, error) {
+	return readNarrowInt[int32](r)
+}
+func (r *Reader) Int64() (int64, error) {
+	value, negative, err := r.readInt()
+	if err != nil {
+		return 0, err
+	}
+	if !negative && value > math.MaxInt64 {
+		return 0, &inspect.OverflowError{Type: "int64", Value: strconv.FormatUint(value, 10)}
+	}
+	return int64(value), nil
+}
+func (r *Reader) Uint8() (uint8, error) {
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	value, negative, err := r.readInt()
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		return 0, &inspect.OverflowError{Type: "uint64", Value: strconv.FormatInt(int64(value), 10)}
+	}
+	return value, nil
+}
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := r.Int64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](result)
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := r.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
+
+// readFloat accepts floats of both sizes and integers
+func (r *Reader) readFloat() (float64, error) {
+	position, tag, err := r.peek()
+	if err != nil {
+		return 0, err
+	}
+	switch tag {
+	case tagFloat32:
+		if position+5 > len(r.data) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		r.advance(position + 5)
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(r.data[position+1:]))), nil
+	case tagFloat64:
+		if position+9 > len(r.data) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		r.advance(position + 9)
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.data[position+1:])), nil
+	case tagInt, tagUint:
+		value, negative, err := r.readInt()
+		if negative {
+			return float64(int64(value)), err
+		}
+		return float64(value), err
+	}
+	return 0, ErrNotANumber
+}
+func (r *Reader) Float32() (float32, error) {
+	result, err := r.readFloat()
+	if err == nil && !math.IsInf(result, 0) && math.Abs(result) > math.MaxFloat32 {
+		return 0, &inspect.OverflowError{Type: "float32", Value: strconv.FormatFloat(result, 'g', -1, 64)}
+	}
+	return float32(result), err
+}
+func (r *Reader) Float64() (float64, error) {
+	return r.readFloat()
+}
+
+// readString reads a string, or bytes when allowBytes is set
+func (r *Reader) readString(allowBytes bool, mismatch error) ([]byte, error) {
+	position, tag, err := r.peek()
+	if err != nil {
+		return nil, err
+	}
+	if tag != tagString && (tag != tagBytes || !allowBytes) {
+		return nil, mismatch
+	}
+	length, start, err := r.length(position+1, r.limits.CheckString)
+	if err != nil {
+		return nil, err
+	}
+	r.advance(start + length)
+	return r.data[start : start+length], nil
+}
+func (r *Reader) String() (string, error) {
+	result, err := r.readString(false, ErrNotAString)
+	return string(result), err
+}
+func (r *Reader) Bytes() ([]byte, error) {
+	result, err := r.readString(true, ErrNotBytes)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), result...), nil
+}
+func (r *Reader) ByteString() ([]byte, error) {
+	return r.Bytes()
+}
+func (r *Reader) IsNull() (bool, error) {
+	position, tag, err := r.peek()
+	if err != nil || tag != tagNull {
+		return false, err
+	}
+	r.advance(position + 1)
+	return true, nil
+}
+
+func (r *Reader) push(value readContext) error {
+	r.contexts.push(value)
+	r.current = &r.contexts[len(r.contexts)-1]
+	return r.limits.CheckDepth(len(r.contexts))
+}
+
+// pop leaves an object, an array or a map, next is the position after it
+func (r *Reader) pop(next int) {
+	r.contexts.pop()
+	if len(r.contexts) == 0 {
+		r.current = nil
+	} else {
+		r.current = &r.contexts[len(r.contexts)-1]
+	}
+	r.advance(next)
+}
+
+// StartObject indexes the properties of the object
+func (r *Reader) StartObject() error {
+	position, tag, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if tag != tagObject {
+		return ErrNotAnObject
+	}
+	object := readContext{kind: contextObject}
+	position++
+	for r.data[position] != keyEnd {
+		name, number, next, err := r.key(position, false)
+		if err != nil {
+			return err
+		}
+		object.fields = append(object.fields, field{name: name, number: number, start: next})
+		if position, err = r.walk(next, nil, false, len(r.contexts)+2); err != nil {
+			return err
+		}
+	}
+	object.end = position + 1
+	r.number = 0
+	return r.push(object)
+}
+
+// Property selects the field with the number given to FieldNumber, or else with the name
+func (r *Reader) Property(name string) error {
+	present, err := r.OptionalProperty(name)
+	if err == nil && !present {
+		return inspect.ErrNoField
+	}
+	return err
+}
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	number := r.number
+	r.number = 0
+	if r.current == nil || r.current.kind != contextObject {
+		return false, ErrNoProperty
+	}
+	r.current.selected = false
+	for i := range r.current.fields {
+		field := &r.current.fields[i]
+		if field.read || number > 0 && field.number != number || number == 0 && (field.number != 0 || field.name != name) {
+			continue
+		}
+		field.read = true
+		r.current.selected = true
+		r.position = field.start
+		r.offset = r.base + int64(field.start)
+		return true, nil
+	}
+	return false, nil
+}
+
+// UnknownFields returns the fields not asked for, fields with a number are named by it
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if r.current == nil || r.current.kind != contextObject {
+		return nil, ErrNoProperty
+	}
+	var result []inspect.RawField
+	for i := range r.current.fields {
+		field := &r.current.fields[i]
+		if field.read {
+			continue
+		}
+		if policy == inspect.FailOnUnknown {
+			r.offset = r.base + int64(field.start)
+			return nil, ErrObjectTooBig
+		}
+		field.read = true
+		if policy == inspect.CollectUnknown {
+			name := field.name
+			if field.number > 0 {
+				name = strconv.Itoa(field.number)
+			}
+			var value []byte
+			if _, err := r.walk(field.start, &value, false, len(r.contexts)+1); err != nil {
+				return nil, err
+			}
+			result = append(result, inspect.RawField{Format: formatName, Name: name, Value: value})
+		}
+	}
+	return result, nil
+}
+func (r *Reader) EndObject() error {
+	if r.current == nil || r.current.kind != contextObject {
+		return ErrNoProperty
+	}
+	r.pop(r.current.end)
+	return nil
+}
+
+// start reads the count of an array or a map, an empty one needs no end call
+func (r *Reader) start(expected byte, kind contextKind, mismatch error) (int, error) {
+	position, tag, err := r.peek()
+	if err != nil {
+		return 0, err
+	}
+	if tag != expected {
+		return 0, mismatch
+	}
+	length, next, err := r.length(position+1, r.limits.CheckArray)
+	if err != nil {
+		return 0, err
+	}
+	if length == 0 {
+		r.advance(next)
+		return 0, nil
+	}
+	r.position = next
+	return length, r.push(readContext{kind: kind})
+}
+func (r *Reader) StartArray() (length int, err error) {
+	return r.start(tagArray, contextArray, ErrNotAnArray)
+}
+func (r *Reader) HaveNext() (bool, error) {
+	return false, nil
+}
+func (r *Reader) EndArray() error {
+	r.pop(r.position)
+	return nil
+}
+func (r *Reader) StartMap() (length int, err error) {
+	return r.start(tagMap, contextMap, ErrNotAMap)
+}
+func (r *Reader) NextKey() (string, error) {
+	length, start, err := r.length(r.position, r.limits.CheckString)
+	if err != nil {
+		return "", err
+	}
+	r.position = start + length
+	return string(r.data[start:r.position]), nil
+}
+func (r *Reader) EndMap() error {
+	r.pop(r.position)
+	return nil
+}
+
+// Skip discards the next value, which is possible anywhere as values carry their type
+func (r *Reader) Skip() error {
+	position, _, err := r.peek()
+	if err != nil {
+		return err
+	}
+	next, err := r.walk(position, nil, false, len(r.contexts)+1)
+	if err != nil {
+		return err
+	}
+	r.advance(next)
+	return nil
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+	var _ inspect.FieldNumbered = (*Reader)(nil)
+}
@@ -0,0 +1,68 @@
+package tagged_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/tagged"
+)
+
+type items []inspecttest.Item
+
+func (i *items) Inspect(inspector *inspect.Inspector) {
+	inspect.Array[*inspecttest.Item]((*[]inspecttest.Item)(i), inspector, "items", "item", "items")
+}
+
+// encoded is items{{"a", 1}, {"b", 2}}, the second object refers to the names defined by the first
+var encoded = []byte{43, 9, 2,
+	11, 0x12, 'n', 'a', 'm', 'e', 7, 1, 'a', 0x16, 'p', 'r', 'i', 'c', 'e', 6, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0,
+	11, 0x01, 7, 1, 'b', 0x05, 6, 0, 0, 0, 0, 0, 0, 0, 0x40, 0}
+
+func TestNameReferences(t *testing.T) {
+	value := items{{Name: "a", Price: 1}, {Name: "b", Price: 2}}
+	data, err := inspect.Marshal(&value, tagged.Format)
+	if err != nil || !bytes.Equal(data, encoded) {
+		t.Fatal("got", data, err)
+	}
+	var result items
+	if err := inspect.Unmarshal(encoded, &result, tagged.Format); err != nil || len(result) != 2 ||
+		result[0] != value[0] || result[1] != value[1] {
+		t.Fatal("got", result, err)
+	}
+	// a reference to a name that wasn't defined yet
+	input := append([]byte(nil), encoded...)
+	input[29] = 0x09
+	if err := inspect.Unmarshal(input, &result, tagged.Format); !errors.Is(err, tagged.ErrUnknownName) {
+		t.Fatal("got", err)
+	}
+}
+
+func TestHostileLength(t *testing.T) {
+	// a value claiming to be a terabyte long
+	input := []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x20, 9, 0}
+	var value items
+	if err := inspect.Unmarshal(input, &value, tagged.Format); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("got", err)
+	}
+	// a string claiming to be longer than its value
+	input = []byte{6, 9, 1, 11, 0x12, 'n', 0x80, 0x01}
+	if err := inspect.Unmarshal(input, &value, tagged.Format); err == nil {
+		t.Fatal("got", value)
+	}
+	err := inspect.Unmarshal(encoded, &value, tagged.Format, inspect.WithLimits(inspect.Limits{MaxTotalBytes: 16}))
+	if !errors.Is(err, inspect.ErrLimitExceeded) {
+		t.Fatal("got", err)
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add(encoded)
+	f.Add([]byte{3, 9, 1, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inspecttest.Fuzz(data, tagged.Format, new(items), new(inspecttest.Order))
+	})
+}
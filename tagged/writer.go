@@ -0,0 +1,255 @@
+package tagged
+
+import (
+	"io"
+
+	"github.com/tvanomr/inspect"
+)
+
+// Writer writes every top level value prefixed with its length, values carry a type tag
+// and properties a field number or a name, which is written in full only the first time
+type Writer struct {
+	writer     io.Writer
+	bufferSize int
+	buffer     []byte
+	names      map[string]int
+	// number of objects, arrays and maps open and where the top level value starts
+	depth      int
+	valueStart int
+	// field number of the following property, 0 when there is none
+	number int
+}
+
+func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
+	w.writer = writer
+	w.bufferSize = bufferSize
+	w.buffer = w.buffer[:0]
+	w.names = map[string]int{}
+	w.depth = 0
+	w.number = 0
+}
+func (w *Writer) FieldNumber(number int) {
+	w.number = number
+}
+
+// begin remembers where a top level value starts
+func (w *Writer) begin() {
+	if w.depth == 0 {
+		w.valueStart = len(w.buffer)
+	}
+}
+
+// end puts the length before a complete top level value
+func (w *Writer) end() error {
+	if w.depth > 0 {
+		return nil
+	}
+	var data [10]byte
+	prefix := appendVarint(data[:0], uint64(len(w.buffer)-w.valueStart))
+	w.buffer = append(w.buffer, prefix...)
+	copy(w.buffer[w.valueStart+len(prefix):], w.buffer[w.valueStart:len(w.buffer)-len(prefix)])
+	copy(w.buffer[w.valueStart:], prefix)
+	if len(w.buffer) > w.bufferSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// value writes a whole value of the given tag, payload may be nil
+func (w *Writer) value(tag byte, payload []byte) error {
+	w.begin()
+	w.buffer = append(w.buffer, tag)
+	w.buffer = append(w.buffer, payload...)
+	return w.end()
+}
+
+func (w *Writer) integer(value int64) error {
+	w.begin()
+	w.buffer = append(w.buffer, tagInt)
+	w.buffer = appendVarint(w.buffer, uint64(value<<1^value>>63))
+	return w.end()
+}
+
+func (w *Writer) unsigned(value uint64) error {
+	w.begin()
+	w.buffer = append(w.buffer, tagUint)
+	w.buffer = appendVarint(w.buffer, value)
+	return w.end()
+}
+
+func (w *Writer) Bool(value bool) error {
+	if value {
+		return w.value(tagTrue, nil)
+	}
+	return w.value(tagFalse, nil)
+}
+func (w *Writer) Int8(value int8) error {
+	return w.integer(int64(value))
+}
+func (w *Writer) Int16(value int16) error {
+	return w.integer(int64(value))
+}
+func (w *Writer) Int32(value int32) error {
+	return w.integer(int64(value))
+}
+func (w *Writer) Int64(value int64) error {
+	return w.integer(value)
+}
+func (w *Writer) Uint8(value uint8) error {
+	return w.unsigned(uint64(value))
+}
+func (w *Writer) Uint16(value uint16) error {
+	return w.unsigned(uint64(value))
+}
+func (w *Writer) Uint32(value uint32) error {
+	return w.unsigned(uint64(value))
+}
+func (w *Writer) Uint64(value uint64) error {
+	return w.unsigned(value)
+}
+func (w *Writer) Float32(value float32, format byte, precision int) error {
+	w.begin()
+	w.buffer = appendFloat32(append(w.buffer, tagFloat32), value)
+	return w.end()
+}
+func (w *Writer) Float64(value float64, format byte, precision int) error {
+	w.begin()
+	w.buffer = appendFloat64(append(w.buffer, tagFloat64), value)
+	return w.end()
+}
+func (w *Writer) String(value string) error {
+	w.begin()
+	w.buffer = appendVarint(append(w.buffer, tagString), uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+	return w.end()
+}
+func (w *Writer) Bytes(value []byte) error {
+	w.begin()
+	w.buffer = appendVarint(append(w.buffer, tagBytes), uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+	return w.end()
+}
+func (w *Writer) ByteString(value []byte) error {
+	w.begin()
+	w.buffer = appendVarint(append(w.buffer, tagString), uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+	return w.end()
+}
+func (w *Writer) Null() error {
+	return w.value(tagNull, nil)
+}
+
+func (w *Writer) Skip() error {
+	return w.Null()
+}
+func (w *Writer) NotNull() error {
+	return nil
+}
+
+// start opens an object, an array or a map
+func (w *Writer) start(tag byte) {
+	w.begin()
+	w.buffer = append(w.buffer, tag)
+	w.depth++
+}
+
+// finish closes an object, an array or a map
+func (w *Writer) finish() error {
+	w.depth--
+	return w.end()
+}
+func (w *Writer) StartObject() error {
+	w.start(tagObject)
+	return nil
+}
+
+// Property writes the field number when there is one, otherwise the name,
+// a name written before is referred to by its index
+func (w *Writer) Property(name string) error {
+	if w.number > 0 {
+		w.buffer = appendVarint(w.buffer, uint64(w.number)<<2|keyNumber)
+		w.number = 0
+		return nil
+	}
+	if index, ok := w.names[name]; ok {
+		w.buffer = appendVarint(w.buffer, uint64(index)<<2|keyReference)
+		return nil
+	}
+	if len(w.names) >= maxNames {
+		w.buffer = appendName(w.buffer, keyLiteral, name)
+		return nil
+	}
+	w.names[name] = len(w.names)
+	w.buffer = appendName(w.buffer, keyDefinition, name)
+	return nil
+}
+
+// OptionalProperty writes nothing for an absent property
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	if !present {
+		w.number = 0
+		return nil
+	}
+	return w.Property(name)
+}
+
+// RawProperty writes a field collected by a tagged reader
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	if field.Format != formatName {
+		return ErrRawField
+	}
+	if err := w.Property(field.Name); err != nil {
+		return err
+	}
+	w.buffer = append(w.buffer, field.Value...)
+	return nil
+}
+func (w *Writer) EndObject() error {
+	w.buffer = appendVarint(w.buffer, keyEnd)
+	return w.finish()
+}
+func (w *Writer) StartArray(length int) error {
+	w.start(tagArray)
+	w.buffer = appendVarint(w.buffer, uint64(length))
+	return nil
+}
+func (w *Writer) EndArray() error {
+	return w.finish()
+}
+func (w *Writer) StartMap(length int) error {
+	w.start(tagMap)
+	w.buffer = appendVarint(w.buffer, uint64(length))
+	return nil
+}
+func (w *Writer) NextKey(key string) error {
+	w.buffer = appendVarint(w.buffer, uint64(len(key)))
+	w.buffer = append(w.buffer, key...)
+	return nil
+}
+func (w *Writer) EndMap() error {
+	return w.finish()
+}
+func (w *Writer) SortKeys() bool {
+	return false
+}
+
+// Flush writes out complete top level values, an open one stays in the buffer
+func (w *Writer) Flush() error {
+	if w.depth > 0 || len(w.buffer) == 0 {
+		return nil
+	}
+	written, err := w.writer.Write(w.buffer)
+	if err != nil {
+		return err
+	}
+	if written < len(w.buffer) {
+		return ErrShortWrite
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func init() {
+	var _ inspect.Writer = (*Writer)(nil)
+	var _ inspect.FieldNumbered = (*Writer)(nil)
+}
@@ -0,0 +1,58 @@
+package toml
+
+import "github.com/tvanomr/inspect"
+
+type tomlError int
+
+const (
+	ErrShortWrite tomlError = iota
+	ErrNotATable
+	ErrNoKey
+	ErrNullInArray
+	ErrIntegerRange
+	ErrSyntax
+	ErrUnterminated
+	ErrDuplicateKey
+	ErrNotAScalar
+	ErrNotABool
+	ErrNotANumber
+	ErrNotAString
+	ErrInvalidBytes
+	ErrNotAnArray
+	ErrObjectTooBig
+	ErrNoProperty
+	ErrRawField
+)
+
+var errorMessages = map[tomlError]string{
+	ErrShortWrite:   "short write",
+	ErrNotATable:    "not a table, toml documents and sub-tables are tables",
+	ErrNoKey:        "value inside a table without a key",
+	ErrNullInArray:  "toml has no null, it can be left out only as a value of a key",
+	ErrIntegerRange: "toml integers are signed 64 bit",
+	ErrSyntax:       "syntax error",
+	ErrUnterminated: "unterminated string, array or inline table",
+	ErrDuplicateKey: "key defined more than once",
+	ErrNotAScalar:   "not a scalar",
+	ErrNotABool:     "not a boolean",
+	ErrNotANumber:   "not a number",
+	ErrNotAString:   "not a string",
+	ErrInvalidBytes: "not base64",
+	ErrNotAnArray:   "not an array",
+	ErrObjectTooBig: "table contains more keys than requested",
+	ErrNoProperty:   "value read without selecting a property",
+	ErrRawField:     "fields collected from another format can't be written"}
+
+func (t tomlError) Error() string {
+	return errorMessages[t]
+}
+
+func (t tomlError) Is(target error) bool {
+	switch t {
+	case ErrNotATable, ErrNotAScalar, ErrNotABool, ErrNotANumber, ErrNotAString, ErrInvalidBytes, ErrNotAnArray:
+		return target == inspect.ErrTypeMismatch
+	case ErrObjectTooBig:
+		return target == inspect.ErrUnknownField
+	}
+	return false
+}
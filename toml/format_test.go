@@ -0,0 +1,111 @@
+package toml_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/toml"
+)
+
+func TestRoundTrip(t *testing.T) {
+	writeInspector := new(inspect.TextWriteInspector[toml.Writer, *toml.Writer])
+	writeInspector.Writer().SetComments(true)
+	writer := inspect.NewInspector(writeInspector)
+	value := inspecttest.Order{
+		ID:       3,
+		Customer: inspecttest.Wrapper{ID: 4, Flags: inspecttest.Flags{Enabled: true}},
+		Items:    []inspecttest.Item{{Name: "a \"b\"", Price: 1.5}, {Name: "c", Price: 2}},
+		Notes:    []*inspecttest.Item{},
+		Tags:     map[string]inspecttest.IntValue{"a b": 1},
+	}
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	expected := `# order with nested values
+# order id
+id = 3
+# order notes
+notes = []
+
+# customer
+[customer]
+# identifier
+id = 4
+
+# nested flags
+[customer.flags]
+# enabled flag
+enabled = true
+# visible flag
+visible = false
+
+# order items
+[[items]]
+# item name
+name = "a \"b\""
+# item price
+price = 1.5
+
+[[items]]
+# item name
+name = "c"
+# item price
+price = 2.0
+
+# order tags
+[tags]
+"a b" = 1
+`
+	if writer.LastError() != nil || buffer.String() != expected {
+		t.Fatal("got", buffer.String(), writer.LastError())
+	}
+
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[toml.Reader, *toml.Reader]))
+	reader.SetReader(&buffer)
+	var result inspecttest.Order
+	result.Inspect(reader)
+	if reader.LastError() != nil || !result.Equal(&value) {
+		t.Fatal("got", result, reader.LastError())
+	}
+
+	input := `# dotted keys, inline tables and multi-line strings
+id = 0x10
+customer = { id = 4, flags.enabled = true, flags.visible = false }
+notes = [
+  # trailing comma
+]
+
+[[items]]
+name = """
+two \
+  lines"""
+price = 5e-1
+
+[[items]]
+name = 'C:\path'
+price = 10
+
+[tags]
+a.b = 2
+`
+	result = inspecttest.Order{}
+	err := inspect.Unmarshal([]byte(input), &result, toml.Format)
+	if !errors.Is(err, inspect.ErrTypeMismatch) {
+		t.Fatal("got", err, "expected", inspect.ErrTypeMismatch)
+	}
+	input = strings.Replace(input, "a.b", `"a.b"`, 1)
+	if err := inspect.Unmarshal([]byte(input), &result, toml.Format); err != nil || result.ID != 16 ||
+		result.Customer.ID != 4 || !result.Customer.Flags.Enabled || len(result.Items) != 2 ||
+		result.Items[0] != (inspecttest.Item{Name: "two lines", Price: 0.5}) ||
+		result.Items[1] != (inspecttest.Item{Name: `C:\path`, Price: 10}) || result.Tags["a.b"] != 2 {
+		t.Fatal("got", result, err)
+	}
+	if err := inspect.Unmarshal([]byte("id = 1\nid = 2\n"), &result, toml.Format); !errors.Is(err, toml.ErrDuplicateKey) {
+		t.Fatal("got", err, "expected", toml.ErrDuplicateKey)
+	}
+}
@@ -0,0 +1,516 @@
+package toml
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tvanomr/inspect"
+)
+
+type nodeKind byte
+
+const (
+	stringNode nodeKind = iota
+	integerNode
+	floatNode
+	boolNode
+	// dates and times are kept as text
+	datetimeNode
+	tableNode
+	arrayNode
+)
+
+type entry struct {
+	key   string
+	value *node
+}
+
+type node struct {
+	kind nodeKind
+	// decoded strings, other scalars as they are in the input
+	text    string
+	entries []entry
+	items   []*node
+	start   int
+	// inline tables and arrays can't be extended, tables can be defined only once
+	closed   bool
+	defined  bool
+	implicit bool
+	// arrays created by [[array]] headers
+	tableArray bool
+}
+
+func (n *node) lookup(key string) *node {
+	for _, item := range n.entries {
+		if item.key == key {
+			return item.value
+		}
+	}
+	return nil
+}
+
+type parser struct {
+	data   []byte
+	pos    int
+	limits *inspect.Limits
+	depth  int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *parser) endOfLine() bool {
+	return p.eof() || p.data[p.pos] == '\n' || p.data[p.pos] == '\r' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '\n'
+}
+
+// skipSpace skips blanks and a comment on the line
+func (p *parser) skipSpace() {
+	for !p.eof() && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+		p.pos++
+	}
+	if !p.eof() && p.data[p.pos] == '#' {
+		for !p.endOfLine() {
+			p.pos++
+		}
+	}
+}
+
+// skipLines skips blanks, comments and line breaks
+func (p *parser) skipLines() {
+	for {
+		p.skipSpace()
+		if p.eof() || !p.endOfLine() {
+			return
+		}
+		if p.data[p.pos] == '\r' {
+			p.pos++
+		}
+		p.pos++
+	}
+}
+
+// lineEnd expects nothing but a comment up to the line break
+func (p *parser) lineEnd() error {
+	p.skipSpace()
+	if !p.endOfLine() {
+		return ErrSyntax
+	}
+	return nil
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	return p.limits.CheckDepth(p.depth)
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parse() (*node, error) {
+	root := &node{kind: tableNode, defined: true}
+	table := root
+	for {
+		p.skipLines()
+		if p.eof() {
+			return root, nil
+		}
+		var err error
+		if p.data[p.pos] == '[' {
+			table, err = p.header(root)
+		} else {
+			err = p.keyValue(table)
+		}
+		if err == nil {
+			err = p.lineEnd()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// header finds or creates the table of a [table] or [[array]] header
+func (p *parser) header(root *node) (*node, error) {
+	start := p.pos
+	array := bytes.HasPrefix(p.data[p.pos:], []byte("[["))
+	p.pos++
+	if array {
+		p.pos++
+	}
+	p.skipSpace()
+	keys, err := p.keys()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !bytes.HasPrefix(p.data[p.pos:], []byte(closing)) {
+		return nil, ErrSyntax
+	}
+	p.pos += len(closing)
+	if err := p.limits.CheckDepth(len(keys)); err != nil {
+		return nil, err
+	}
+	table := root
+	for _, key := range keys[:len(keys)-1] {
+		if table, err = descend(table, key); err != nil {
+			return nil, err
+		}
+	}
+	key := keys[len(keys)-1]
+	existing := table.lookup(key)
+	if array {
+		if existing == nil {
+			existing = &node{kind: arrayNode, tableArray: true, start: start}
+			table.entries = append(table.entries, entry{key: key, value: existing})
+		} else if !existing.tableArray {
+			return nil, ErrDuplicateKey
+		}
+		item := &node{kind: tableNode, defined: true, start: start}
+		existing.items = append(existing.items, item)
+		return item, nil
+	}
+	if existing == nil {
+		existing = &node{kind: tableNode, start: start}
+		table.entries = append(table.entries, entry{key: key, value: existing})
+	} else if existing.kind != tableNode || existing.defined || existing.closed {
+		return nil, ErrDuplicateKey
+	}
+	existing.defined = true
+	existing.start = start
+	return existing, nil
+}
+
+// descend finds or creates a table on the way to a header or a dotted key,
+// an array of tables gives its last table
+func descend(table *node, key string) (*node, error) {
+	next := table.lookup(key)
+	switch {
+	case next == nil:
+		next = &node{kind: tableNode, implicit: true, start: table.start}
+		table.entries = append(table.entries, entry{key: key, value: next})
+		return next, nil
+	case next.kind == tableNode && !next.closed:
+		return next, nil
+	case next.tableArray:
+		return next.items[len(next.items)-1], nil
+	}
+	return nil, ErrDuplicateKey
+}
+
+// keys reads a dotted key
+func (p *parser) keys() ([]string, error) {
+	var result []string
+	for {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, key)
+		p.skipSpace()
+		if p.eof() || p.data[p.pos] != '.' {
+			return result, nil
+		}
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+func (p *parser) key() (string, error) {
+	if p.eof() {
+		return "", ErrSyntax
+	}
+	switch p.data[p.pos] {
+	case '"':
+		return p.basicString()
+	case '\'':
+		return p.literalString()
+	}
+	start := p.pos
+	for !p.eof() && isBareKey(string(p.data[p.pos:p.pos+1])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", ErrSyntax
+	}
+	return string(p.data[start:p.pos]), nil
+}
+
+func (p *parser) keyValue(table *node) error {
+	keys, err := p.keys()
+	if err != nil {
+		return err
+	}
+	if p.eof() || p.data[p.pos] != '=' {
+		return ErrSyntax
+	}
+	p.pos++
+	p.skipSpace()
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys[:len(keys)-1] {
+		if table, err = descend(table, key); err != nil {
+			return err
+		}
+		// a table made by dotted keys can't get a header later
+		table.defined = true
+	}
+	key := keys[len(keys)-1]
+	if table.lookup(key) != nil {
+		return ErrDuplicateKey
+	}
+	table.entries = append(table.entries, entry{key: key, value: value})
+	return nil
+}
+
+func (p *parser) value() (*node, error) {
+	if p.eof() {
+		return nil, ErrSyntax
+	}
+	start := p.pos
+	switch p.data[p.pos] {
+	case '"', '\'':
+		var text string
+		var err error
+		switch {
+		case bytes.HasPrefix(p.data[p.pos:], []byte(`"""`)):
+			text, err = p.multilineString('"')
+		case bytes.HasPrefix(p.data[p.pos:], []byte(`'''`)):
+			text, err = p.multilineString('\'')
+		case p.data[p.pos] == '"':
+			text, err = p.basicString()
+		default:
+			text, err = p.literalString()
+		}
+		return &node{kind: stringNode, text: text, start: start}, err
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	}
+	end := p.pos
+	for end < len(p.data) && strings.IndexByte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_+-.:", p.data[end]) >= 0 {
+		end++
+	}
+	// a date and a time separated by a space
+	if end-p.pos == 10 && p.data[p.pos+4] == '-' && end+1 < len(p.data) && p.data[end] == ' ' &&
+		p.data[end+1] >= '0' && p.data[end+1] <= '9' {
+		end++
+		for end < len(p.data) && strings.IndexByte("0123456789+-.:zZ", p.data[end]) >= 0 {
+			end++
+		}
+	}
+	text := string(p.data[p.pos:end])
+	p.pos = end
+	result := &node{text: text, start: start}
+	switch {
+	case text == "true" || text == "false":
+		result.kind = boolNode
+	case len(text) >= 8 && (text[2] == ':' || len(text) >= 10 && text[4] == '-' && text[7] == '-'):
+		result.kind = datetimeNode
+	default:
+		number := strings.ReplaceAll(text, "_", "")
+		if _, err := parseInteger(number); err == nil || isRange(err) || err == ErrIntegerRange {
+			result.kind = integerNode
+		} else if _, err := parseFloat(number); err == nil || isRange(err) {
+			result.kind = floatNode
+		} else {
+			return nil, ErrSyntax
+		}
+	}
+	return result, nil
+}
+
+// skipArray skips blanks, comments and line breaks inside arrays and inline tables
+func (p *parser) skipArray() error {
+	p.skipLines()
+	if p.eof() {
+		return ErrUnterminated
+	}
+	return nil
+}
+
+func (p *parser) array() (*node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	result := &node{kind: arrayNode, closed: true, start: p.pos}
+	p.pos++
+	for {
+		if err := p.skipArray(); err != nil {
+			return nil, err
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return result, nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		result.items = append(result.items, item)
+		if err := p.skipArray(); err != nil {
+			return nil, err
+		}
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, ErrSyntax
+		}
+	}
+}
+
+func (p *parser) inlineTable() (*node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	result := &node{kind: tableNode, defined: true, start: p.pos}
+	p.pos++
+	for {
+		if err := p.skipArray(); err != nil {
+			return nil, err
+		}
+		if p.data[p.pos] == '}' {
+			p.pos++
+			result.closed = true
+			return result, nil
+		}
+		if err := p.keyValue(result); err != nil {
+			return nil, err
+		}
+		if err := p.skipArray(); err != nil {
+			return nil, err
+		}
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return nil, ErrSyntax
+		}
+	}
+}
+
+func (p *parser) literalString() (string, error) {
+	p.pos++
+	end := bytes.IndexByte(p.data[p.pos:], '\'')
+	if end < 0 || bytes.IndexByte(p.data[p.pos:p.pos+end], '\n') >= 0 {
+		return "", ErrUnterminated
+	}
+	result := string(p.data[p.pos : p.pos+end])
+	p.pos += end + 1
+	return result, nil
+}
+
+func (p *parser) basicString() (string, error) {
+	p.pos++
+	var result []byte
+	for {
+		if p.endOfLine() {
+			return "", ErrUnterminated
+		}
+		switch c := p.data[p.pos]; c {
+		case '"':
+			p.pos++
+			return string(result), nil
+		case '\\':
+			var err error
+			if result, err = p.escape(result); err != nil {
+				return "", err
+			}
+		default:
+			result = append(result, c)
+			p.pos++
+		}
+	}
+}
+
+// multilineString reads a string in triple quotes, a line break right after
+// the opening quotes is dropped
+func (p *parser) multilineString(quote byte) (string, error) {
+	delimiter := []byte{quote, quote, quote}
+	p.pos += 3
+	if bytes.HasPrefix(p.data[p.pos:], []byte("\r\n")) {
+		p.pos += 2
+	} else if !p.eof() && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	var result []byte
+	for {
+		if p.eof() {
+			return "", ErrUnterminated
+		}
+		c := p.data[p.pos]
+		switch {
+		case bytes.HasPrefix(p.data[p.pos:], delimiter):
+			// up to two quotes may come right before the closing ones
+			for i := 0; i < 2 && p.pos+3 < len(p.data) && p.data[p.pos+3] == quote; i++ {
+				result = append(result, quote)
+				p.pos++
+			}
+			p.pos += 3
+			return string(result), nil
+		case c == '\\' && quote == '"':
+			var err error
+			if result, err = p.escape(result); err != nil {
+				return "", err
+			}
+		default:
+			result = append(result, c)
+			p.pos++
+		}
+	}
+}
+
+var escapes = map[byte]byte{'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', 'e': 0x1b, '"': '"', '\\': '\\'}
+
+func (p *parser) escape(result []byte) ([]byte, error) {
+	p.pos++
+	if p.eof() {
+		return nil, ErrUnterminated
+	}
+	c := p.data[p.pos]
+	p.pos++
+	if escaped, ok := escapes[c]; ok {
+		return append(result, escaped), nil
+	}
+	size := 0
+	switch c {
+	case ' ', '\t', '\r', '\n':
+		// a backslash at the end of a line drops the line break and the blanks after it
+		p.pos--
+		for !p.eof() && strings.IndexByte(" \t\r\n", p.data[p.pos]) >= 0 {
+			p.pos++
+		}
+		return result, nil
+	case 'x':
+		size = 2
+	case 'u':
+		size = 4
+	case 'U':
+		size = 8
+	default:
+		return nil, ErrSyntax
+	}
+	if p.pos+size > len(p.data) {
+		return nil, ErrUnterminated
+	}
+	code, err := strconv.ParseUint(string(p.data[p.pos:p.pos+size]), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return nil, ErrSyntax
+	}
+	p.pos += size
+	return utf8.AppendRune(result, rune(code)), nil
+}
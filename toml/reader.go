@@ -0,0 +1,464 @@
+package toml
+
+import (
+	"encoding/base64"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tvanomr/inspect"
+)
+
+type contextKind byte
+
+const (
+	contextTable contextKind = iota
+	contextArray
+)
+
+type readContext struct {
+	kind contextKind
+	// entries of a table and whether they were asked for
+	entries []entry
+	read    []bool
+	// value of the property or the map key being read
+	selected *node
+	// items of an array
+	items []*node
+	index int
+}
+
+// Reader reads a document as a single top level table
+type Reader struct {
+	reader   io.Reader
+	limits   inspect.Limits
+	loaded   bool
+	document *node
+	offset   int
+	current  *readContext
+	contexts stack[readContext]
+}
+
+func (r *Reader) SetReader(reader io.Reader) {
+	r.reader = reader
+	r.loaded = false
+	r.document = nil
+	r.offset = 0
+	r.current = nil
+	r.contexts = r.contexts[:0]
+}
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+func (r *Reader) Offset() int64 {
+	return int64(r.offset)
+}
+
+func (r *Reader) load() error {
+	r.loaded = true
+	reader := r.reader
+	if r.limits.MaxTotalBytes > 0 {
+		reader = io.LimitReader(reader, r.limits.MaxTotalBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if err := r.limits.CheckTotal(int64(len(data))); err != nil {
+		return err
+	}
+	p := parser{data: data, limits: &r.limits}
+	document, err := p.parse()
+	if err != nil {
+		r.offset = p.pos
+		return err
+	}
+	r.document = document
+	return nil
+}
+
+// peek returns the node of the next value without consuming it,
+// at the top level it is the document, io.EOF once it was read
+func (r *Reader) peek() (*node, error) {
+	if r.current == nil {
+		if !r.loaded {
+			if err := r.load(); err != nil {
+				return nil, err
+			}
+		}
+		if r.document == nil {
+			return nil, io.EOF
+		}
+		return r.document, nil
+	}
+	if r.current.kind == contextArray {
+		if r.current.index >= len(r.current.items) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return r.current.items[r.current.index], nil
+	}
+	if r.current.selected == nil {
+		return nil, ErrNoProperty
+	}
+	return r.current.selected, nil
+}
+
+// advance consumes the node returned by peek
+func (r *Reader) advance(value *node) {
+	r.offset = value.start
+	if r.current == nil {
+		r.document = nil
+	} else if r.current.kind == contextArray {
+		r.current.index++
+	} else {
+		r.current.selected = nil
+	}
+}
+
+// scalar consumes a scalar once parse accepts it
+func (r *Reader) scalar(parse func(value *node) error) error {
+	value, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if value.kind == tableNode || value.kind == arrayNode {
+		return ErrNotAScalar
+	}
+	if err := r.limits.CheckString(int64(len(value.text))); err != nil {
+		return err
+	}
+	if err := parse(value); err != nil {
+		return err
+	}
+	r.advance(value)
+	return nil
+}
+
+// number parses an integer or a float, it is consumed also when it overflows
+func number[T any](r *Reader, parse func(text string) (T, error), kind string, kinds ...nodeKind) (T, error) {
+	var result T
+	overflow := ""
+	err := r.scalar(func(value *node) error {
+		accepted := false
+		for _, item := range kinds {
+			accepted = accepted || value.kind == item
+		}
+		if !accepted {
+			return ErrNotANumber
+		}
+		var err error
+		result, err = parse(strings.ReplaceAll(value.text, "_", ""))
+		if isRange(err) || err == ErrIntegerRange {
+			overflow = value.text
+			return nil
+		}
+		if err != nil {
+			return ErrNotANumber
+		}
+		return nil
+	})
+	if err == nil && len(overflow) > 0 {
+		return result, &inspect.OverflowError{Type: kind, Value: overflow}
+	}
+	return result, err
+}
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := r.Int64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](result)
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := r.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
+
+func (r *Reader) Bool() (result bool, err error) {
+	err = r.scalar(func(value *node) error {
+		if value.kind != boolNode {
+			return ErrNotABool
+		}
+		result = value.text == "true"
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) Int8() (int8, error) {
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	return readNarrowInt[int16](r)
+}
+func (r *Reader) Int32() (int32, error) {
+	return readNarrowInt[int32](r)
+}
+func (r *Reader) Int64() (int64, error) {
+	return number(r, parseInteger, "int64", integerNode)
+}
+func (r *Reader) Uint8() (uint8, error) {
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	return number(r, func(text string) (uint64, error) {
+		result, err := parseInteger(text)
+		if err == nil && result < 0 {
+			err = &strconv.NumError{Func: "ParseUint", Num: text, Err: strconv.ErrRange}
+		}
+		return uint64(result), err
+	}, "uint64", integerNode)
+}
+
+// Float32 accepts floats and integers
+func (r *Reader) Float32() (float32, error) {
+	result, err := number(r, func(text string) (float64, error) {
+		result, err := parseNumber(text)
+		if err == nil && !math.IsInf(result, 0) && math.Abs(result) > math.MaxFloat32 {
+			err = &strconv.NumError{Func: "ParseFloat", Num: text, Err: strconv.ErrRange}
+		}
+		return result, err
+	}, "float32", floatNode, integerNode)
+	return float32(result), err
+}
+
+// Float64 accepts floats and integers
+func (r *Reader) Float64() (float64, error) {
+	return number(r, parseNumber, "float64", floatNode, integerNode)
+}
+
+// parseNumber reads a float or an integer as a float
+func parseNumber(text string) (float64, error) {
+	if integer, err := parseInteger(text); err == nil {
+		return float64(integer), nil
+	}
+	return parseFloat(text)
+}
+
+// String accepts strings and dates, which are returned as they are written
+func (r *Reader) String() (result string, err error) {
+	err = r.scalar(func(value *node) error {
+		if value.kind != stringNode && value.kind != datetimeNode {
+			return ErrNotAString
+		}
+		result = value.text
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) Bytes() (result []byte, err error) {
+	err = r.scalar(func(value *node) error {
+		if value.kind != stringNode {
+			return ErrNotAString
+		}
+		var err error
+		if result, err = base64.StdEncoding.DecodeString(value.text); err != nil {
+			return ErrInvalidBytes
+		}
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) ByteString() ([]byte, error) {
+	result, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(result), nil
+}
+
+// IsNull is always false, toml has no null and leaves out the key instead
+func (r *Reader) IsNull() (bool, error) {
+	_, err := r.peek()
+	return false, err
+}
+
+func (r *Reader) push(value readContext) error {
+	r.contexts.push(value)
+	r.current = &r.contexts[len(r.contexts)-1]
+	return r.limits.CheckDepth(len(r.contexts))
+}
+func (r *Reader) pop() {
+	r.contexts.pop()
+	if len(r.contexts) == 0 {
+		r.current = nil
+	} else {
+		r.current = &r.contexts[len(r.contexts)-1]
+	}
+}
+func (r *Reader) StartObject() error {
+	value, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if value.kind != tableNode {
+		return ErrNotATable
+	}
+	r.advance(value)
+	return r.push(readContext{kind: contextTable, entries: value.entries, read: make([]bool, len(value.entries))})
+}
+func (r *Reader) Property(name string) error {
+	present, err := r.OptionalProperty(name)
+	if err == nil && !present {
+		return inspect.ErrNoField
+	}
+	return err
+}
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	if r.current == nil || r.current.kind != contextTable {
+		return false, ErrNoProperty
+	}
+	r.current.selected = nil
+	for i, item := range r.current.entries {
+		if !r.current.read[i] && item.key == name {
+			r.current.read[i] = true
+			r.current.selected = item.value
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UnknownFields returns the keys not asked for with their values as inline toml
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if r.current == nil || r.current.kind != contextTable {
+		return nil, ErrNoProperty
+	}
+	var result []inspect.RawField
+	for i, item := range r.current.entries {
+		if r.current.read[i] {
+			continue
+		}
+		if policy == inspect.FailOnUnknown {
+			r.offset = item.value.start
+			return nil, ErrObjectTooBig
+		}
+		r.current.read[i] = true
+		if policy == inspect.CollectUnknown {
+			result = append(result, inspect.RawField{Format: formatName, Name: item.key, Value: appendNode(nil, item.value)})
+		}
+	}
+	return result, nil
+}
+func (r *Reader) EndObject() error {
+	r.pop()
+	return nil
+}
+
+// StartArray returns the number of items, an empty array needs no EndArray
+func (r *Reader) StartArray() (length int, err error) {
+	value, err := r.peek()
+	if err != nil {
+		return 0, err
+	}
+	if value.kind != arrayNode {
+		return 0, ErrNotAnArray
+	}
+	r.advance(value)
+	if len(value.items) == 0 {
+		return 0, nil
+	}
+	if err := r.limits.CheckArray(int64(len(value.items))); err != nil {
+		return 0, err
+	}
+	return len(value.items), r.push(readContext{kind: contextArray, items: value.items})
+}
+func (r *Reader) HaveNext() (bool, error) {
+	return false, nil
+}
+func (r *Reader) EndArray() error {
+	r.pop()
+	return nil
+}
+func (r *Reader) StartMap() (length int, err error) {
+	value, err := r.peek()
+	if err != nil {
+		return 0, err
+	}
+	if value.kind != tableNode {
+		return 0, ErrNotATable
+	}
+	r.advance(value)
+	if len(value.entries) == 0 {
+		return 0, nil
+	}
+	if err := r.limits.CheckArray(int64(len(value.entries))); err != nil {
+		return 0, err
+	}
+	return len(value.entries), r.push(readContext{kind: contextTable, entries: value.entries,
+		read: make([]bool, len(value.entries))})
+}
+
+// NextKey returns the next key of the map and selects its value
+func (r *Reader) NextKey() (string, error) {
+	if r.current == nil || r.current.kind != contextTable {
+		return "", nil
+	}
+	for i, item := range r.current.entries {
+		if !r.current.read[i] {
+			r.current.read[i] = true
+			r.current.selected = item.value
+			return item.key, nil
+		}
+	}
+	return "", nil
+}
+func (r *Reader) EndMap() error {
+	r.pop()
+	return nil
+}
+func (r *Reader) Skip() error {
+	value, err := r.peek()
+	if err != nil {
+		return err
+	}
+	r.advance(value)
+	return nil
+}
+
+// appendNode writes a value read from the input with inline tables
+func appendNode(buffer []byte, value *node) []byte {
+	switch value.kind {
+	case tableNode:
+		if len(value.entries) == 0 {
+			return append(buffer, "{}"...)
+		}
+		buffer = append(buffer, "{ "...)
+		for i, item := range value.entries {
+			if i > 0 {
+				buffer = append(buffer, ", "...)
+			}
+			buffer = appendKey(buffer, item.key)
+			buffer = append(buffer, " = "...)
+			buffer = appendNode(buffer, item.value)
+		}
+		return append(buffer, " }"...)
+	case arrayNode:
+		buffer = append(buffer, '[')
+		for i, item := range value.items {
+			if i > 0 {
+				buffer = append(buffer, ", "...)
+			}
+			buffer = appendNode(buffer, item)
+		}
+		return append(buffer, ']')
+	case stringNode:
+		return appendString(buffer, value.text)
+	}
+	return append(buffer, value.text...)
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+}
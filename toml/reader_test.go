@@ -0,0 +1,162 @@
+package toml_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/toml"
+)
+
+type limits struct {
+	connections int64
+}
+
+func (l *limits) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("limits", "server limits")
+	o.Int64("connections", &l.connections, true, "connections")
+	o.End()
+}
+
+type server struct {
+	name   string
+	limits limits
+}
+
+func (s *server) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("server", "server")
+	o.String("name", &s.name, true, "name")
+	inspect.PropertyObject(o, "limits", &s.limits, false, "limits")
+	o.End()
+}
+
+type owner struct {
+	name string
+}
+
+func (o *owner) Inspect(inspector *inspect.Inspector) {
+	object := inspector.StartObject("owner", "owner")
+	object.String("name", &o.name, true, "name")
+	object.End()
+}
+
+type config struct {
+	title   string
+	owner   owner
+	servers []server
+}
+
+func (c *config) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("config", "configuration")
+	o.String("title", &c.title, true, "title")
+	inspect.PropertyObject(o, "owner", &c.owner, true, "owner")
+	inspect.PropertyArray[*server](o, "servers", &c.servers, "server", true, "servers")
+	o.End()
+}
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"headers", `title = "t"
+
+[ "owner" ]
+name = "o"
+
+[[servers]]
+name = "a"
+
+[servers.limits]
+connections = 10
+
+[[ servers ]]
+name = "b"
+`},
+		{"dotted keys", `title = "t"
+owner.name = "o"
+servers = [{ name = "a", limits.connections = 10 }, { name = "b" }]
+`},
+		{"inline tables", `title = "t"
+owner = { name = "o" }
+servers = [
+  { name = "a", limits = { connections = 10 } },
+  { name = "b" },
+]
+`},
+		{"sub-table first", `title = "t"
+[owner]
+name = "o"
+[[servers]]
+limits.connections = 10
+name = "a"
+[[servers]]
+name = "b"
+`},
+	}
+	for _, test := range tests {
+		var value config
+		if err := inspect.Unmarshal([]byte(test.input), &value, toml.Format); err != nil || value.title != "t" ||
+			value.owner.name != "o" || len(value.servers) != 2 || value.servers[0].name != "a" ||
+			value.servers[0].limits.connections != 10 || value.servers[1] != (server{name: "b"}) {
+			t.Fatal(test.name, "got", value, err)
+		}
+	}
+}
+
+func TestInvalidTables(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		err   error
+	}{
+		{"[owner]\nname = \"a\"\n[owner]\nname = \"b\"\n", toml.ErrDuplicateKey},
+		{"owner = { name = \"a\" }\n[owner]\n", toml.ErrDuplicateKey},
+		{"servers = []\n[[servers]]\n", toml.ErrDuplicateKey},
+		{"[[owner]]\n[owner]\n", toml.ErrDuplicateKey},
+		{"title = \"t\"\ntitle.x = 1\n", toml.ErrDuplicateKey},
+		{"[owner\n", toml.ErrSyntax},
+		{"title = \"t\n", toml.ErrUnterminated},
+		{"servers = [{ name = \"a\" }\n", toml.ErrUnterminated},
+	} {
+		var value config
+		if err := inspect.Unmarshal([]byte(test.input), &value, toml.Format); !errors.Is(err, test.err) {
+			t.Fatalf("%q: got %v, expected %v", test.input, err, test.err)
+		}
+	}
+}
+
+type text string
+
+func (t *text) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("text", "a single string")
+	o.String("s", (*string)(t), true, "string")
+	o.End()
+}
+
+func TestStrings(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected text
+	}{
+		{`s = "a\tbé\U0001F600"`, "a\tbé\U0001F600"},
+		{`s = 'a\tb'`, `a\tb`},
+		{"s = \"\"\"\na\nb\"\"\"", "a\nb"},
+		{"s = '''\na\\\n b'''", "a\\\n b"},
+		{"s = \"\"\"a\"\"\"\"\"", "a\"\""},
+		{"s = \"#\" # comment", "#"},
+	} {
+		var value text
+		if err := inspect.Unmarshal([]byte(test.input+"\n"), &value, toml.Format); err != nil || value != test.expected {
+			t.Fatalf("%s: got %q %v", test.input, value, err)
+		}
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte("title = \"t\"\n[owner]\nname = 'o'\n[[servers]]\nname = \"a\"\n[servers.limits]\nconnections = 1_000\n"))
+	f.Add([]byte("servers = [{ name = \"a\", limits.connections = 0x10 }]\nowner.name = \"\"\"x\\\n y\"\"\"\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inspecttest.Fuzz(data, toml.Format, new(config))
+	})
+}
@@ -0,0 +1,117 @@
+package toml
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func isBareKey(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func appendKey(buffer []byte, key string) []byte {
+	if isBareKey(key) {
+		return append(buffer, key...)
+	}
+	return appendString(buffer, key)
+}
+
+// appendString writes a basic string, only quotes, backslashes and control characters are escaped
+func appendString(buffer []byte, text string) []byte {
+	buffer = append(buffer, '"')
+	for _, c := range text {
+		switch c {
+		case '"':
+			buffer = append(buffer, `\"`...)
+		case '\\':
+			buffer = append(buffer, `\\`...)
+		case '\b':
+			buffer = append(buffer, `\b`...)
+		case '\t':
+			buffer = append(buffer, `\t`...)
+		case '\n':
+			buffer = append(buffer, `\n`...)
+		case '\f':
+			buffer = append(buffer, `\f`...)
+		case '\r':
+			buffer = append(buffer, `\r`...)
+		default:
+			if c < ' ' || c == 0x7f {
+				buffer = append(buffer, `\u00`...)
+				buffer = append(buffer, "0123456789abcdef"[c>>4], "0123456789abcdef"[c&15])
+			} else {
+				buffer = utf8.AppendRune(buffer, c)
+			}
+		}
+	}
+	return append(buffer, '"')
+}
+
+// appendFloat makes sure that the number doesn't read back as an integer
+func appendFloat(buffer []byte, value float64, format byte, precision int, bitSize int) []byte {
+	switch {
+	case math.IsNaN(value):
+		return append(buffer, "nan"...)
+	case math.IsInf(value, 1):
+		return append(buffer, "inf"...)
+	case math.IsInf(value, -1):
+		return append(buffer, "-inf"...)
+	}
+	start := len(buffer)
+	buffer = strconv.AppendFloat(buffer, value, format, precision, bitSize)
+	if !bytes.ContainsAny(buffer[start:], ".eE") {
+		buffer = append(buffer, ".0"...)
+	}
+	return buffer
+}
+
+// parseInteger reads a TOML integer, underscores already removed
+func parseInteger(text string) (int64, error) {
+	if len(text) > 2 && text[0] == '0' && strings.IndexByte("xob", text[1]) >= 0 {
+		value, err := strconv.ParseUint(text[2:], map[byte]int{'x': 16, 'o': 8, 'b': 2}[text[1]], 64)
+		if err == nil && value > math.MaxInt64 {
+			return 0, ErrIntegerRange
+		}
+		return int64(value), err
+	}
+	digits := strings.TrimLeft(text, "+-")
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, ErrSyntax
+	}
+	return strconv.ParseInt(text, 10, 64)
+}
+
+// parseFloat reads a TOML float, underscores already removed
+func parseFloat(text string) (float64, error) {
+	switch text {
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	digits := strings.TrimLeft(text, "+-")
+	if len(digits) == 0 || digits[0] < '0' || digits[0] > '9' || digits[len(digits)-1] < '0' || digits[len(digits)-1] > '9' ||
+		strings.ContainsAny(digits, "xXpP") {
+		return 0, ErrSyntax
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+func isRange(err error) bool {
+	numError, ok := err.(*strconv.NumError)
+	return ok && numError.Err == strconv.ErrRange
+}
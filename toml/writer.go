@@ -0,0 +1,348 @@
+package toml
+
+import (
+	"encoding/base64"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tvanomr/inspect"
+)
+
+type valueKind byte
+
+const (
+	scalarValue valueKind = iota
+	tableValue
+	arrayValue
+)
+
+// value is kept in memory until the document ends,
+// as keys of a table have to come before its sub-tables
+type value struct {
+	kind valueKind
+	// a scalar in toml syntax
+	text        []byte
+	entries     []tableEntry
+	items       []*value
+	description string
+}
+
+type tableEntry struct {
+	key         string
+	value       *value
+	description string
+}
+
+// tableArray tells arrays written as [[array]] tables
+func (v *value) tableArray() bool {
+	if v.kind != arrayValue || len(v.items) == 0 {
+		return false
+	}
+	for _, item := range v.items {
+		if item.kind != tableValue {
+			return false
+		}
+	}
+	return true
+}
+
+// Writer writes a document for every top level object. Nested objects and maps become
+// sub-tables, arrays of objects [[array]] tables, and the rest are key/value pairs
+// or inline values inside arrays.
+type Writer struct {
+	writer     io.Writer
+	bufferSize int
+	buffer     []byte
+	comments   bool
+	// open tables and arrays
+	values stack[*value]
+	key    string
+	hasKey bool
+	// description of the document or of the next key
+	description    string
+	keyDescription string
+}
+
+// SetComments writes descriptions of documents, keys and tables as # comments
+func (w *Writer) SetComments(comments bool) {
+	w.comments = comments
+}
+
+func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
+	w.writer = writer
+	w.bufferSize = bufferSize
+	w.buffer = w.buffer[:0]
+	w.values = w.values[:0]
+	w.hasKey = false
+	w.description = ""
+}
+func (w *Writer) Describe(name string, elementName string, description string) {
+	w.description = description
+}
+
+// add puts a value into the open table or array
+func (w *Writer) add(item *value) error {
+	if len(w.values) == 0 {
+		if item.kind != tableValue {
+			return ErrNotATable
+		}
+		item.description = w.description
+		return nil
+	}
+	parent := w.values[len(w.values)-1]
+	if parent.kind == arrayValue {
+		parent.items = append(parent.items, item)
+		return nil
+	}
+	if !w.hasKey {
+		return ErrNoKey
+	}
+	w.hasKey = false
+	parent.entries = append(parent.entries, tableEntry{key: w.key, value: item, description: w.keyDescription})
+	return nil
+}
+
+func (w *Writer) scalar(text []byte) error {
+	return w.add(&value{kind: scalarValue, text: text})
+}
+
+func (w *Writer) Bool(value bool) error {
+	return w.scalar(strconv.AppendBool(nil, value))
+}
+func (w *Writer) Int8(value int8) error {
+	return w.scalar(strconv.AppendInt(nil, int64(value), 10))
+}
+func (w *Writer) Int16(value int16) error {
+	return w.scalar(strconv.AppendInt(nil, int64(value), 10))
+}
+func (w *Writer) Int32(value int32) error {
+	return w.scalar(strconv.AppendInt(nil, int64(value), 10))
+}
+func (w *Writer) Int64(value int64) error {
+	return w.scalar(strconv.AppendInt(nil, value, 10))
+}
+func (w *Writer) Uint8(value uint8) error {
+	return w.scalar(strconv.AppendUint(nil, uint64(value), 10))
+}
+func (w *Writer) Uint16(value uint16) error {
+	return w.scalar(strconv.AppendUint(nil, uint64(value), 10))
+}
+func (w *Writer) Uint32(value uint32) error {
+	return w.scalar(strconv.AppendUint(nil, uint64(value), 10))
+}
+func (w *Writer) Uint64(value uint64) error {
+	if value > math.MaxInt64 {
+		return ErrIntegerRange
+	}
+	return w.scalar(strconv.AppendUint(nil, value, 10))
+}
+func (w *Writer) Float32(value float32, format byte, precision int) error {
+	return w.scalar(appendFloat(nil, float64(value), format, precision, 32))
+}
+func (w *Writer) Float64(value float64, format byte, precision int) error {
+	return w.scalar(appendFloat(nil, value, format, precision, 64))
+}
+func (w *Writer) String(value string) error {
+	return w.scalar(appendString(nil, value))
+}
+func (w *Writer) Bytes(value []byte) error {
+	return w.scalar(appendString(nil, base64.StdEncoding.EncodeToString(value)))
+}
+func (w *Writer) ByteString(value []byte) error {
+	return w.scalar(appendString(nil, string(value)))
+}
+
+// Null leaves the key out as toml has no null,
+// such values read back only from optional properties
+func (w *Writer) Null() error {
+	if len(w.values) == 0 {
+		return ErrNotATable
+	}
+	if w.values[len(w.values)-1].kind == arrayValue {
+		return ErrNullInArray
+	}
+	w.hasKey = false
+	return nil
+}
+
+func (w *Writer) Skip() error {
+	return w.Null()
+}
+func (w *Writer) NotNull() error {
+	return nil
+}
+
+func (w *Writer) start(kind valueKind) error {
+	item := &value{kind: kind}
+	if err := w.add(item); err != nil {
+		return err
+	}
+	w.values.push(item)
+	return nil
+}
+
+// end writes the document out once its table is closed
+func (w *Writer) end() error {
+	table := w.values.pop()
+	if len(w.values) > 0 {
+		return nil
+	}
+	w.comment(table.description)
+	w.table("", table)
+	if len(w.buffer) > w.bufferSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+func (w *Writer) comment(description string) {
+	if !w.comments || len(description) == 0 {
+		return
+	}
+	for _, line := range strings.Split(description, "\n") {
+		w.buffer = append(w.buffer, "# "...)
+		w.buffer = append(w.buffer, line...)
+		w.buffer = append(w.buffer, '\n')
+	}
+}
+
+// table writes the key/value pairs of a table and then its sub-tables
+func (w *Writer) table(path string, table *value) {
+	for _, entry := range table.entries {
+		if entry.value.kind == tableValue || entry.value.tableArray() {
+			continue
+		}
+		w.comment(entry.description)
+		w.buffer = appendKey(w.buffer, entry.key)
+		w.buffer = append(w.buffer, " = "...)
+		w.buffer = appendInline(w.buffer, entry.value)
+		w.buffer = append(w.buffer, '\n')
+	}
+	for _, entry := range table.entries {
+		name := string(appendKey([]byte(path), entry.key))
+		if entry.value.kind == tableValue {
+			w.header(name, "[", "]\n", entry.description)
+			w.table(name+".", entry.value)
+		} else if entry.value.tableArray() {
+			for i, item := range entry.value.items {
+				if i > 0 {
+					entry.description = ""
+				}
+				w.header(name, "[[", "]]\n", entry.description)
+				w.table(name+".", item)
+			}
+		}
+	}
+}
+
+func (w *Writer) header(name string, open string, close string, description string) {
+	if len(w.buffer) > 0 {
+		w.buffer = append(w.buffer, '\n')
+	}
+	w.comment(description)
+	w.buffer = append(w.buffer, open...)
+	w.buffer = append(w.buffer, name...)
+	w.buffer = append(w.buffer, close...)
+}
+
+// appendInline writes a value on a single line, tables inside arrays become inline tables
+func appendInline(buffer []byte, item *value) []byte {
+	switch item.kind {
+	case tableValue:
+		buffer = append(buffer, '{')
+		for i, entry := range item.entries {
+			if i > 0 {
+				buffer = append(buffer, ',')
+			}
+			buffer = append(buffer, ' ')
+			buffer = appendKey(buffer, entry.key)
+			buffer = append(buffer, " = "...)
+			buffer = appendInline(buffer, entry.value)
+		}
+		if len(item.entries) > 0 {
+			buffer = append(buffer, ' ')
+		}
+		return append(buffer, '}')
+	case arrayValue:
+		buffer = append(buffer, '[')
+		for i, element := range item.items {
+			if i > 0 {
+				buffer = append(buffer, ", "...)
+			}
+			buffer = appendInline(buffer, element)
+		}
+		return append(buffer, ']')
+	}
+	return append(buffer, item.text...)
+}
+
+func (w *Writer) StartObject() error {
+	return w.start(tableValue)
+}
+func (w *Writer) Property(name string) error {
+	w.key = name
+	w.hasKey = true
+	w.keyDescription = w.description
+	return nil
+}
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	if !present {
+		return nil
+	}
+	return w.Property(name)
+}
+
+// RawProperty writes a key collected by a toml reader, its value is inline
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	if field.Format != formatName {
+		return ErrRawField
+	}
+	w.description = ""
+	if err := w.Property(field.Name); err != nil {
+		return err
+	}
+	return w.scalar(field.Value)
+}
+func (w *Writer) EndObject() error {
+	return w.end()
+}
+func (w *Writer) StartArray(length int) error {
+	return w.start(arrayValue)
+}
+func (w *Writer) EndArray() error {
+	return w.end()
+}
+func (w *Writer) StartMap(length int) error {
+	return w.start(tableValue)
+}
+func (w *Writer) NextKey(key string) error {
+	w.description = ""
+	return w.Property(key)
+}
+func (w *Writer) EndMap() error {
+	return w.end()
+}
+func (w *Writer) SortKeys() bool {
+	return false
+}
+func (w *Writer) Flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	written, err := w.writer.Write(w.buffer)
+	if err != nil {
+		return err
+	}
+	if written < len(w.buffer) {
+		return ErrShortWrite
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func init() {
+	var _ inspect.Writer = (*Writer)(nil)
+	var _ inspect.Described = (*Writer)(nil)
+}
@@ -0,0 +1,24 @@
+package inspect
+
+// UnknownFieldPolicy tells a reader what to do with object members
+// that were not asked for by the Inspect method
+type UnknownFieldPolicy byte
+
+const (
+	// reading fails on the first unknown member
+	FailOnUnknown UnknownFieldPolicy = iota
+	// unknown members are silently discarded
+	SkipUnknown
+	// unknown members are kept as RawField values
+	CollectUnknown
+)
+
+// RawField is an object member kept in the encoding of the format it was read from,
+// writing it back to the same format reproduces the member as it was read,
+// writers of other formats refuse it
+type RawField struct {
+	// name the format is registered with, e.g. "json"
+	Format string
+	Name   string
+	Value  []byte
+}
@@ -13,6 +13,25 @@ type WriterPtr[T any] interface {
 type WriteInspector[W any, PW WriterPtr[W]] struct {
 	writer    W
 	lastError error
+	sortKeys  bool
+}
+
+// SetSortKeys makes the map helpers write keys in order, so the same maps
+// give the same output in every process
+func (w *WriteInspector[W, PW]) SetSortKeys(sortKeys bool) {
+	w.sortKeys = sortKeys
+}
+
+// SetCanonical asks writers of formats with a canonical form for it, see Canonical
+func (w *WriteInspector[W, PW]) SetCanonical(canonical bool) {
+	if writer, ok := any(&w.writer).(Canonical); ok {
+		writer.SetCanonical(canonical)
+	}
+}
+
+// Writer gives access to the backend, e.g. for its output options
+func (w *WriteInspector[W, PW]) Writer() PW {
+	return &w.writer
 }
 
 func (w *WriteInspector[W, PW]) LastError() error {
@@ -27,6 +46,21 @@ func (w *WriteInspector[W, PW]) SetReader(io.Reader) {
 		w.lastError = ErrWriterCantRead
 	}
 }
+func (w *WriteInspector[W, PW]) Bool(value *bool) {
+	if w.lastError == nil {
+		w.lastError = PW(&w.writer).Bool(*value)
+	}
+}
+func (w *WriteInspector[W, PW]) Int8(value *int8) {
+	if w.lastError == nil {
+		w.lastError = PW(&w.writer).Int8(*value)
+	}
+}
+func (w *WriteInspector[W, PW]) Int16(value *int16) {
+	if w.lastError == nil {
+		w.lastError = PW(&w.writer).Int16(*value)
+	}
+}
 func (w *WriteInspector[W, PW]) Int32(value *int32) {
 	if w.lastError == nil {
 		w.lastError = PW(&w.writer).Int32(*value)
@@ -47,6 +81,36 @@ func (w *WriteInspector[W, PW]) Int(value *int) {
 		w.lastError = PW(&w.writer).Int32(int32(*value))
 	}
 }
+func (w *WriteInspector[W, PW]) Uint8(value *uint8) {
+	if w.lastError == nil {
+		w.lastError = PW(&w.writer).Uint8(*value)
+	}
+}
+func (w *WriteInspector[W, PW]) Uint16(value *uint16) {
+	if w.lastError == nil {
+		w.lastError = PW(&w.writer).Uint16(*value)
+	}
+}
+func (w *WriteInspector[W, PW]) Uint32(value *uint32) {
+	if w.lastError == nil {
+		w.lastError = PW(&w.writer).Uint32(*value)
+	}
+}
+func (w *WriteInspector[W, PW]) Uint64(value *uint64) {
+	if w.lastError == nil {
+		w.lastError = PW(&w.writer).Uint64(*value)
+	}
+}
+func (w *WriteInspector[W, PW]) Uint(value *uint) {
+	if w.lastError != nil {
+		return
+	}
+	if strconv.IntSize == 64 {
+		w.lastError = PW(&w.writer).Uint64(uint64(*value))
+	} else if strconv.IntSize == 32 {
+		w.lastError = PW(&w.writer).Uint32(uint32(*value))
+	}
+}
 func (w *WriteInspector[W, PW]) Float32(value *float32, format byte, precision int) {
 	if w.lastError == nil {
 		w.lastError = PW(&w.writer).Float32(*value, format, precision)
@@ -72,73 +136,163 @@ func (w *WriteInspector[W, PW]) ByteString(value *[]byte) {
 		w.lastError = PW(&w.writer).ByteString(*value)
 	}
 }
+func (w *WriteInspector[W, PW]) Null(isNull *bool) {
+	if w.lastError != nil {
+		return
+	}
+	if *isNull {
+		w.lastError = PW(&w.writer).Null()
+	} else {
+		w.lastError = PW(&w.writer).NotNull()
+	}
+}
+
+// describe passes the metadata of the following value to writers that use it
+func (w *WriteInspector[W, PW]) describe(name string, elementName string, description string) {
+	if described, ok := any(&w.writer).(Described); ok {
+		described.Describe(name, elementName, description)
+	}
+}
 func (w *WriteInspector[W, PW]) StartObject(name string, description string) {
 	if w.lastError == nil {
+		w.describe(name, "", description)
 		w.lastError = PW(&w.writer).StartObject()
 	}
 }
+
+// property writes the property name, optional properties get a presence
+// marker in formats that can't tell a missing property from the next one
+func (w *WriteInspector[W, PW]) property(name string, mandatory bool, description string) bool {
+	if w.lastError != nil {
+		return false
+	}
+	w.describe(name, "", description)
+	if mandatory {
+		w.lastError = PW(&w.writer).Property(name)
+	} else {
+		w.lastError = PW(&w.writer).OptionalProperty(name, true)
+	}
+	return w.lastError == nil
+}
 func (w *WriteInspector[W, PW]) Property(name string, mandatory bool, description string) bool {
+	return w.property(name, mandatory, description)
+}
+func (w *WriteInspector[W, PW]) FieldNumber(number int) {
+	if numbered, ok := any(&w.writer).(FieldNumbered); ok {
+		numbered.FieldNumber(number)
+	}
+}
+func (w *WriteInspector[W, PW]) UseVarint() {
+	if varints, ok := any(&w.writer).(Varints); ok {
+		varints.UseVarint()
+	}
+}
+func (w *WriteInspector[W, PW]) UseAttribute() {
+	if attributes, ok := any(&w.writer).(Attributes); ok {
+		attributes.UseAttribute()
+	}
+}
+
+func (w *WriteInspector[W, PW]) OptionalProperty(name string, present *bool, description string) bool {
 	if w.lastError != nil {
 		return false
 	}
-	w.lastError = PW(&w.writer).Property(name)
-	return true
+	if *present {
+		w.describe(name, "", description)
+	}
+	w.lastError = PW(&w.writer).OptionalProperty(name, *present)
+	return w.lastError == nil && *present
+}
+func (w *WriteInspector[W, PW]) PropertyBool(name string, value *bool, mandatory bool, description string) {
+	if w.property(name, mandatory, description) {
+		w.Bool(value)
+	}
+}
+func (w *WriteInspector[W, PW]) PropertyInt8(name string, value *int8, mandatory bool, description string) {
+	if w.property(name, mandatory, description) {
+		w.Int8(value)
+	}
+}
+func (w *WriteInspector[W, PW]) PropertyInt16(name string, value *int16, mandatory bool, description string) {
+	if w.property(name, mandatory, description) {
+		w.Int16(value)
+	}
 }
 func (w *WriteInspector[W, PW]) PropertyInt32(name string, value *int32, mandatory bool, description string) {
-	if w.lastError != nil {
-		return
+	if w.property(name, mandatory, description) {
+		w.Int32(value)
 	}
-	w.lastError = PW(&w.writer).Property(name)
-	w.Int32(value)
 }
 func (w *WriteInspector[W, PW]) PropertyInt64(name string, value *int64, mandatory bool, description string) {
-	if w.lastError != nil {
-		return
+	if w.property(name, mandatory, description) {
+		w.Int64(value)
 	}
-	w.lastError = PW(&w.writer).Property(name)
-	w.Int64(value)
 }
 func (w *WriteInspector[W, PW]) PropertyInt(name string, value *int, mandatory bool, description string) {
-	if w.lastError != nil {
-		return
+	if w.property(name, mandatory, description) {
+		w.Int(value)
+	}
+}
+func (w *WriteInspector[W, PW]) PropertyUint8(name string, value *uint8, mandatory bool, description string) {
+	if w.property(name, mandatory, description) {
+		w.Uint8(value)
+	}
+}
+func (w *WriteInspector[W, PW]) PropertyUint16(name string, value *uint16, mandatory bool, description string) {
+	if w.property(name, mandatory, description) {
+		w.Uint16(value)
+	}
+}
+func (w *WriteInspector[W, PW]) PropertyUint32(name string, value *uint32, mandatory bool, description string) {
+	if w.property(name, mandatory, description) {
+		w.Uint32(value)
+	}
+}
+func (w *WriteInspector[W, PW]) PropertyUint64(name string, value *uint64, mandatory bool, description string) {
+	if w.property(name, mandatory, description) {
+		w.Uint64(value)
+	}
+}
+func (w *WriteInspector[W, PW]) PropertyUint(name string, value *uint, mandatory bool, description string) {
+	if w.property(name, mandatory, description) {
+		w.Uint(value)
 	}
-	w.lastError = PW(&w.writer).Property(name)
-	w.Int(value)
 }
 func (w *WriteInspector[W, PW]) PropertyFloat32(name string, value *float32, format byte, precision int, mandatory bool, description string) {
-	if w.lastError != nil {
-		return
+	if w.property(name, mandatory, description) {
+		w.Float32(value, format, precision)
 	}
-	w.lastError = PW(&w.writer).Property(name)
-	w.Float32(value, format, precision)
 }
 func (w *WriteInspector[W, PW]) PropertyFloat64(name string, value *float64, format byte, precision int, mandatory bool, description string) {
-	if w.lastError != nil {
-		return
+	if w.property(name, mandatory, description) {
+		w.Float64(value, format, precision)
 	}
-	w.lastError = PW(&w.writer).Property(name)
-	w.Float64(value, format, precision)
 }
 func (w *WriteInspector[W, PW]) PropertyString(name string, value *string, mandatory bool, description string) {
-	if w.lastError != nil {
-		return
+	if w.property(name, mandatory, description) {
+		w.String(value)
 	}
-	w.lastError = PW(&w.writer).Property(name)
-	w.String(value)
 }
 func (w *WriteInspector[W, PW]) PropertyBytes(name string, value *[]byte, mandatory bool, description string) {
-	if w.lastError != nil {
-		return
+	if w.property(name, mandatory, description) {
+		w.Bytes(value)
 	}
-	w.lastError = PW(&w.writer).Property(name)
-	w.Bytes(value)
 }
 func (w *WriteInspector[W, PW]) PropertyByteString(name string, value *[]byte, mandatory bool, description string) {
-	if w.lastError != nil {
+	if w.property(name, mandatory, description) {
+		w.ByteString(value)
+	}
+}
+func (w *WriteInspector[W, PW]) UnknownFields(policy UnknownFieldPolicy, fields *[]RawField) {
+	if fields == nil {
 		return
 	}
-	w.lastError = PW(&w.writer).Property(name)
-	w.ByteString(value)
+	for _, field := range *fields {
+		if w.lastError != nil {
+			return
+		}
+		w.lastError = PW(&w.writer).RawProperty(field)
+	}
 }
 func (w *WriteInspector[W, PW]) EndObject() {
 	if w.lastError == nil {
@@ -153,6 +307,7 @@ func (w *WriteInspector[W, PW]) ReadArray() int {
 }
 func (w *WriteInspector[W, PW]) WriteArray(name string, elementName string, length int, description string) {
 	if w.lastError == nil {
+		w.describe(name, elementName, description)
 		w.lastError = PW(&w.writer).StartArray(length)
 	}
 }
@@ -169,6 +324,7 @@ func (w *WriteInspector[W, PW]) ReadMap() int {
 }
 func (w *WriteInspector[W, PW]) WriteMap(name string, elementName string, length int, description string) {
 	if w.lastError == nil {
+		w.describe(name, elementName, description)
 		w.lastError = PW(&w.writer).StartMap(length)
 	}
 }
@@ -194,10 +350,21 @@ func (w *WriteInspector[W, PW]) EndMap() {
 		w.lastError = PW(&w.writer).EndMap()
 	}
 }
+
+// Skip leaves out a value that readers are expected to skip
+func (w *WriteInspector[W, PW]) Skip() {
+	if w.lastError == nil {
+		w.lastError = PW(&w.writer).Skip()
+	}
+}
 func (w *WriteInspector[W, PW]) IsReading() bool {
 	return false
 }
 
+func (w *WriteInspector[W, PW]) SortKeys() bool {
+	return w.sortKeys || PW(&w.writer).SortKeys()
+}
+
 func (w *WriteInspector[W, PW]) Flush() {
 	if w.lastError == nil {
 		w.lastError = PW(&w.writer).Flush()
@@ -220,17 +387,8 @@ func (w *TextWriteInspector[W, PW]) Value(value RawValue) {
 }
 
 func (w *TextWriteInspector[W, PW]) PropertyValue(name string, value RawValue, mandatory bool, description string) {
-	if w.lastError != nil {
-		return
-	}
-	w.lastError = PW(&w.writer).Property(name)
-	if w.lastError != nil {
-		return
-	}
-	var data []byte
-	data, w.lastError = value.MarshalText()
-	if w.lastError == nil {
-		w.lastError = PW(&w.writer).ByteString(data)
+	if w.property(name, mandatory, description) {
+		w.Value(value)
 	}
 }
 
@@ -250,16 +408,7 @@ func (w *BinaryWriteInspector[W, PW]) Value(value RawValue) {
 }
 
 func (w *BinaryWriteInspector[W, PW]) PropertyValue(name string, value RawValue, mandatory bool, description string) {
-	if w.lastError != nil {
-		return
-	}
-	w.lastError = PW(&w.writer).Property(name)
-	if w.lastError != nil {
-		return
-	}
-	var data []byte
-	data, w.lastError = value.GobEncode()
-	if w.lastError == nil {
-		w.lastError = PW(&w.writer).Bytes(data)
+	if w.property(name, mandatory, description) {
+		w.Value(value)
 	}
 }
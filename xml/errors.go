@@ -0,0 +1,48 @@
+package xml
+
+import "github.com/tvanomr/inspect"
+
+type xmlError int
+
+const (
+	ErrShortWrite xmlError = iota
+	ErrNoProperty
+	ErrLateAttribute
+	ErrAttributeValue
+	ErrNoRoot
+	ErrManyRoots
+	ErrNotAValue
+	ErrNotANumber
+	ErrInvalidBytes
+	ErrObjectTooBig
+	ErrRawField
+	ErrNoElementName
+)
+
+var errorMessages = map[xmlError]string{
+	ErrShortWrite:     "short write",
+	ErrNoProperty:     "value inside an object without a property name",
+	ErrLateAttribute:  "attribute after child elements",
+	ErrAttributeValue: "attributes hold only scalar values",
+	ErrNoRoot:         "no root element",
+	ErrManyRoots:      "more than one root element",
+	ErrNotAValue:      "element has child elements or is nil",
+	ErrNotANumber:     "text is not a number",
+	ErrInvalidBytes:   "text is not base64",
+	ErrObjectTooBig:   "element contains more children than requested",
+	ErrRawField:       "fields collected from another format can't be written",
+	ErrNoElementName:  "arrays and maps need an element name for their items"}
+
+func (x xmlError) Error() string {
+	return errorMessages[x]
+}
+
+func (x xmlError) Is(target error) bool {
+	switch x {
+	case ErrAttributeValue, ErrNotAValue, ErrNotANumber, ErrInvalidBytes:
+		return target == inspect.ErrTypeMismatch
+	case ErrObjectTooBig:
+		return target == inspect.ErrUnknownField
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package xml
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// appender lets xml.EscapeText write into a buffer
+type appender struct {
+	buffer *[]byte
+}
+
+func (a appender) Write(data []byte) (int, error) {
+	*a.buffer = append(*a.buffer, data...)
+	return len(data), nil
+}
+
+func appendEscaped(buffer []byte, text string) []byte {
+	xml.EscapeText(appender{&buffer}, []byte(text))
+	return buffer
+}
+
+func appendAttribute(buffer []byte, name string, value string) []byte {
+	buffer = append(buffer, ' ')
+	buffer = append(buffer, name...)
+	buffer = append(buffer, '=', '"')
+	buffer = appendEscaped(buffer, value)
+	return append(buffer, '"')
+}
+
+// appendComment keeps "--" out of the comment text, it ends the comment
+func appendComment(buffer []byte, text string) []byte {
+	for strings.Contains(text, "--") {
+		text = strings.ReplaceAll(text, "--", "- -")
+	}
+	buffer = append(buffer, "<!-- "...)
+	buffer = append(buffer, text...)
+	return append(buffer, " -->"...)
+}
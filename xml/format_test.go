@@ -0,0 +1,58 @@
+package xml_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/xml"
+)
+
+func TestRoundTrip(t *testing.T) {
+	writeInspector := new(inspect.TextWriteInspector[xml.Writer, *xml.Writer])
+	writeInspector.Writer().SetIndent("  ")
+	writeInspector.Writer().SetComments(true)
+	writer := inspect.NewInspector(writeInspector)
+	value := inspecttest.Order{
+		ID:       3,
+		Customer: inspecttest.Wrapper{ID: 4, Flags: inspecttest.Flags{Enabled: true}},
+		Items:    []inspecttest.Item{{Name: "a & b", Price: 1.5}},
+		Notes:    []*inspecttest.Item{},
+		Tags:     map[string]inspecttest.IntValue{"a b": 1},
+	}
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if writer.LastError() != nil || !strings.Contains(buffer.String(), `
+  <!-- order items -->
+  <items>
+    <!-- order item -->
+    <item>
+      <!-- item name -->
+      <name>a &amp; b</name>`) || !strings.Contains(buffer.String(), `<notes/>`) ||
+		!strings.Contains(buffer.String(), `<tag key="a b">1</tag>`) {
+		t.Fatal("got", buffer.String(), writer.LastError())
+	}
+	var result inspecttest.Order
+	if err := inspect.Unmarshal(buffer.Bytes(), &result, xml.Format); err != nil || !result.Equal(&value) {
+		t.Fatal("got", result, err)
+	}
+
+	data, err := inspect.Marshal(&inspecttest.Book{ISBN: "123", Title: "Go", Pages: 300}, xml.Format)
+	if err != nil || string(data) != `<book isbn="123"><title>Go</title><pages>300</pages></book>` {
+		t.Fatal("got", string(data), err)
+	}
+	var parsed inspecttest.Book
+	if err := inspect.Unmarshal([]byte(`<book isbn="1"><!-- c --><pages> 12 </pages><title>x</title></book>`),
+		&parsed, xml.Format); err != nil || parsed != (inspecttest.Book{ISBN: "1", Title: "x", Pages: 12}) {
+		t.Fatal("got", parsed, err)
+	}
+	if err := inspect.Unmarshal([]byte(`<book isbn="1"><title><b/></title></book>`),
+		&parsed, xml.Format); !errors.Is(err, inspect.ErrTypeMismatch) {
+		t.Fatal("got", err, "expected", inspect.ErrTypeMismatch)
+	}
+}
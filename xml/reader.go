@@ -0,0 +1,495 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tvanomr/inspect"
+)
+
+// node is an element or an attribute found in the input
+type node struct {
+	name      string
+	attribute bool
+	text      string
+	key       string
+	null      bool
+	children  []*node
+	// bytes of the element, an attribute keeps its own
+	start int
+	end   int
+	raw   []byte
+}
+
+// elements returns the child elements without the attributes
+func (n *node) elements() []*node {
+	var result []*node
+	for _, child := range n.children {
+		if !child.attribute {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+type readContext struct {
+	kind contextKind
+	// children of an object and whether they were asked for
+	children []*node
+	read     []bool
+	// node of the property or the map item being read
+	selected *node
+	// items of an array or a map
+	items []*node
+	index int
+}
+
+// Reader reads the whole document into memory before giving out its elements,
+// as properties may come in any order
+type Reader struct {
+	reader   io.Reader
+	limits   inspect.Limits
+	data     []byte
+	offset   int
+	loaded   bool
+	root     *node
+	current  *readContext
+	contexts stack[readContext]
+	// the next property is an attribute
+	attribute bool
+}
+
+func (r *Reader) SetReader(reader io.Reader) {
+	r.reader = reader
+	r.data = nil
+	r.offset = 0
+	r.loaded = false
+	r.root = nil
+	r.current = nil
+	r.contexts = r.contexts[:0]
+	r.attribute = false
+}
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+func (r *Reader) Offset() int64 {
+	return int64(r.offset)
+}
+
+// load reads and parses the document
+func (r *Reader) load() error {
+	reader := r.reader
+	if r.limits.MaxTotalBytes > 0 {
+		reader = io.LimitReader(reader, r.limits.MaxTotalBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if err := r.limits.CheckTotal(int64(len(data))); err != nil {
+		return err
+	}
+	r.data = data
+	r.loaded = true
+	return r.parse()
+}
+
+func (r *Reader) parse() error {
+	decoder := xml.NewDecoder(bytes.NewReader(r.data))
+	var open stack[*node]
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.offset = start
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			element := &node{name: token.Name.Local, start: start}
+			for _, attribute := range token.Attr {
+				switch attribute.Name.Local {
+				case keyAttribute:
+					element.key = attribute.Value
+				case nullAttribute:
+					element.null = attribute.Value == "true"
+				default:
+					element.children = append(element.children, &node{name: attribute.Name.Local,
+						attribute: true, text: attribute.Value, start: start,
+						raw: appendAttribute(nil, attribute.Name.Local, attribute.Value)[1:]})
+				}
+			}
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				parent.children = append(parent.children, element)
+			} else if r.root != nil {
+				r.offset = start
+				return ErrManyRoots
+			} else {
+				r.root = element
+			}
+			open.push(element)
+			if err := r.limits.CheckDepth(len(open)); err != nil {
+				r.offset = start
+				return err
+			}
+		case xml.EndElement:
+			open.pop().end = int(decoder.InputOffset())
+		case xml.CharData:
+			if len(open) > 0 {
+				open[len(open)-1].text += string(token)
+			}
+		}
+	}
+	if r.root == nil {
+		return ErrNoRoot
+	}
+	return nil
+}
+
+// peek returns the node of the next value without consuming it
+func (r *Reader) peek() (*node, error) {
+	if r.current == nil {
+		if !r.loaded {
+			if err := r.load(); err != nil {
+				return nil, err
+			}
+		}
+		if r.root == nil {
+			return nil, io.EOF
+		}
+		return r.root, nil
+	}
+	if r.current.kind == contextArray {
+		if r.current.index >= len(r.current.items) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return r.current.items[r.current.index], nil
+	}
+	if r.current.selected == nil {
+		return nil, ErrNoProperty
+	}
+	return r.current.selected, nil
+}
+
+// advance consumes the node returned by peek
+func (r *Reader) advance(value *node) {
+	r.offset = value.start
+	if r.current == nil {
+		r.root = nil
+	} else if r.current.kind == contextArray {
+		r.current.index++
+	} else {
+		r.current.selected = nil
+	}
+}
+
+// text consumes a value that has no child elements
+func (r *Reader) text(parse func(text string) error) error {
+	value, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if value.null || len(value.elements()) > 0 {
+		return ErrNotAValue
+	}
+	if err := r.limits.CheckString(int64(len(value.text))); err != nil {
+		return err
+	}
+	if err := parse(value.text); err != nil {
+		return err
+	}
+	r.advance(value)
+	return nil
+}
+
+func isRange(err error) bool {
+	numError, ok := err.(*strconv.NumError)
+	return ok && numError.Err == strconv.ErrRange
+}
+
+// number parses a number consuming it also when it overflows
+func number[T any](r *Reader, parse func(text string) (T, error), kind string) (T, error) {
+	var result T
+	var overflow string
+	err := r.text(func(text string) error {
+		var err error
+		text = strings.TrimSpace(text)
+		result, err = parse(text)
+		if isRange(err) {
+			overflow = text
+			return nil
+		}
+		if err != nil {
+			return ErrNotANumber
+		}
+		return nil
+	})
+	if err == nil && len(overflow) > 0 {
+		return result, &inspect.OverflowError{Type: kind, Value: overflow}
+	}
+	return result, err
+}
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := r.Int64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](result)
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := r.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
+
+func (r *Reader) Bool() (result bool, err error) {
+	err = r.text(func(text string) error {
+		var err error
+		if result, err = strconv.ParseBool(strings.TrimSpace(text)); err != nil {
+			return ErrNotAValue
+		}
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) Int8() (int8, error) {
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	return readNarrowInt[int16](r)
+}
+func (r *Reader) Int32() (int32, error) {
+	return readNarrowInt[int32](r)
+}
+func (r *Reader) Int64() (int64, error) {
+	return number(r, func(text string) (int64, error) {
+		return strconv.ParseInt(text, 10, 64)
+	}, "int64")
+}
+func (r *Reader) Uint8() (uint8, error) {
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	return number(r, func(text string) (uint64, error) {
+		return strconv.ParseUint(text, 10, 64)
+	}, "uint64")
+}
+func (r *Reader) Float32() (float32, error) {
+	result, err := number(r, func(text string) (float64, error) {
+		return strconv.ParseFloat(text, 32)
+	}, "float32")
+	return float32(result), err
+}
+func (r *Reader) Float64() (float64, error) {
+	return number(r, func(text string) (float64, error) {
+		return strconv.ParseFloat(text, 64)
+	}, "float64")
+}
+func (r *Reader) String() (result string, err error) {
+	err = r.text(func(text string) error {
+		result = text
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) Bytes() (result []byte, err error) {
+	err = r.text(func(text string) error {
+		var err error
+		if result, err = base64.StdEncoding.DecodeString(strings.TrimSpace(text)); err != nil {
+			return ErrInvalidBytes
+		}
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) ByteString() ([]byte, error) {
+	result, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(result), nil
+}
+func (r *Reader) IsNull() (bool, error) {
+	value, err := r.peek()
+	if err != nil {
+		return false, err
+	}
+	if value.null {
+		r.advance(value)
+		return true, nil
+	}
+	return false, nil
+}
+
+// element peeks at the next value and checks that it is an element
+func (r *Reader) element() (*node, error) {
+	value, err := r.peek()
+	if err != nil {
+		return nil, err
+	}
+	if value.attribute {
+		return nil, ErrAttributeValue
+	}
+	return value, nil
+}
+func (r *Reader) StartObject() error {
+	value, err := r.element()
+	if err != nil {
+		return err
+	}
+	r.advance(value)
+	return r.push(readContext{kind: contextObject, children: value.children,
+		read: make([]bool, len(value.children))})
+}
+
+// push starts a context, the current one stays at the top of contexts
+func (r *Reader) push(value readContext) error {
+	r.contexts.push(value)
+	r.current = &r.contexts[len(r.contexts)-1]
+	return r.limits.CheckDepth(len(r.contexts))
+}
+func (r *Reader) pop() {
+	r.contexts.pop()
+	if len(r.contexts) == 0 {
+		r.current = nil
+	} else {
+		r.current = &r.contexts[len(r.contexts)-1]
+	}
+}
+func (r *Reader) Property(name string) error {
+	present, err := r.OptionalProperty(name)
+	if err == nil && !present {
+		return inspect.ErrNoField
+	}
+	return err
+}
+
+// UseAttribute reads the following property from an attribute
+func (r *Reader) UseAttribute() {
+	r.attribute = true
+}
+
+// OptionalProperty selects the first child element of that name, or the attribute after UseAttribute
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	attribute := r.attribute
+	r.attribute = false
+	if r.current == nil || r.current.kind != contextObject {
+		return false, ErrNoProperty
+	}
+	r.current.selected = nil
+	for i, child := range r.current.children {
+		if !r.current.read[i] && child.name == name && child.attribute == attribute {
+			r.current.read[i] = true
+			r.current.selected = child
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UnknownFields returns elements and attributes not asked for as they are in the input
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if r.current == nil || r.current.kind != contextObject {
+		return nil, ErrNoProperty
+	}
+	var result []inspect.RawField
+	for i, child := range r.current.children {
+		if r.current.read[i] {
+			continue
+		}
+		if policy == inspect.FailOnUnknown {
+			r.offset = child.start
+			return nil, ErrObjectTooBig
+		}
+		r.current.read[i] = true
+		if policy == inspect.CollectUnknown {
+			value := child.raw
+			if !child.attribute {
+				value = append([]byte(nil), r.data[child.start:child.end]...)
+			}
+			result = append(result, inspect.RawField{Format: formatName, Name: child.name, Value: value})
+		}
+	}
+	return result, nil
+}
+func (r *Reader) EndObject() error {
+	r.pop()
+	return nil
+}
+
+// StartArray returns the number of child elements, their names don't matter
+func (r *Reader) StartArray() (length int, err error) {
+	return r.startItems(contextArray)
+}
+func (r *Reader) startItems(kind contextKind) (int, error) {
+	value, err := r.element()
+	if err != nil {
+		return 0, err
+	}
+	r.advance(value)
+	items := value.elements()
+	if len(items) == 0 {
+		return 0, nil
+	}
+	if err := r.limits.CheckArray(int64(len(items))); err != nil {
+		return 0, err
+	}
+	return len(items), r.push(readContext{kind: kind, items: items})
+}
+func (r *Reader) HaveNext() (bool, error) {
+	return false, nil
+}
+func (r *Reader) EndArray() error {
+	r.pop()
+	return nil
+}
+func (r *Reader) StartMap() (length int, err error) {
+	return r.startItems(contextMap)
+}
+
+// NextKey returns the key attribute of the next item and selects it for the value
+func (r *Reader) NextKey() (string, error) {
+	if r.current == nil || r.current.kind != contextMap || r.current.index >= len(r.current.items) {
+		return "", nil
+	}
+	value := r.current.items[r.current.index]
+	r.current.index++
+	r.current.selected = value
+	r.offset = value.start
+	return value.key, nil
+}
+func (r *Reader) EndMap() error {
+	r.pop()
+	return nil
+}
+func (r *Reader) Skip() error {
+	value, err := r.peek()
+	if err != nil {
+		return err
+	}
+	r.advance(value)
+	return nil
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+	var _ inspect.Attributes = (*Reader)(nil)
+}
@@ -0,0 +1,120 @@
+package xml_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/xml"
+)
+
+// taggedBook has an attribute and a list of elements
+type taggedBook struct {
+	isbn  string
+	title string
+	tags  inspecttest.Texts
+}
+
+func (b *taggedBook) Inspect(inspector *inspect.Inspector) {
+	o := inspector.StartObject("book", "book with an attribute")
+	o.Attribute().String("isbn", &b.isbn, true, "isbn")
+	o.String("title", &b.title, true, "title")
+	inspect.PropertyObject(o, "tags", &b.tags, false, "tags")
+	o.End()
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected inspecttest.Text
+	}{
+		{`<value>a &amp; b</value>`, "a & b"},
+		{`<value><![CDATA[<a>]]></value>`, "<a>"},
+		{`<value>a<!-- comment -->b</value>`, "ab"},
+		{`<?xml version="1.0"?>` + "\n<value>&#x41;&lt;</value>\n", "A<"},
+		{`<value/>`, ""},
+		{`<value>  spaced  </value>`, "  spaced  "},
+	}
+	for _, test := range tests {
+		var result inspecttest.Text
+		if err := inspect.Unmarshal([]byte(test.input), &result, xml.Format); err != nil || result != test.expected {
+			t.Fatalf("%s: got %q %v", test.input, result, err)
+		}
+	}
+	for _, test := range []struct {
+		input string
+		err   error
+	}{
+		{`<value><a/></value>`, xml.ErrNotAValue},
+		{`<value nil="true"/>`, xml.ErrNotAValue},
+		{`<value/><value/>`, xml.ErrManyRoots},
+		{`<!-- nothing -->`, xml.ErrNoRoot},
+	} {
+		var result inspecttest.Text
+		if err := inspect.Unmarshal([]byte(test.input), &result, xml.Format); !errors.Is(err, test.err) {
+			t.Fatal(test.input, "got", err, "expected", test.err)
+		}
+	}
+	var result inspecttest.Text
+	if err := inspect.Unmarshal([]byte(`<value>a</b>`), &result, xml.Format); err == nil {
+		t.Fatal("got", result)
+	}
+}
+
+func TestElements(t *testing.T) {
+	// children come in any order, whitespace between them is ignored
+	input := "<book isbn=\"1\">\n  <tags>\n    <text>a</text>\n    <text/>\n  </tags>\n  <title>Go</title>\n</book>"
+	var value taggedBook
+	if err := inspect.Unmarshal([]byte(input), &value, xml.Format); err != nil || value.isbn != "1" ||
+		value.title != "Go" || len(value.tags) != 2 || value.tags[0] != "a" || value.tags[1] != "" {
+		t.Fatal("got", value, err)
+	}
+	// an attribute is not found among the child elements
+	input = `<book><isbn>2</isbn><title>Go</title></book>`
+	if err := inspect.Unmarshal([]byte(input), &value, xml.Format); !errors.Is(err, inspect.ErrNoField) {
+		t.Fatal("got", value, err)
+	}
+
+	var values inspecttest.Dictionary
+	input = `<dictionary><text key="a b">x</text><text key="">y</text></dictionary>`
+	if err := inspect.Unmarshal([]byte(input), &values, xml.Format); err != nil || len(values) != 2 ||
+		values["a b"] != "x" || values[""] != "y" {
+		t.Fatal("got", values, err)
+	}
+
+	var list inspecttest.Texts
+	input = strings.Repeat("<texts>", 20) + strings.Repeat("</texts>", 20)
+	err := inspect.Unmarshal([]byte(input), &list, xml.Format, inspect.WithLimits(inspect.Limits{MaxDepth: 10}))
+	if !errors.Is(err, inspect.ErrLimitExceeded) {
+		t.Fatal("got", err)
+	}
+}
+
+type unnamed []inspecttest.Text
+
+func (u *unnamed) Inspect(inspector *inspect.Inspector) {
+	inspect.Array[*inspecttest.Text]((*[]inspecttest.Text)(u), inspector, "unnamed", "", "items without a name")
+}
+
+func TestNames(t *testing.T) {
+	// a child element is not found among the attributes
+	var value taggedBook
+	input := `<book isbn="1" title="Go"/>`
+	if err := inspect.Unmarshal([]byte(input), &value, xml.Format); !errors.Is(err, inspect.ErrNoField) {
+		t.Fatal("got", value, err)
+	}
+	if _, err := inspect.Marshal(&unnamed{"a"}, xml.Format); !errors.Is(err, xml.ErrNoElementName) {
+		t.Fatal("got", err)
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte(`<book isbn="1"><title>Go</title><tags><text>a</text></tags></book>`))
+	f.Add([]byte(`<dictionary><text key="k">v</text><text nil="true"/></dictionary>`))
+	f.Add([]byte(`<value><![CDATA[a]]>&amp;</value>`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inspecttest.Fuzz(data, xml.Format, new(taggedBook), new(inspecttest.Dictionary))
+	})
+}
@@ -0,0 +1,372 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strconv"
+
+	"github.com/tvanomr/inspect"
+)
+
+type contextKind byte
+
+const (
+	contextObject contextKind = iota
+	contextArray
+	contextMap
+)
+
+// attributes are reserved for map keys and nulls
+const (
+	keyAttribute  = "key"
+	nullAttribute = "nil"
+)
+
+// element is an element open for children
+type element struct {
+	name string
+	kind contextKind
+	// name of the items of an array or a map
+	itemName string
+	// child elements were written, the end tag goes on its own line
+	children bool
+}
+
+// Writer writes objects as elements with a child element per property, properties
+// after ObjectInspector.Attribute become attributes. Array items are elements named by
+// the elementName of the array, map items also carry the key in the key attribute.
+type Writer struct {
+	writer     io.Writer
+	bufferSize int
+	buffer     []byte
+	indent     string
+	comments   bool
+	started    bool
+	// the last start tag still takes attributes
+	open     bool
+	elements stack[element]
+	// name of the next value inside an object and whether it is an attribute
+	property  string
+	attribute bool
+	// key of the next map item
+	key    string
+	hasKey bool
+	// metadata of the next value
+	describedName string
+	itemName      string
+	description   string
+}
+
+// SetIndent puts every element on its own line indented once per nesting level
+func (w *Writer) SetIndent(indent string) {
+	w.indent = indent
+}
+
+// SetComments writes descriptions as comments before the elements
+func (w *Writer) SetComments(comments bool) {
+	w.comments = comments
+}
+
+func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
+	w.writer = writer
+	w.bufferSize = bufferSize
+	w.buffer = w.buffer[:0]
+	w.started = false
+	w.open = false
+	w.elements = w.elements[:0]
+	w.property = ""
+	w.attribute = false
+	w.hasKey = false
+	w.describedName = ""
+	w.itemName = ""
+	w.description = ""
+}
+
+// Describe keeps the names for the following elements, the description of a property
+// wins over the one of its object
+func (w *Writer) Describe(name string, elementName string, description string) {
+	w.describedName = name
+	w.itemName = elementName
+	if len(w.description) == 0 {
+		w.description = description
+	}
+}
+
+// nextName returns the name of the element of the next value
+func (w *Writer) nextName() (name string, attribute bool, err error) {
+	if len(w.elements) == 0 {
+		if len(w.describedName) == 0 {
+			return "value", false, nil
+		}
+		return w.describedName, false, nil
+	}
+	parent := &w.elements[len(w.elements)-1]
+	if parent.kind != contextObject {
+		return parent.itemName, false, nil
+	}
+	name, attribute = w.property, w.attribute
+	w.property = ""
+	w.attribute = false
+	if len(name) == 0 {
+		return "", false, ErrNoProperty
+	}
+	return name, attribute, nil
+}
+
+// UseAttribute writes the following property as an attribute of the element of its object
+func (w *Writer) UseAttribute() {
+	w.attribute = true
+}
+
+func (w *Writer) closeTag() {
+	if w.open {
+		w.buffer = append(w.buffer, '>')
+		w.open = false
+	}
+}
+
+func (w *Writer) newline() {
+	if len(w.indent) == 0 || !w.started {
+		return
+	}
+	w.buffer = append(w.buffer, '\n')
+	for range w.elements {
+		w.buffer = append(w.buffer, w.indent...)
+	}
+}
+
+func (w *Writer) startTag(name string) {
+	w.closeTag()
+	if len(w.elements) > 0 {
+		w.elements[len(w.elements)-1].children = true
+	}
+	w.newline()
+	w.started = true
+	if w.comments && len(w.description) > 0 {
+		w.buffer = appendComment(w.buffer, w.description)
+		w.newline()
+	}
+	w.description = ""
+	w.buffer = append(w.buffer, '<')
+	w.buffer = append(w.buffer, name...)
+	if w.hasKey {
+		w.buffer = appendAttribute(w.buffer, keyAttribute, w.key)
+		w.hasKey = false
+	}
+	w.open = true
+}
+
+// scalar writes an element with text or an attribute of the open element
+func (w *Writer) scalar(text string) error {
+	name, attribute, err := w.nextName()
+	if err != nil {
+		return err
+	}
+	if attribute {
+		if !w.open {
+			return ErrLateAttribute
+		}
+		w.description = ""
+		w.buffer = appendAttribute(w.buffer, name, text)
+		return nil
+	}
+	w.startTag(name)
+	w.buffer = append(w.buffer, '>')
+	w.open = false
+	w.buffer = appendEscaped(w.buffer, text)
+	w.buffer = append(w.buffer, "</"...)
+	w.buffer = append(w.buffer, name...)
+	w.buffer = append(w.buffer, '>')
+	return w.flushIfFull()
+}
+
+func (w *Writer) flushIfFull() error {
+	if len(w.buffer) > w.bufferSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+func (w *Writer) Bool(value bool) error {
+	return w.scalar(strconv.FormatBool(value))
+}
+func (w *Writer) Int8(value int8) error {
+	return w.scalar(strconv.FormatInt(int64(value), 10))
+}
+func (w *Writer) Int16(value int16) error {
+	return w.scalar(strconv.FormatInt(int64(value), 10))
+}
+func (w *Writer) Int32(value int32) error {
+	return w.scalar(strconv.FormatInt(int64(value), 10))
+}
+func (w *Writer) Int64(value int64) error {
+	return w.scalar(strconv.FormatInt(value, 10))
+}
+func (w *Writer) Uint8(value uint8) error {
+	return w.scalar(strconv.FormatUint(uint64(value), 10))
+}
+func (w *Writer) Uint16(value uint16) error {
+	return w.scalar(strconv.FormatUint(uint64(value), 10))
+}
+func (w *Writer) Uint32(value uint32) error {
+	return w.scalar(strconv.FormatUint(uint64(value), 10))
+}
+func (w *Writer) Uint64(value uint64) error {
+	return w.scalar(strconv.FormatUint(value, 10))
+}
+func (w *Writer) Float32(value float32, format byte, precision int) error {
+	return w.scalar(strconv.FormatFloat(float64(value), format, precision, 32))
+}
+func (w *Writer) Float64(value float64, format byte, precision int) error {
+	return w.scalar(strconv.FormatFloat(value, format, precision, 64))
+}
+func (w *Writer) String(value string) error {
+	return w.scalar(value)
+}
+func (w *Writer) Bytes(value []byte) error {
+	return w.scalar(base64.StdEncoding.EncodeToString(value))
+}
+func (w *Writer) ByteString(value []byte) error {
+	return w.scalar(string(value))
+}
+
+// Null writes an empty element with nil="true", a null attribute is left out
+func (w *Writer) Null() error {
+	name, attribute, err := w.nextName()
+	if err != nil || attribute {
+		w.description = ""
+		return err
+	}
+	w.startTag(name)
+	w.buffer = appendAttribute(w.buffer, nullAttribute, "true")
+	w.buffer = append(w.buffer, "/>"...)
+	w.open = false
+	return w.flushIfFull()
+}
+
+func (w *Writer) Skip() error {
+	return w.Null()
+}
+func (w *Writer) NotNull() error {
+	return nil
+}
+
+func (w *Writer) start(kind contextKind) error {
+	name, attribute, err := w.nextName()
+	if err != nil {
+		return err
+	}
+	if attribute {
+		return ErrAttributeValue
+	}
+	value := element{name: name, kind: kind}
+	if kind != contextObject {
+		value.itemName = w.itemName
+		if len(value.itemName) == 0 {
+			return ErrNoElementName
+		}
+	}
+	w.itemName = ""
+	w.startTag(name)
+	w.elements.push(value)
+	return nil
+}
+
+// end closes the innermost element, one without children ends with "/>"
+func (w *Writer) end() error {
+	value := w.elements.pop()
+	if w.open {
+		w.buffer = append(w.buffer, "/>"...)
+		w.open = false
+	} else {
+		if value.children {
+			w.newline()
+		}
+		w.buffer = append(w.buffer, "</"...)
+		w.buffer = append(w.buffer, value.name...)
+		w.buffer = append(w.buffer, '>')
+	}
+	return w.flushIfFull()
+}
+func (w *Writer) StartObject() error {
+	return w.start(contextObject)
+}
+func (w *Writer) Property(name string) error {
+	w.property = name
+	return nil
+}
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	if !present {
+		w.property = ""
+		w.attribute = false
+		return nil
+	}
+	return w.Property(name)
+}
+
+// RawProperty writes an element or an attribute collected by an xml reader,
+// only an element starts with '<'
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	if field.Format != formatName {
+		return ErrRawField
+	}
+	if !bytes.HasPrefix(field.Value, []byte("<")) {
+		if !w.open {
+			return ErrLateAttribute
+		}
+		w.buffer = append(w.buffer, ' ')
+		w.buffer = append(w.buffer, field.Value...)
+		return nil
+	}
+	w.closeTag()
+	if len(w.elements) > 0 {
+		w.elements[len(w.elements)-1].children = true
+	}
+	w.newline()
+	w.buffer = append(w.buffer, field.Value...)
+	return w.flushIfFull()
+}
+func (w *Writer) EndObject() error {
+	return w.end()
+}
+func (w *Writer) StartArray(length int) error {
+	return w.start(contextArray)
+}
+func (w *Writer) EndArray() error {
+	return w.end()
+}
+func (w *Writer) StartMap(length int) error {
+	return w.start(contextMap)
+}
+func (w *Writer) NextKey(key string) error {
+	w.key = key
+	w.hasKey = true
+	return nil
+}
+func (w *Writer) EndMap() error {
+	return w.end()
+}
+func (w *Writer) SortKeys() bool {
+	return false
+}
+func (w *Writer) Flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	written, err := w.writer.Write(w.buffer)
+	if err != nil {
+		return err
+	}
+	if written < len(w.buffer) {
+		return ErrShortWrite
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func init() {
+	var _ inspect.Writer = (*Writer)(nil)
+	var _ inspect.Described = (*Writer)(nil)
+	var _ inspect.Attributes = (*Writer)(nil)
+}
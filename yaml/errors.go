@@ -0,0 +1,54 @@
+package yaml
+
+import "github.com/tvanomr/inspect"
+
+type yamlError int
+
+const (
+	ErrShortWrite yamlError = iota
+	ErrSyntax
+	ErrIndentation
+	ErrUnterminated
+	ErrUnsupported
+	ErrUnknownAlias
+	ErrNoProperty
+	ErrNotAScalar
+	ErrNotABool
+	ErrNotANumber
+	ErrInvalidBytes
+	ErrNotAMapping
+	ErrNotASequence
+	ErrObjectTooBig
+	ErrRawField
+)
+
+var errorMessages = map[yamlError]string{
+	ErrShortWrite:   "short write",
+	ErrSyntax:       "syntax error",
+	ErrIndentation:  "bad indentation",
+	ErrUnterminated: "unterminated quoted scalar or flow collection",
+	ErrUnsupported:  "complex keys are not supported",
+	ErrUnknownAlias: "alias of an unknown anchor",
+	ErrNoProperty:   "value inside a mapping without a key",
+	ErrNotAScalar:   "not a scalar",
+	ErrNotABool:     "not a boolean",
+	ErrNotANumber:   "not a number",
+	ErrInvalidBytes: "not base64",
+	ErrNotAMapping:  "not a mapping",
+	ErrNotASequence: "not a sequence",
+	ErrObjectTooBig: "mapping contains more keys than requested",
+	ErrRawField:     "fields collected from another format can't be written"}
+
+func (y yamlError) Error() string {
+	return errorMessages[y]
+}
+
+func (y yamlError) Is(target error) bool {
+	switch y {
+	case ErrNotAScalar, ErrNotABool, ErrNotANumber, ErrInvalidBytes, ErrNotAMapping, ErrNotASequence:
+		return target == inspect.ErrTypeMismatch
+	case ErrObjectTooBig:
+		return target == inspect.ErrUnknownField
+	}
+	return false
+}
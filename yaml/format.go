@@ -0,0 +1,13 @@
+package yaml
+
+import "github.com/tvanomr/inspect"
+
+// Format is YAML for inspect.Marshal, inspect.Unmarshal, inspect.Encode and inspect.Decode
+var Format inspect.Format = inspect.TextFormat[Reader, *Reader, Writer, *Writer]{}
+
+// formatName tags the fields collected by Reader, Writer accepts only those
+const formatName = "yaml"
+
+func init() {
+	inspect.RegisterFormat(formatName, Format, "application/yaml", "application/x-yaml", "text/yaml")
+}
@@ -0,0 +1,121 @@
+package yaml_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/yaml"
+)
+
+func TestRoundTrip(t *testing.T) {
+	writeInspector := new(inspect.TextWriteInspector[yaml.Writer, *yaml.Writer])
+	writeInspector.Writer().SetComments(true)
+	writer := inspect.NewInspector(writeInspector)
+	value := inspecttest.Order{
+		ID:       3,
+		Customer: inspecttest.Wrapper{ID: 4, Flags: inspecttest.Flags{Enabled: true}},
+		Items:    []inspecttest.Item{{Name: "a: b", Price: 1.5}, {Name: "true", Price: 2}},
+		Notes:    []*inspecttest.Item{},
+		Tags:     map[string]inspecttest.IntValue{"a": 1},
+	}
+	var buffer bytes.Buffer
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	novel := inspecttest.Book{ISBN: "1", Title: "Go", Pages: 300}
+	novel.Inspect(writer)
+	writer.Flush()
+	expected := `# order with nested values
+# order id
+id: 3
+# customer
+customer:
+  # identifier
+  id: 4
+  # nested flags
+  flags:
+    # enabled flag
+    enabled: true
+    # visible flag
+    visible: false
+# order items
+items:
+  - name: "a: b"
+    # item price
+    price: 1.5
+  - name: "true"
+    # item price
+    price: 2
+# order notes
+notes: []
+# order tags
+tags:
+  a: 1
+---
+# book with an attribute
+# isbn
+isbn: "1"
+# title
+title: Go
+# number of pages
+pages: 300
+`
+	if writer.LastError() != nil || buffer.String() != expected {
+		t.Fatal("got", buffer.String(), writer.LastError())
+	}
+
+	// a stream of documents is read into successive values
+	reader := inspect.NewInspector(new(inspect.TextReadInspector[yaml.Reader, *yaml.Reader]))
+	reader.SetReader(&buffer)
+	var result inspecttest.Order
+	result.Inspect(reader)
+	var parsed inspecttest.Book
+	parsed.Inspect(reader)
+	if reader.LastError() != nil || !result.Equal(&value) || parsed != novel {
+		t.Fatal("got", result, parsed, reader.LastError())
+	}
+
+	writeInspector.Writer().SetFlow(true)
+	writer.SetWriter(&buffer, 10)
+	value.Inspect(writer)
+	writer.Flush()
+	if buffer.String() != `{id: 3, customer: {id: 4, flags: {enabled: true, visible: false}}, `+
+		`items: [{name: "a: b", price: 1.5}, {name: "true", price: 2}], notes: [], tags: {a: 1}}`+"\n" {
+		t.Fatal("got", buffer.String())
+	}
+
+	input := `%YAML 1.2
+--- # comments, anchors, block scalars and flow collections
+id: 0x10
+customer: &customer
+  id: 4
+  flags: {enabled: yes, visible: false}
+items:
+- name: |
+    two
+    lines
+  price: .5
+- name: >-
+    folded
+    text
+  price: 1e1
+notes: []
+tags: {"a": 1, 'b''': 2}
+...
+`
+	result = inspecttest.Order{}
+	err := inspect.Unmarshal([]byte(input), &result, yaml.Format)
+	if !errors.Is(err, inspect.ErrTypeMismatch) {
+		t.Fatal("got", err, "expected", inspect.ErrTypeMismatch)
+	}
+	input = strings.Replace(input, "yes", "true", 1)
+	if err := inspect.Unmarshal([]byte(input), &result, yaml.Format); err != nil || result.ID != 16 ||
+		result.Customer.ID != 4 || !result.Customer.Flags.Enabled || len(result.Items) != 2 ||
+		result.Items[0] != (inspecttest.Item{Name: "two\nlines\n", Price: 0.5}) ||
+		result.Items[1] != (inspecttest.Item{Name: "folded text", Price: 10}) || result.Tags["b'"] != 2 {
+		t.Fatal("got", result, err)
+	}
+}
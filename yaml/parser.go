@@ -0,0 +1,730 @@
+package yaml
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tvanomr/inspect"
+)
+
+type nodeKind byte
+
+const (
+	scalarNode nodeKind = iota
+	mappingNode
+	sequenceNode
+)
+
+type entry struct {
+	key   string
+	value *node
+}
+
+type node struct {
+	kind nodeKind
+	text string
+	// a plain scalar may be a null, a boolean or a number, quoted ones are strings
+	plain   bool
+	entries []entry
+	items   []*node
+	start   int
+}
+
+// anchored is the value of an anchor, size counts the bytes it was read from
+// and those its aliases stand for
+type anchored struct {
+	value *node
+	size  int64
+}
+
+// parser reads the block and flow styles of YAML without complex keys and merge keys,
+// tags are ignored
+type parser struct {
+	data    []byte
+	pos     int
+	limits  *inspect.Limits
+	depth   int
+	anchors map[string]anchored
+	// bytes the aliases read so far stand for, they count against MaxTotalBytes
+	// as if the anchored values were repeated in the input
+	expanded int64
+	// start of the line holding scanned, column searches for line breaks only past scanned
+	lineStart int
+	scanned   int
+}
+
+func (p *parser) reset(data []byte, limits *inspect.Limits) {
+	p.data = data
+	p.pos = 0
+	p.limits = limits
+	p.depth = 0
+	p.anchors = nil
+	p.expanded = 0
+	p.lineStart = 0
+	p.scanned = 0
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// endOfLine tells whether only a line break follows
+func (p *parser) endOfLine() bool {
+	return p.eof() || p.data[p.pos] == '\n' || p.data[p.pos] == '\r'
+}
+
+// spaceAt tells whether the byte at position separates tokens
+func (p *parser) spaceAt(position int) bool {
+	return position >= len(p.data) || isSpace(p.data[position])
+}
+
+// column doesn't search a long line back to its start again for every value on it
+func (p *parser) column() int {
+	if p.pos < p.lineStart {
+		p.lineStart = bytes.LastIndexAny(p.data[:p.pos], "\r\n") + 1
+		p.scanned = p.pos
+	}
+	for ; p.scanned < p.pos; p.scanned++ {
+		if c := p.data[p.scanned]; c == '\n' || c == '\r' {
+			p.lineStart = p.scanned + 1
+		}
+	}
+	return p.pos - p.lineStart
+}
+
+// lineBreaks counts the line breaks in data, CR LF is a single one
+func lineBreaks(data []byte) int {
+	count := 0
+	for i, c := range data {
+		if c == '\n' || c == '\r' && (i+1 == len(data) || data[i+1] != '\n') {
+			count++
+		}
+	}
+	return count
+}
+
+// skipInline skips blanks and a comment up to the line break
+func (p *parser) skipInline() {
+	for !p.eof() && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+		p.pos++
+	}
+	if !p.eof() && p.data[p.pos] == '#' && (p.pos == 0 || isSpace(p.data[p.pos-1])) {
+		for !p.endOfLine() {
+			p.pos++
+		}
+	}
+}
+
+// skipLines skips blanks, comments and line breaks, returns false at the end of the input
+func (p *parser) skipLines() bool {
+	for {
+		p.skipInline()
+		if p.eof() {
+			return false
+		}
+		if !p.endOfLine() {
+			return true
+		}
+		p.pos++
+	}
+}
+
+// marker tells whether a document marker starts at the position
+func (p *parser) marker(marker string) bool {
+	return p.column() == 0 && bytes.HasPrefix(p.data[p.pos:], []byte(marker)) && p.spaceAt(p.pos+len(marker))
+}
+
+func (p *parser) atDocumentEnd() bool {
+	return p.eof() || p.marker("---") || p.marker("...")
+}
+
+func (p *parser) dash() bool {
+	return !p.eof() && p.data[p.pos] == '-' && p.spaceAt(p.pos+1)
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	return p.limits.CheckDepth(p.depth)
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func empty(position int) *node {
+	return &node{kind: scalarNode, plain: true, start: position}
+}
+
+// document parses the next document of the stream, io.EOF when there are no more
+func (p *parser) document() (*node, error) {
+	for {
+		if !p.skipLines() {
+			return nil, io.EOF
+		}
+		if p.data[p.pos] == '%' && p.column() == 0 {
+			// directives
+			for !p.endOfLine() {
+				p.pos++
+			}
+			continue
+		}
+		if p.marker("...") {
+			p.pos += 3
+			continue
+		}
+		break
+	}
+	p.anchors = nil
+	var result *node
+	var err error
+	if p.marker("---") {
+		p.pos += 3
+		result, err = p.value(-1, false)
+	} else {
+		result, err = p.content(-1, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.skipLines() && !p.marker("---") {
+		if !p.marker("...") {
+			return nil, ErrSyntax
+		}
+		p.pos += 3
+	}
+	return result, nil
+}
+
+// value parses the value after a key, a dash or a document marker, on the same line
+// or on the following ones; indent is the column of the enclosing collection,
+// a mapping on the same line may follow only a dash
+func (p *parser) value(indent int, afterKey bool) (*node, error) {
+	p.skipInline()
+	if p.endOfLine() {
+		return p.block(indent, afterKey)
+	}
+	return p.content(indent, !afterKey && indent >= 0)
+}
+
+// block parses a value on the following lines, it has to be indented deeper than the
+// enclosing collection, only a sequence in a mapping may start at the same column
+func (p *parser) block(indent int, sequenceAtIndent bool) (*node, error) {
+	start := p.pos
+	if !p.skipLines() || p.atDocumentEnd() {
+		return empty(start), nil
+	}
+	column := p.column()
+	if column < indent || column == indent && !(sequenceAtIndent && p.dash()) {
+		return empty(start), nil
+	}
+	return p.content(indent, true)
+}
+
+// content parses the value starting at the position, a mapping
+// may start only on its own line or after a dash
+func (p *parser) content(indent int, mapping bool) (*node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	start, expanded := p.pos, p.expanded
+	anchor := ""
+	for !p.eof() && (p.data[p.pos] == '&' || p.data[p.pos] == '!') {
+		name := p.token()
+		if name[0] == '&' {
+			anchor = name[1:]
+		}
+		p.skipInline()
+		if p.endOfLine() {
+			result, err := p.block(indent, false)
+			p.anchor(anchor, result, start, expanded)
+			return result, err
+		}
+	}
+	result, err := p.node(indent, mapping)
+	p.anchor(anchor, result, start, expanded)
+	return result, err
+}
+
+// anchor names the value read since start, expanded is the count of the aliases before it
+func (p *parser) anchor(name string, value *node, start int, expanded int64) {
+	if len(name) > 0 && value != nil {
+		if p.anchors == nil {
+			p.anchors = make(map[string]anchored)
+		}
+		p.anchors[name] = anchored{value: value, size: int64(p.pos-start) + p.expanded - expanded}
+	}
+}
+
+// token reads up to the next blank, e.g. an anchor or a tag
+func (p *parser) token() string {
+	start := p.pos
+	for !p.spaceAt(p.pos) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+func (p *parser) node(indent int, mapping bool) (*node, error) {
+	start := p.pos
+	column := p.column()
+	switch p.data[p.pos] {
+	case '-':
+		if p.dash() {
+			return p.sequence(column)
+		}
+	case '[', '{':
+		return p.flow()
+	case '|', '>':
+		return p.blockScalar(indent)
+	case '*':
+		return p.alias()
+	case '?':
+		if p.spaceAt(p.pos + 1) {
+			return nil, ErrUnsupported
+		}
+	case '"', '\'':
+		text, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		if mapping && p.isKey() {
+			p.pos = start
+			return p.mapping(column)
+		}
+		return &node{kind: scalarNode, text: text, start: start}, nil
+	}
+	text := p.plain(false)
+	if !p.eof() && p.data[p.pos] == ':' {
+		if !mapping {
+			return nil, ErrSyntax
+		}
+		p.pos = start
+		return p.mapping(column)
+	}
+	return p.continuation(text, indent, start), nil
+}
+
+// isKey tells whether a colon follows the key just read
+func (p *parser) isKey() bool {
+	for !p.eof() && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+		p.pos++
+	}
+	return !p.eof() && p.data[p.pos] == ':' && p.spaceAt(p.pos+1)
+}
+
+// continuation folds the following lines of a plain scalar in
+func (p *parser) continuation(text string, indent int, start int) *node {
+	var folded strings.Builder
+	folded.WriteString(text)
+	for {
+		end := p.pos
+		p.skipInline()
+		if !p.endOfLine() || !p.skipLines() || p.atDocumentEnd() || p.column() <= indent ||
+			p.data[p.pos] == '#' || p.dash() {
+			p.pos = end
+			break
+		}
+		breaks := lineBreaks(p.data[end:p.pos])
+		line := p.plain(false)
+		if !p.eof() && p.data[p.pos] == ':' {
+			p.pos = end
+			break
+		}
+		if breaks == 1 {
+			folded.WriteByte(' ')
+		} else {
+			folded.WriteString(strings.Repeat("\n", breaks-1))
+		}
+		folded.WriteString(line)
+	}
+	return &node{kind: scalarNode, text: folded.String(), plain: true, start: start}
+}
+
+// plain reads a plain scalar up to a colon, a comment or the line break,
+// in flow style also up to the flow indicators
+func (p *parser) plain(flow bool) string {
+	start := p.pos
+	for ; !p.endOfLine(); p.pos++ {
+		c := p.data[p.pos]
+		if c == ':' && (p.spaceAt(p.pos+1) || flow && strings.IndexByte(",[]{}", p.data[p.pos+1]) >= 0) ||
+			c == '#' && p.pos > start && isSpace(p.data[p.pos-1]) ||
+			flow && strings.IndexByte(",[]{}", c) >= 0 {
+			break
+		}
+	}
+	return strings.TrimRight(string(p.data[start:p.pos]), " \t")
+}
+
+func (p *parser) alias() (*node, error) {
+	p.pos++
+	start := p.pos
+	for !p.spaceAt(p.pos) && strings.IndexByte(",[]{}", p.data[p.pos]) < 0 {
+		p.pos++
+	}
+	result, ok := p.anchors[string(p.data[start:p.pos])]
+	if !ok {
+		return nil, ErrUnknownAlias
+	}
+	// the value is shared but read again for every alias, nested anchors would multiply it
+	p.expanded += result.size
+	if err := p.limits.CheckTotal(int64(len(p.data)) + p.expanded); err != nil {
+		return nil, err
+	}
+	return result.value, nil
+}
+
+// key reads a mapping key and the colon after it
+func (p *parser) key() (string, error) {
+	var key string
+	switch p.data[p.pos] {
+	case '"', '\'':
+		var err error
+		if key, err = p.quoted(); err != nil {
+			return "", err
+		}
+	case '?':
+		return "", ErrUnsupported
+	default:
+		key = p.plain(false)
+	}
+	if !p.isKey() {
+		return "", ErrSyntax
+	}
+	p.pos++
+	return key, nil
+}
+
+func (p *parser) mapping(column int) (*node, error) {
+	result := &node{kind: mappingNode, start: p.pos}
+	for {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.value(column, true)
+		if err != nil {
+			return nil, err
+		}
+		result.entries = append(result.entries, entry{key: key, value: value})
+		if !p.skipLines() || p.atDocumentEnd() || p.column() < column {
+			return result, nil
+		}
+		if p.column() > column {
+			return nil, ErrIndentation
+		}
+	}
+}
+
+func (p *parser) sequence(column int) (*node, error) {
+	result := &node{kind: sequenceNode, start: p.pos}
+	for {
+		p.pos++
+		item, err := p.value(column, false)
+		if err != nil {
+			return nil, err
+		}
+		result.items = append(result.items, item)
+		if !p.skipLines() || p.atDocumentEnd() || p.column() < column {
+			return result, nil
+		}
+		if p.column() > column {
+			return nil, ErrIndentation
+		}
+		if !p.dash() {
+			// a key of the mapping holding the sequence at the same column
+			return result, nil
+		}
+	}
+}
+
+// skipFlow skips blanks, line breaks and comments inside a flow collection
+func (p *parser) skipFlow() {
+	for p.skipLines() && p.endOfLine() {
+	}
+}
+
+func (p *parser) flow() (*node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	result := &node{kind: sequenceNode, start: p.pos}
+	closing := byte(']')
+	if p.data[p.pos] == '{' {
+		result.kind = mappingNode
+		closing = '}'
+	}
+	p.pos++
+	for {
+		p.skipFlow()
+		if p.eof() {
+			return nil, ErrUnterminated
+		}
+		if p.data[p.pos] == closing {
+			p.pos++
+			return result, nil
+		}
+		if result.kind == mappingNode {
+			key, err := p.flowKey()
+			if err != nil {
+				return nil, err
+			}
+			value := empty(p.pos)
+			p.skipFlow()
+			if !p.eof() && p.data[p.pos] == ':' {
+				p.pos++
+				if value, err = p.flowValue(); err != nil {
+					return nil, err
+				}
+			}
+			result.entries = append(result.entries, entry{key: key, value: value})
+		} else {
+			value, err := p.flowValue()
+			if err != nil {
+				return nil, err
+			}
+			result.items = append(result.items, value)
+		}
+		p.skipFlow()
+		if p.eof() {
+			return nil, ErrUnterminated
+		}
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+		case closing:
+		default:
+			return nil, ErrSyntax
+		}
+	}
+}
+
+func (p *parser) flowKey() (string, error) {
+	switch p.data[p.pos] {
+	case '"', '\'':
+		return p.quoted()
+	case '?':
+		return "", ErrUnsupported
+	}
+	return p.plain(true), nil
+}
+
+func (p *parser) flowValue() (*node, error) {
+	p.skipFlow()
+	start, expanded := p.pos, p.expanded
+	anchor := ""
+	for !p.eof() && (p.data[p.pos] == '&' || p.data[p.pos] == '!') {
+		name := p.token()
+		if name[0] == '&' {
+			anchor = name[1:]
+		}
+		p.skipFlow()
+	}
+	if p.eof() {
+		return nil, ErrUnterminated
+	}
+	var result *node
+	var err error
+	switch p.data[p.pos] {
+	case '[', '{':
+		result, err = p.flow()
+	case '*':
+		result, err = p.alias()
+	case '"', '\'':
+		var text string
+		text, err = p.quoted()
+		result = &node{kind: scalarNode, text: text, start: start}
+	default:
+		result = &node{kind: scalarNode, text: p.plain(true), plain: true, start: start}
+	}
+	p.anchor(anchor, result, start, expanded)
+	return result, err
+}
+
+// fold replaces a line break inside a quoted scalar and the blanks around it,
+// a single break becomes a space and each empty line a line feed
+func (p *parser) fold(text []byte) []byte {
+	text = bytes.TrimRight(text, " \t")
+	start := p.pos
+	for !p.eof() && isSpace(p.data[p.pos]) {
+		p.pos++
+	}
+	breaks := lineBreaks(p.data[start:p.pos])
+	if breaks == 1 {
+		return append(text, ' ')
+	}
+	return append(text, strings.Repeat("\n", breaks-1)...)
+}
+
+var escapes = map[byte]string{'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029"}
+
+func (p *parser) quoted() (string, error) {
+	quote := p.data[p.pos]
+	p.pos++
+	var result []byte
+	for {
+		if p.eof() {
+			return "", ErrUnterminated
+		}
+		c := p.data[p.pos]
+		switch {
+		case c == quote && quote == '\'' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '\'':
+			result = append(result, '\'')
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return string(result), nil
+		case c == '\\' && quote == '"':
+			if err := p.escape(&result); err != nil {
+				return "", err
+			}
+		case c == '\n' || c == '\r':
+			result = p.fold(result)
+		default:
+			result = append(result, c)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) escape(result *[]byte) error {
+	p.pos++
+	if p.eof() {
+		return ErrUnterminated
+	}
+	c := p.data[p.pos]
+	p.pos++
+	if text, ok := escapes[c]; ok {
+		*result = append(*result, text...)
+		return nil
+	}
+	size := 0
+	switch c {
+	case '\n', '\r':
+		// escaped line break, the blanks of the next line are dropped
+		for !p.eof() && isSpace(p.data[p.pos]) {
+			p.pos++
+		}
+		return nil
+	case 'x':
+		size = 2
+	case 'u':
+		size = 4
+	case 'U':
+		size = 8
+	default:
+		return ErrSyntax
+	}
+	if p.pos+size > len(p.data) {
+		return ErrUnterminated
+	}
+	code, err := strconv.ParseUint(string(p.data[p.pos:p.pos+size]), 16, 32)
+	if err != nil {
+		return ErrSyntax
+	}
+	p.pos += size
+	*result = utf8.AppendRune(*result, rune(code))
+	return nil
+}
+
+// blockScalar reads a literal or a folded scalar, its lines are indented deeper than indent
+func (p *parser) blockScalar(indent int) (*node, error) {
+	start := p.pos
+	literal := p.data[p.pos] == '|'
+	p.pos++
+	chomp := byte(0)
+	contentIndent := -1
+	for !p.eof() && !isSpace(p.data[p.pos]) && p.data[p.pos] != '#' {
+		switch c := p.data[p.pos]; {
+		case c == '+' || c == '-':
+			chomp = c
+		case c >= '1' && c <= '9':
+			contentIndent = indent + int(c-'0')
+			if indent < 0 {
+				contentIndent++
+			}
+		default:
+			return nil, ErrSyntax
+		}
+		p.pos++
+	}
+	p.skipInline()
+	if !p.endOfLine() {
+		return nil, ErrSyntax
+	}
+	var text strings.Builder
+	emptyLines := 0
+	first := true
+	previousIndented := false
+	for !p.eof() {
+		// the position is at the line break before the next line
+		lineBreak := p.pos
+		if p.data[p.pos] == '\r' {
+			p.pos++
+		}
+		if !p.eof() && p.data[p.pos] == '\n' {
+			p.pos++
+		}
+		if p.eof() {
+			// the break ends the last line, it doesn't start an empty one
+			break
+		}
+		lineStart := p.pos
+		for !p.eof() && p.data[p.pos] == ' ' {
+			p.pos++
+		}
+		spaces := p.pos - lineStart
+		blank := p.endOfLine()
+		for !p.endOfLine() {
+			p.pos++
+		}
+		lineEnd := p.pos
+		if blank && (contentIndent < 0 || spaces <= contentIndent) {
+			emptyLines++
+			continue
+		}
+		if contentIndent < 0 {
+			contentIndent = spaces
+		}
+		p.pos = lineStart
+		if spaces < contentIndent || spaces <= indent || p.atDocumentEnd() {
+			p.pos = lineBreak
+			break
+		}
+		p.pos = lineEnd
+		line := strings.TrimRight(string(p.data[lineStart+contentIndent:lineEnd]), "\r")
+		indented := len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+		switch {
+		case first:
+			text.WriteString(strings.Repeat("\n", emptyLines))
+		case literal || indented || previousIndented:
+			text.WriteString(strings.Repeat("\n", emptyLines+1))
+		case emptyLines > 0:
+			text.WriteString(strings.Repeat("\n", emptyLines))
+		default:
+			text.WriteByte(' ')
+		}
+		text.WriteString(line)
+		first = false
+		previousIndented = indented
+		emptyLines = 0
+	}
+	result := text.String()
+	switch {
+	case chomp == '+':
+		result += strings.Repeat("\n", emptyLines+1)
+	case chomp != '-' && !first:
+		result += "\n"
+	}
+	return &node{kind: scalarNode, text: result, start: start}, nil
+}
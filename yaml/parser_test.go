@@ -0,0 +1,128 @@
+package yaml_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tvanomr/inspect"
+	"github.com/tvanomr/inspect/internal/inspecttest"
+	"github.com/tvanomr/inspect/yaml"
+)
+
+func TestScalars(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected inspecttest.Text
+	}{
+		{"plain", "a b\n", "a b"},
+		{"plain lines", "a\n  b\n\n  c\n", "a b\nc"},
+		{"plain CR LF", "a\r\n  b\r\n\r\n  c\r\n", "a b\nc"},
+		{"plain CR", "a\r b\r\r c\r", "a b\nc"},
+		{"literal", "|\n  a\n   b\n\n", "a\n b\n"},
+		{"literal strip", "|-\n  a\n  b\n\n", "a\nb"},
+		{"literal keep", "|+\n  a\n\n", "a\n\n"},
+		{"literal CR LF", "|\r\n  a\r\n  b\r\n", "a\nb\n"},
+		{"folded", ">\n  a\n  b\n\n  c\n", "a b\nc\n"},
+		{"folded indented", ">\n  a\n    b\n  c\n", "a\n  b\nc\n"},
+		{"folded keep", ">+\n  a\n  b\n\n", "a b\n\n"},
+		{"folded indicator", ">2-\n   a\n  b\n", " a\nb"},
+		{"single quoted", "'a''b\n  c'\n", "a'b c"},
+		{"double quoted", "\"a\\tb\n\n  c\"\n", "a\tb\nc"},
+		{"double quoted CR", "\"a\r\rb\"\n", "a\nb"},
+		{"escaped break", "\"a\\\n  b\"\n", "ab"},
+	}
+	for _, test := range tests {
+		var value inspecttest.Text
+		if err := inspect.Unmarshal([]byte(test.input), &value, yaml.Format); err != nil || value != test.expected {
+			t.Fatalf("%s: got %q %v", test.name, value, err)
+		}
+	}
+}
+
+func TestFlowCollections(t *testing.T) {
+	sequences := []struct {
+		input    string
+		expected inspecttest.Texts
+	}{
+		{"[a, b, 'c d']\n", inspecttest.Texts{"a", "b", "c d"}},
+		{"[]\n", inspecttest.Texts{}},
+		{"[a,\n  \"b\" ,\n  c d ]\n", inspecttest.Texts{"a", "b", "c d"}},
+		{"[a, b,]\n", inspecttest.Texts{"a", "b"}},
+	}
+	for _, test := range sequences {
+		var value inspecttest.Texts
+		err := inspect.Unmarshal([]byte(test.input), &value, yaml.Format)
+		if err != nil || len(value) != len(test.expected) {
+			t.Fatalf("%q: got %q %v", test.input, value, err)
+		}
+		for i := range test.expected {
+			if value[i] != test.expected[i] {
+				t.Fatalf("%q: got %q", test.input, value)
+			}
+		}
+	}
+
+	mappings := []struct {
+		input    string
+		expected inspecttest.Dictionary
+	}{
+		{"{a: x, b: y}\n", inspecttest.Dictionary{"a": "x", "b": "y"}},
+		{"{}\n", inspecttest.Dictionary{}},
+		{"{\"a b\": 'x y',\n  c: z}\n", inspecttest.Dictionary{"a b": "x y", "c": "z"}},
+	}
+	for _, test := range mappings {
+		var value inspecttest.Dictionary
+		err := inspect.Unmarshal([]byte(test.input), &value, yaml.Format)
+		if err != nil || len(value) != len(test.expected) {
+			t.Fatalf("%q: got %q %v", test.input, value, err)
+		}
+		for key, expected := range test.expected {
+			if value[key] != expected {
+				t.Fatalf("%q: got %q", test.input, value)
+			}
+		}
+	}
+}
+
+func TestBareCarriageReturn(t *testing.T) {
+	// a plain scalar continued after a lone CR used to panic
+	var value inspecttest.Dictionary
+	if err := inspect.Unmarshal([]byte("a\r -5\ns: 1\n"), &value, yaml.Format); err == nil {
+		t.Fatal("got", value)
+	}
+	if err := inspect.Unmarshal([]byte("a: b\r  c\rs: 1\r"), &value, yaml.Format); err != nil ||
+		len(value) != 2 || value["a"] != "b c" || value["s"] != "1" {
+		t.Fatalf("got %q %v", value, err)
+	}
+}
+
+func TestAliasExpansion(t *testing.T) {
+	// every level refers to the previous one 20 times, the last one stands for 20^4 strings
+	input := "a: &a [x, x, x, x, x, x, x, x, x, x, x, x, x, x, x, x, x, x, x, x]\n"
+	for _, name := range []string{"b", "c", "d", "e"} {
+		previous := string(rune(name[0] - 1))
+		input += name + ": &" + name + " [" + strings.Repeat("*"+previous+", ", 19) + "*" + previous + "]\n"
+	}
+	limits := inspect.WithLimits(inspect.Limits{MaxTotalBytes: 1 << 20})
+	var value inspecttest.Dictionary
+	if err := inspect.Unmarshal([]byte(input), &value, yaml.Format, limits); !errors.Is(err, inspect.ErrLimitExceeded) {
+		t.Fatal("got", err)
+	}
+	// a few aliases are fine
+	var list inspecttest.Texts
+	if err := inspect.Unmarshal([]byte("- &a x\n- *a\n- *a\n"), &list, yaml.Format, limits); err != nil ||
+		len(list) != 3 || list[2] != "x" {
+		t.Fatal("got", list, err)
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte("a\r -5\ns: 1\n"))
+	f.Add([]byte("a: |\n  x\n  y\nb: >-\n  z\n\n  w\nc: [a, 'b', {d: e}]\n"))
+	f.Add([]byte("- &x \"a\\tb\"\n- *x\n- {a: [1, 2]}\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inspecttest.Fuzz(data, yaml.Format, new(inspecttest.Dictionary), new(inspecttest.Texts))
+	})
+}
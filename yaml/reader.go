@@ -0,0 +1,452 @@
+package yaml
+
+import (
+	"encoding/base64"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tvanomr/inspect"
+)
+
+type contextKind byte
+
+const (
+	contextMapping contextKind = iota
+	contextSequence
+)
+
+type readContext struct {
+	kind contextKind
+	// entries of a mapping and whether they were asked for
+	entries []entry
+	read    []bool
+	// value of the property or the map key being read
+	selected *node
+	// items of a sequence
+	items []*node
+	index int
+}
+
+// Reader reads one document of a stream for every top level value.
+// An alias counts against MaxTotalBytes as the bytes of its anchored value,
+// without the limit a few nested anchors can stand for an enormous document
+type Reader struct {
+	reader   io.Reader
+	limits   inspect.Limits
+	parser   parser
+	loaded   bool
+	document *node
+	offset   int
+	current  *readContext
+	contexts stack[readContext]
+}
+
+func (r *Reader) SetReader(reader io.Reader) {
+	r.reader = reader
+	r.parser.reset(nil, &r.limits)
+	r.loaded = false
+	r.document = nil
+	r.offset = 0
+	r.current = nil
+	r.contexts = r.contexts[:0]
+}
+func (r *Reader) SetLimits(limits inspect.Limits) {
+	r.limits = limits
+}
+func (r *Reader) Offset() int64 {
+	return int64(r.offset)
+}
+
+func (r *Reader) load() error {
+	reader := r.reader
+	if r.limits.MaxTotalBytes > 0 {
+		reader = io.LimitReader(reader, r.limits.MaxTotalBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if err := r.limits.CheckTotal(int64(len(data))); err != nil {
+		return err
+	}
+	r.parser.reset(data, &r.limits)
+	r.loaded = true
+	return nil
+}
+
+// peek returns the node of the next value without consuming it,
+// at the top level it is the next document
+func (r *Reader) peek() (*node, error) {
+	if r.current == nil {
+		if r.document != nil {
+			return r.document, nil
+		}
+		if !r.loaded {
+			if err := r.load(); err != nil {
+				return nil, err
+			}
+		}
+		document, err := r.parser.document()
+		if err != nil {
+			r.offset = r.parser.pos
+			return nil, err
+		}
+		r.document = document
+		return document, nil
+	}
+	if r.current.kind == contextSequence {
+		if r.current.index >= len(r.current.items) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return r.current.items[r.current.index], nil
+	}
+	if r.current.selected == nil {
+		return nil, ErrNoProperty
+	}
+	return r.current.selected, nil
+}
+
+// advance consumes the node returned by peek
+func (r *Reader) advance(value *node) {
+	r.offset = value.start
+	if r.current == nil {
+		r.document = nil
+	} else if r.current.kind == contextSequence {
+		r.current.index++
+	} else {
+		r.current.selected = nil
+	}
+}
+
+// scalar consumes a scalar once parse accepts it
+func (r *Reader) scalar(parse func(value *node) error) error {
+	value, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if value.kind != scalarNode {
+		return ErrNotAScalar
+	}
+	if err := r.limits.CheckString(int64(len(value.text))); err != nil {
+		return err
+	}
+	if err := parse(value); err != nil {
+		return err
+	}
+	r.advance(value)
+	return nil
+}
+
+// number parses a plain scalar, it is consumed also when it overflows
+func number[T any](r *Reader, parse func(text string) (T, error), kind string) (T, error) {
+	var result T
+	overflow := ""
+	err := r.scalar(func(value *node) error {
+		if !value.plain {
+			return ErrNotANumber
+		}
+		var err error
+		result, err = parse(value.text)
+		if isRange(err) {
+			overflow = value.text
+			return nil
+		}
+		if err != nil {
+			return ErrNotANumber
+		}
+		return nil
+	})
+	if err == nil && len(overflow) > 0 {
+		return result, &inspect.OverflowError{Type: kind, Value: overflow}
+	}
+	return result, err
+}
+
+func readNarrowInt[T inspect.SignedInt](r *Reader) (T, error) {
+	result, err := r.Int64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowInt[T](result)
+}
+
+func readNarrowUint[T inspect.UnsignedInt](r *Reader) (T, error) {
+	result, err := r.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	return inspect.NarrowUint[T](result)
+}
+
+func (r *Reader) Bool() (result bool, err error) {
+	err = r.scalar(func(value *node) error {
+		var ok bool
+		if result, ok = parseBool(value.text); !ok || !value.plain {
+			return ErrNotABool
+		}
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) Int8() (int8, error) {
+	return readNarrowInt[int8](r)
+}
+func (r *Reader) Int16() (int16, error) {
+	return readNarrowInt[int16](r)
+}
+func (r *Reader) Int32() (int32, error) {
+	return readNarrowInt[int32](r)
+}
+func (r *Reader) Int64() (int64, error) {
+	return number(r, func(text string) (int64, error) {
+		return strconv.ParseInt(text, integerBase(text), 64)
+	}, "int64")
+}
+func (r *Reader) Uint8() (uint8, error) {
+	return readNarrowUint[uint8](r)
+}
+func (r *Reader) Uint16() (uint16, error) {
+	return readNarrowUint[uint16](r)
+}
+func (r *Reader) Uint32() (uint32, error) {
+	return readNarrowUint[uint32](r)
+}
+func (r *Reader) Uint64() (uint64, error) {
+	return number(r, func(text string) (uint64, error) {
+		return strconv.ParseUint(strings.TrimPrefix(text, "+"), integerBase(text), 64)
+	}, "uint64")
+}
+func (r *Reader) Float32() (float32, error) {
+	result, err := number(r, func(text string) (float64, error) {
+		result, err := parseFloat(text)
+		if err == nil && !math.IsInf(result, 0) && math.Abs(result) > math.MaxFloat32 {
+			err = &strconv.NumError{Func: "ParseFloat", Num: text, Err: strconv.ErrRange}
+		}
+		return result, err
+	}, "float32")
+	return float32(result), err
+}
+func (r *Reader) Float64() (float64, error) {
+	return number(r, parseFloat, "float64")
+}
+
+// String accepts every scalar, plain ones are taken as they are
+func (r *Reader) String() (result string, err error) {
+	err = r.scalar(func(value *node) error {
+		result = value.text
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) Bytes() (result []byte, err error) {
+	err = r.scalar(func(value *node) error {
+		var err error
+		if result, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value.text), "")); err != nil {
+			return ErrInvalidBytes
+		}
+		return nil
+	})
+	return result, err
+}
+func (r *Reader) ByteString() ([]byte, error) {
+	result, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(result), nil
+}
+
+// IsNull consumes a plain null, ~ or an empty value
+func (r *Reader) IsNull() (bool, error) {
+	value, err := r.peek()
+	if err != nil {
+		return false, err
+	}
+	if value.kind == scalarNode && value.plain && isNullText(value.text) {
+		r.advance(value)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *Reader) push(value readContext) error {
+	r.contexts.push(value)
+	r.current = &r.contexts[len(r.contexts)-1]
+	return r.limits.CheckDepth(len(r.contexts))
+}
+func (r *Reader) pop() {
+	r.contexts.pop()
+	if len(r.contexts) == 0 {
+		r.current = nil
+	} else {
+		r.current = &r.contexts[len(r.contexts)-1]
+	}
+}
+func (r *Reader) StartObject() error {
+	value, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if value.kind != mappingNode {
+		return ErrNotAMapping
+	}
+	r.advance(value)
+	return r.push(readContext{kind: contextMapping, entries: value.entries, read: make([]bool, len(value.entries))})
+}
+func (r *Reader) Property(name string) error {
+	present, err := r.OptionalProperty(name)
+	if err == nil && !present {
+		return inspect.ErrNoField
+	}
+	return err
+}
+func (r *Reader) OptionalProperty(name string) (bool, error) {
+	if r.current == nil || r.current.kind != contextMapping {
+		return false, ErrNoProperty
+	}
+	r.current.selected = nil
+	for i, item := range r.current.entries {
+		if !r.current.read[i] && item.key == name {
+			r.current.read[i] = true
+			r.current.selected = item.value
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UnknownFields returns the keys not asked for with their values in flow style
+func (r *Reader) UnknownFields(policy inspect.UnknownFieldPolicy) ([]inspect.RawField, error) {
+	if r.current == nil || r.current.kind != contextMapping {
+		return nil, ErrNoProperty
+	}
+	var result []inspect.RawField
+	for i, item := range r.current.entries {
+		if r.current.read[i] {
+			continue
+		}
+		if policy == inspect.FailOnUnknown {
+			r.offset = item.value.start
+			return nil, ErrObjectTooBig
+		}
+		r.current.read[i] = true
+		if policy == inspect.CollectUnknown {
+			result = append(result, inspect.RawField{Format: formatName, Name: item.key, Value: appendFlow(nil, item.value)})
+		}
+	}
+	return result, nil
+}
+func (r *Reader) EndObject() error {
+	r.pop()
+	return nil
+}
+
+// StartArray returns the number of items, an empty sequence needs no EndArray
+func (r *Reader) StartArray() (length int, err error) {
+	value, err := r.peek()
+	if err != nil {
+		return 0, err
+	}
+	if value.kind != sequenceNode {
+		return 0, ErrNotASequence
+	}
+	r.advance(value)
+	if len(value.items) == 0 {
+		return 0, nil
+	}
+	if err := r.limits.CheckArray(int64(len(value.items))); err != nil {
+		return 0, err
+	}
+	return len(value.items), r.push(readContext{kind: contextSequence, items: value.items})
+}
+func (r *Reader) HaveNext() (bool, error) {
+	return false, nil
+}
+func (r *Reader) EndArray() error {
+	r.pop()
+	return nil
+}
+func (r *Reader) StartMap() (length int, err error) {
+	value, err := r.peek()
+	if err != nil {
+		return 0, err
+	}
+	if value.kind != mappingNode {
+		return 0, ErrNotAMapping
+	}
+	r.advance(value)
+	if len(value.entries) == 0 {
+		return 0, nil
+	}
+	if err := r.limits.CheckArray(int64(len(value.entries))); err != nil {
+		return 0, err
+	}
+	return len(value.entries), r.push(readContext{kind: contextMapping, entries: value.entries,
+		read: make([]bool, len(value.entries))})
+}
+
+// NextKey returns the next key of the map and selects its value
+func (r *Reader) NextKey() (string, error) {
+	if r.current == nil || r.current.kind != contextMapping {
+		return "", nil
+	}
+	for i, item := range r.current.entries {
+		if !r.current.read[i] {
+			r.current.read[i] = true
+			r.current.selected = item.value
+			return item.key, nil
+		}
+	}
+	return "", nil
+}
+func (r *Reader) EndMap() error {
+	r.pop()
+	return nil
+}
+func (r *Reader) Skip() error {
+	value, err := r.peek()
+	if err != nil {
+		return err
+	}
+	r.advance(value)
+	return nil
+}
+
+// appendFlow writes a value read from the input in flow style
+func appendFlow(buffer []byte, value *node) []byte {
+	switch value.kind {
+	case mappingNode:
+		buffer = append(buffer, '{')
+		for i, item := range value.entries {
+			if i > 0 {
+				buffer = append(buffer, ", "...)
+			}
+			buffer = appendString(buffer, item.key)
+			buffer = append(buffer, ": "...)
+			buffer = appendFlow(buffer, item.value)
+		}
+		return append(buffer, '}')
+	case sequenceNode:
+		buffer = append(buffer, '[')
+		for i, item := range value.items {
+			if i > 0 {
+				buffer = append(buffer, ", "...)
+			}
+			buffer = appendFlow(buffer, item)
+		}
+		return append(buffer, ']')
+	}
+	if value.plain && isNullText(value.text) {
+		return append(buffer, "null"...)
+	}
+	if value.plain && isTyped(value.text) {
+		return append(buffer, value.text...)
+	}
+	return appendString(buffer, value.text)
+}
+
+func init() {
+	var _ inspect.Reader = (*Reader)(nil)
+}
@@ -0,0 +1,103 @@
+package yaml
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func isNullText(text string) bool {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return true
+	}
+	return false
+}
+
+func parseBool(text string) (result bool, ok bool) {
+	switch text {
+	case "true", "True", "TRUE":
+		return true, true
+	case "false", "False", "FALSE":
+		return false, true
+	}
+	return false, false
+}
+
+// integerBase returns the base of an integer with 0x, 0o or 0b prefix,
+// the rest are decimal even with leading zeros
+func integerBase(text string) int {
+	digits := strings.TrimLeft(text, "+-")
+	if len(digits) > 1 && digits[0] == '0' && strings.ContainsRune("xXoObB", rune(digits[1])) {
+		return 0
+	}
+	return 10
+}
+
+func parseFloat(text string) (float64, error) {
+	switch strings.ToLower(strings.TrimLeft(text, "+")) {
+	case ".inf":
+		return math.Inf(1), nil
+	case "-.inf":
+		return math.Inf(-1), nil
+	case ".nan":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+// isTyped tells plain scalars that don't read back as strings in YAML 1.1 or 1.2
+func isTyped(text string) bool {
+	if isNullText(text) {
+		return true
+	}
+	switch strings.ToLower(text) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n":
+		return true
+	}
+	if _, err := parseFloat(text); err == nil || isRange(err) {
+		return true
+	}
+	_, err := strconv.ParseInt(text, integerBase(text), 64)
+	return err == nil || isRange(err)
+}
+
+func isRange(err error) bool {
+	numError, ok := err.(*strconv.NumError)
+	return ok && numError.Err == strconv.ErrRange
+}
+
+// isPlain tells strings that can be written without quotes in block and flow style
+func isPlain(text string) bool {
+	if len(text) == 0 || text[0] == ' ' || text[len(text)-1] == ' ' || !utf8.ValidString(text) ||
+		strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(text[0])) || isTyped(text) ||
+		strings.Contains(text, ": ") || strings.Contains(text, " #") || text[len(text)-1] == ':' {
+		return false
+	}
+	for _, c := range text {
+		if c < ' ' || c == 0x7f || strings.ContainsRune(",[]{}", c) || !strconv.IsPrint(c) && c != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+func appendString(buffer []byte, text string) []byte {
+	if isPlain(text) {
+		return append(buffer, text...)
+	}
+	return strconv.AppendQuote(buffer, text)
+}
+
+func appendFloat(buffer []byte, value float64, format byte, precision int, bitSize int) []byte {
+	switch {
+	case math.IsNaN(value):
+		return append(buffer, ".nan"...)
+	case math.IsInf(value, 1):
+		return append(buffer, ".inf"...)
+	case math.IsInf(value, -1):
+		return append(buffer, "-.inf"...)
+	}
+	return strconv.AppendFloat(buffer, value, format, precision, bitSize)
+}
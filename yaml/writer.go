@@ -0,0 +1,314 @@
+package yaml
+
+import (
+	"encoding/base64"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tvanomr/inspect"
+)
+
+// indentation of nested block collections
+const indent = "  "
+
+// what the current line ends with
+type lineEnd byte
+
+const (
+	afterValue lineEnd = iota
+	// "key:", a scalar follows after a space, a block collection on the next line
+	afterKey
+	// "- ", the first entry of a collection follows on the same line
+	afterDash
+)
+
+type writeContext struct {
+	kind  contextKind
+	level int
+	count int
+}
+
+// Writer writes a document for every top level value, separated by "---".
+// Block style is the default, flow style puts each document on a single line.
+type Writer struct {
+	writer      io.Writer
+	bufferSize  int
+	buffer      []byte
+	flow        bool
+	comments    bool
+	contexts    stack[writeContext]
+	after       lineEnd
+	lineStart   bool
+	documents   int
+	description string
+}
+
+// SetFlow writes collections in flow style: {key: value, list: [1, 2]}
+func (w *Writer) SetFlow(flow bool) {
+	w.flow = flow
+}
+
+// SetComments writes descriptions of properties and documents as # comments in block style
+func (w *Writer) SetComments(comments bool) {
+	w.comments = comments
+}
+
+func (w *Writer) SetWriter(writer io.Writer, bufferSize int) {
+	w.writer = writer
+	w.bufferSize = bufferSize
+	w.buffer = w.buffer[:0]
+	w.contexts = w.contexts[:0]
+	w.after = afterValue
+	w.lineStart = true
+	w.documents = 0
+	w.description = ""
+}
+
+// Describe keeps the description for the following key or document
+func (w *Writer) Describe(name string, elementName string, description string) {
+	w.description = description
+}
+
+func (w *Writer) comment(level int) {
+	for _, line := range strings.Split(w.description, "\n") {
+		for i := 0; i < level; i++ {
+			w.buffer = append(w.buffer, indent...)
+		}
+		w.buffer = append(w.buffer, "# "...)
+		w.buffer = append(w.buffer, line...)
+		w.buffer = append(w.buffer, '\n')
+	}
+}
+
+// entry starts a key or a dash of a block collection on its own line,
+// the first entry after a dash stays on the line of the dash
+func (w *Writer) entry(context *writeContext) {
+	if w.flow {
+		if context.count > 0 {
+			w.buffer = append(w.buffer, ", "...)
+		}
+	} else if w.after != afterDash {
+		if !w.lineStart {
+			w.buffer = append(w.buffer, '\n')
+		}
+		if w.comments && len(w.description) > 0 && context.kind == contextMapping {
+			w.comment(context.level)
+		}
+		for i := 0; i < context.level; i++ {
+			w.buffer = append(w.buffer, indent...)
+		}
+	}
+	w.description = ""
+	w.lineStart = false
+	context.count++
+}
+
+// startValue separates documents and writes the dash of a sequence item
+func (w *Writer) startValue() {
+	if len(w.contexts) == 0 {
+		if w.documents > 0 {
+			w.buffer = append(w.buffer, "---\n"...)
+		}
+		if w.comments && len(w.description) > 0 && !w.flow {
+			w.comment(0)
+		}
+		w.description = ""
+		return
+	}
+	w.description = ""
+	context := &w.contexts[len(w.contexts)-1]
+	if context.kind == contextSequence {
+		w.entry(context)
+		if !w.flow {
+			w.buffer = append(w.buffer, "- "...)
+			w.after = afterDash
+		}
+	}
+}
+
+// endValue ends the document after a top level value
+func (w *Writer) endValue() error {
+	w.after = afterValue
+	if len(w.contexts) == 0 {
+		w.buffer = append(w.buffer, '\n')
+		w.documents++
+		w.lineStart = true
+	}
+	if len(w.buffer) > w.bufferSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+func (w *Writer) scalar(text []byte) error {
+	w.startValue()
+	if w.after == afterKey {
+		w.buffer = append(w.buffer, ' ')
+	}
+	w.buffer = append(w.buffer, text...)
+	return w.endValue()
+}
+
+func (w *Writer) Bool(value bool) error {
+	return w.scalar(strconv.AppendBool(nil, value))
+}
+func (w *Writer) Int8(value int8) error {
+	return w.scalar(strconv.AppendInt(nil, int64(value), 10))
+}
+func (w *Writer) Int16(value int16) error {
+	return w.scalar(strconv.AppendInt(nil, int64(value), 10))
+}
+func (w *Writer) Int32(value int32) error {
+	return w.scalar(strconv.AppendInt(nil, int64(value), 10))
+}
+func (w *Writer) Int64(value int64) error {
+	return w.scalar(strconv.AppendInt(nil, value, 10))
+}
+func (w *Writer) Uint8(value uint8) error {
+	return w.scalar(strconv.AppendUint(nil, uint64(value), 10))
+}
+func (w *Writer) Uint16(value uint16) error {
+	return w.scalar(strconv.AppendUint(nil, uint64(value), 10))
+}
+func (w *Writer) Uint32(value uint32) error {
+	return w.scalar(strconv.AppendUint(nil, uint64(value), 10))
+}
+func (w *Writer) Uint64(value uint64) error {
+	return w.scalar(strconv.AppendUint(nil, value, 10))
+}
+func (w *Writer) Float32(value float32, format byte, precision int) error {
+	return w.scalar(appendFloat(nil, float64(value), format, precision, 32))
+}
+func (w *Writer) Float64(value float64, format byte, precision int) error {
+	return w.scalar(appendFloat(nil, value, format, precision, 64))
+}
+func (w *Writer) String(value string) error {
+	return w.scalar(appendString(nil, value))
+}
+func (w *Writer) Bytes(value []byte) error {
+	return w.scalar(appendString(nil, base64.StdEncoding.EncodeToString(value)))
+}
+func (w *Writer) ByteString(value []byte) error {
+	return w.scalar(appendString(nil, string(value)))
+}
+func (w *Writer) Null() error {
+	return w.scalar([]byte("null"))
+}
+
+func (w *Writer) Skip() error {
+	return w.Null()
+}
+func (w *Writer) NotNull() error {
+	return nil
+}
+
+func (w *Writer) start(kind contextKind) error {
+	w.startValue()
+	level := 0
+	if len(w.contexts) > 0 {
+		level = w.contexts[len(w.contexts)-1].level + 1
+	}
+	if w.flow {
+		if kind == contextMapping {
+			w.buffer = append(w.buffer, '{')
+		} else {
+			w.buffer = append(w.buffer, '[')
+		}
+	}
+	w.contexts.push(writeContext{kind: kind, level: level})
+	return nil
+}
+
+// end closes a collection, an empty one is written in flow style also in block style
+func (w *Writer) end() error {
+	context := w.contexts.pop()
+	brackets := "{}"
+	if context.kind == contextSequence {
+		brackets = "[]"
+	}
+	switch {
+	case w.flow:
+		w.buffer = append(w.buffer, brackets[1])
+	case context.count == 0:
+		if w.after == afterKey {
+			w.buffer = append(w.buffer, ' ')
+		}
+		w.buffer = append(w.buffer, brackets...)
+	}
+	return w.endValue()
+}
+func (w *Writer) StartObject() error {
+	return w.start(contextMapping)
+}
+func (w *Writer) Property(name string) error {
+	if len(w.contexts) == 0 || w.contexts[len(w.contexts)-1].kind != contextMapping {
+		return ErrNoProperty
+	}
+	w.entry(&w.contexts[len(w.contexts)-1])
+	w.buffer = appendString(w.buffer, name)
+	if w.flow {
+		w.buffer = append(w.buffer, ": "...)
+	} else {
+		w.buffer = append(w.buffer, ':')
+		w.after = afterKey
+	}
+	return nil
+}
+func (w *Writer) OptionalProperty(name string, present bool) error {
+	if !present {
+		return nil
+	}
+	return w.Property(name)
+}
+
+// RawProperty writes a key collected by a yaml reader, its value is in flow style
+func (w *Writer) RawProperty(field inspect.RawField) error {
+	if field.Format != formatName {
+		return ErrRawField
+	}
+	if err := w.Property(field.Name); err != nil {
+		return err
+	}
+	return w.scalar(field.Value)
+}
+func (w *Writer) EndObject() error {
+	return w.end()
+}
+func (w *Writer) StartArray(length int) error {
+	return w.start(contextSequence)
+}
+func (w *Writer) EndArray() error {
+	return w.end()
+}
+func (w *Writer) StartMap(length int) error {
+	return w.start(contextMapping)
+}
+func (w *Writer) NextKey(key string) error {
+	return w.Property(key)
+}
+func (w *Writer) EndMap() error {
+	return w.end()
+}
+func (w *Writer) SortKeys() bool {
+	return false
+}
+func (w *Writer) Flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	written, err := w.writer.Write(w.buffer)
+	if err != nil {
+		return err
+	}
+	if written < len(w.buffer) {
+		return ErrShortWrite
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func init() {
+	var _ inspect.Writer = (*Writer)(nil)
+	var _ inspect.Described = (*Writer)(nil)
+}